@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minmaxmean/sigma/llm"
+	"github.com/minmaxmean/sigma/siq/calibrate"
+	"github.com/minmaxmean/sigma/siq/draft"
+	"github.com/minmaxmean/sigma/siq/packtext"
+	"github.com/minmaxmean/sigma/siq/translate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	aiProvider string
+	aiBaseURL  string
+	aiAPIKey   string
+	aiModel    string
+
+	translateOptions  = translate.DefaultOptions()
+	translateGlossary string
+	translateCache    string
+
+	draftOptions = draft.DefaultOptions()
+	draftTarget  draft.Target
+
+	calibrateOptions = calibrate.DefaultOptions()
+	calibrateScope   string
+	calibrateOutput  string
+)
+
+var aiCmd = &cobra.Command{
+	Use:   "ai",
+	Short: "Process SIQ files with a language model",
+	Long: `Process SIQ files with a language model:
+- translate: translate the texts of a package into another language
+- generate: draft a theme of questions on a topic for review
+- calibrate: check question prices against estimated difficulty
+
+Models are served by Ollama (configured with OLLAMA_HOST) or by any server
+with an OpenAI-compatible API such as llama.cpp or vLLM (--provider openai).`,
+}
+
+var aiTranslateCmd = &cobra.Command{
+	Use:   "translate [input-siq-file] [output-siq-file]",
+	Short: "Translate a SIQ file into another language",
+	Long: `Translate question texts, answers, round and theme names and comments of a
+package and write the result with the original media. Media references and
+the package structure are kept, the package language is set to --to.
+
+Translations are cached by a hash of the source text, the languages, the
+model and the glossary terms the text uses in --cache (by default the output
+path with a .translations.json extension), so a repeated run only sends new
+or changed texts to the model. The output may be the input file.
+
+A glossary is a YAML file mapping source terms to their translations. Terms
+found in a batch of texts are passed to the model to keep proper nouns
+consistent:
+
+  Пушкин: Pushkin
+  Санкт-Петербург: Saint Petersburg`,
+	Args: cobra.ExactArgs(2),
+	Run:  runAITranslate,
+}
+
+var aiGenerateCmd = &cobra.Command{
+	Use:   "generate [siq-file]",
+	Short: "Draft a theme of questions on a topic",
+	Long: `Ask the model for a theme of questions on --topic at increasing prices and
+add it to a package, which is created when it does not exist.
+
+Every question gets a right answer, acceptable alternates and sources. The
+response is checked against the package model, invalid responses (wrong
+number of questions, empty answers, answers given away in the text) are sent
+back to the model for correction.
+
+Drafted themes and questions are marked in their comments with
+"Generated draft, review before use" and must be reviewed before playing.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runAIGenerate,
+}
+
+var aiCalibrateCmd = &cobra.Command{
+	Use:   "calibrate [siq-file]",
+	Short: "Check question prices against estimated difficulty",
+	Long: `Ask the model to rate the difficulty of every question from 1 to 10 and
+compare the scores with the prices. Questions are sent without prices so the
+scores are not biased by them.
+
+Within a theme a more expensive question should not be easier than a cheaper
+one. Such pairs are reported as inversions, for example a 500 rated easier
+than a 100. Differences smaller than --min-gap are not reported, nor are
+stakes selected from a number set.
+
+With --reorder the questions of themes with inversions are sorted by
+difficulty and the package is written to --output with its media. Prices
+stay in place, so the easiest question gets the lowest price. Themes with
+prices set by a price parameter are kept as they are.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runAICalibrate,
+}
+
+func init() {
+	aiCmd.PersistentFlags().StringVar(&aiProvider, "provider", "ollama", "Model provider: ollama or openai")
+	aiCmd.PersistentFlags().StringVar(&aiBaseURL, "base-url", "http://localhost:8080/v1", "API root of the openai provider")
+	aiCmd.PersistentFlags().StringVar(&aiAPIKey, "api-key", "", "API key of the openai provider (default $OPENAI_API_KEY)")
+	aiCmd.PersistentFlags().StringVarP(&aiModel, "model", "m", "gemma3:12b", "Model name")
+
+	aiTranslateCmd.Flags().StringVar(&translateOptions.Language, "to", "", "Target language, for example en")
+	aiTranslateCmd.Flags().StringVar(&translateOptions.SourceLanguage, "from", "", "Source language (default: the package language)")
+	aiTranslateCmd.Flags().StringVarP(&translateGlossary, "glossary", "g", "", "YAML glossary of term translations")
+	aiTranslateCmd.Flags().StringVar(&translateCache, "cache", "", "Translation cache file (default: output path with .translations.json extension)")
+	aiTranslateCmd.Flags().IntVar(&translateOptions.BatchSize, "batch-size", translateOptions.BatchSize, "Maximal number of texts per request")
+	aiTranslateCmd.Flags().IntVar(&translateOptions.MaxRetries, "max-retries", translateOptions.MaxRetries, "Maximal number of unusable responses retried in smaller batches")
+	aiTranslateCmd.MarkFlagRequired("to")
+
+	aiGenerateCmd.Flags().StringVarP(&draftOptions.Topic, "topic", "t", "", "Topic of the questions")
+	aiGenerateCmd.Flags().IntVarP(&draftOptions.Questions, "count", "n", draftOptions.Questions, "Number of questions")
+	aiGenerateCmd.Flags().IntSliceVar(&draftOptions.Prices, "prices", nil, "Question prices (default 100, 200, ...)")
+	aiGenerateCmd.Flags().StringVar(&draftOptions.Theme, "theme", "", "Theme name (default: suggested by the model)")
+	aiGenerateCmd.Flags().StringVar(&draftOptions.Language, "language", "", "Language of the questions (default: the language of the topic)")
+	aiGenerateCmd.Flags().IntVar(&draftOptions.Attempts, "attempts", draftOptions.Attempts, "Number of requests before giving up on invalid responses")
+	aiGenerateCmd.Flags().StringVar(&draftTarget.Round, "round", "", "Round receiving the theme, created when missing (default: the last round)")
+	aiGenerateCmd.MarkFlagRequired("topic")
+
+	aiCalibrateCmd.Flags().StringVar(&calibrateScope, "scope", "", "Questions to score: r1, r1/t2 or r1/t2/q3 (default: all)")
+	aiCalibrateCmd.Flags().IntVar(&calibrateOptions.ChunkTokens, "chunk-tokens", calibrateOptions.ChunkTokens, "Estimated token budget of the questions of a request")
+	aiCalibrateCmd.Flags().IntVar(&calibrateOptions.MinGap, "min-gap", calibrateOptions.MinGap, "Smallest difficulty difference reported as an inversion")
+	aiCalibrateCmd.Flags().BoolVar(&calibrateOptions.Reorder, "reorder", false, "Reorder questions of themes with inversions by difficulty")
+	aiCalibrateCmd.Flags().StringVarP(&calibrateOutput, "output", "o", "", "Output SIQ file of the reordered package")
+	aiCalibrateCmd.MarkFlagsRequiredTogether("reorder", "output")
+
+	aiCmd.AddCommand(aiTranslateCmd)
+	aiCmd.AddCommand(aiGenerateCmd)
+	aiCmd.AddCommand(aiCalibrateCmd)
+}
+
+// newProvider creates the model provider selected by the --provider flag
+func newProvider() llm.Provider {
+	switch aiProvider {
+	case "ollama":
+		provider, err := llm.NewOllama()
+		if err != nil {
+			log.Fatal("Failed to create provider:", err)
+		}
+		return provider
+	case "openai":
+		apiKey := aiAPIKey
+		if apiKey == "" {
+			apiKey = os.Getenv("OPENAI_API_KEY")
+		}
+		return llm.NewOpenAI(aiBaseURL, apiKey)
+	default:
+		log.Fatalf("Unknown provider %q, expected ollama or openai", aiProvider)
+		return nil
+	}
+}
+
+func runAITranslate(cmd *cobra.Command, args []string) {
+	input, output := args[0], args[1]
+
+	cachePath := translateCache
+	if cachePath == "" {
+		cachePath = strings.TrimSuffix(output, filepath.Ext(output)) + ".translations.json"
+	}
+	cache, err := translate.LoadCache(cachePath)
+	if err != nil {
+		log.Fatal("Failed to load translation cache:", err)
+	}
+	if translateGlossary != "" {
+		translateOptions.Glossary, err = translate.LoadGlossary(translateGlossary)
+		if err != nil {
+			log.Fatal("Failed to load glossary:", err)
+		}
+	}
+
+	translateOptions.Model = aiModel
+	translateOptions.Cache = cache
+	translateOptions.Progress = func(done, total int) {
+		fmt.Printf("Translated %d/%d texts\n", done, total)
+	}
+
+	result, err := translate.TranslateFile(context.Background(), newProvider(), input, output, translateOptions)
+	// Translations finished before an error are kept for the next run
+	if saveErr := cache.Save(cachePath); saveErr != nil {
+		log.Fatal("Failed to save translation cache:", saveErr)
+	}
+	if err != nil {
+		log.Fatal("Failed to translate SIQ file:", err)
+	}
+
+	fmt.Printf("\nTranslated %d strings (%d unique, %d from cache) in %d request(s)\n",
+		result.Strings, result.Unique, result.Cached, result.Requests)
+	if result.Retries > 0 {
+		fmt.Printf("Retried %d batch(es) after unusable responses\n", result.Retries)
+	}
+	if result.PromptTokens > 0 || result.CompletionTokens > 0 {
+		fmt.Printf("Tokens: %d prompt, %d completion\n", result.PromptTokens, result.CompletionTokens)
+	}
+	fmt.Printf("Wrote %s (language %s), cache %s\n", output, translateOptions.Language, cachePath)
+}
+
+func runAIGenerate(cmd *cobra.Command, args []string) {
+	// Explicit prices set the number of questions unless it is given too
+	if len(draftOptions.Prices) > 0 && !cmd.Flags().Changed("count") {
+		draftOptions.Questions = len(draftOptions.Prices)
+	}
+	draftOptions.Model = aiModel
+
+	result, err := draft.Draft(context.Background(), newProvider(), draftOptions)
+	if err != nil {
+		log.Fatal("Failed to draft theme:", err)
+	}
+
+	theme := result.Theme
+	fmt.Printf("Theme: %s (%d attempt(s))\n", theme.Name, result.Attempts)
+	for _, question := range theme.Questions {
+		fmt.Printf("  %d: %s — %s\n", question.BasePrice, question.GetQuestionContent()[0].Value, strings.Join(question.Right, " / "))
+	}
+	if result.PromptTokens > 0 || result.CompletionTokens > 0 {
+		fmt.Printf("Tokens: %d prompt, %d completion\n", result.PromptTokens, result.CompletionTokens)
+	}
+
+	draftTarget.Path = args[0]
+	draftTarget.Language = draftOptions.Language
+	if err := draft.WriteTheme(theme, draftTarget); err != nil {
+		log.Fatal("Failed to write SIQ file:", err)
+	}
+	round := draftTarget.Round
+	if round == "" {
+		round = "the last round"
+	}
+	fmt.Printf("\nAdded theme %q to %s in %s\n", theme.Name, round, args[0])
+}
+
+func runAICalibrate(cmd *cobra.Command, args []string) {
+	scope, err := packtext.ParseScope(calibrateScope)
+	if err != nil {
+		log.Fatal("Failed to parse scope:", err)
+	}
+	calibrateOptions.Scope = scope
+	calibrateOptions.Model = aiModel
+	calibrateOptions.Progress = func(done, total int) {
+		fmt.Printf("Scored %d/%d request(s)\n", done, total)
+	}
+
+	report, err := calibrate.CalibrateFile(context.Background(), newProvider(), args[0], calibrateOutput, calibrateOptions)
+	if err != nil {
+		log.Fatal("Failed to calibrate SIQ file:", err)
+	}
+
+	for _, theme := range report.Themes {
+		fmt.Printf("\n=== %s / %s ===\n", theme.RoundName, theme.ThemeName)
+		for _, score := range theme.Scores {
+			difficulty := "-"
+			if score.Difficulty > 0 {
+				difficulty = fmt.Sprintf("%d/%d", score.Difficulty, calibrate.MaxDifficulty)
+			}
+			fmt.Printf("  %5s  %5s  %s\n", score.Ref.Price, difficulty, score.Text)
+			if score.Reason != "" {
+				fmt.Printf("                %s\n", score.Reason)
+			}
+		}
+		for _, inversion := range theme.Inversions {
+			fmt.Printf("  Inversion: %s (%d) is easier than %s (%d)\n",
+				inversion.Expensive.Ref.Price, inversion.Expensive.Difficulty,
+				inversion.Cheaper.Ref.Price, inversion.Cheaper.Difficulty)
+		}
+	}
+
+	fmt.Printf("\nFound %d inversion(s) in %d theme(s)\n", report.Inversions(), len(report.Themes))
+	if len(report.Unscored) > 0 {
+		fmt.Printf("Not scored: %s\n", strings.Join(report.Unscored, ", "))
+	}
+	if report.PromptTokens > 0 || report.CompletionTokens > 0 {
+		fmt.Printf("Tokens: %d prompt, %d completion in %d request(s)\n", report.PromptTokens, report.CompletionTokens, report.Requests)
+	}
+	if calibrateOutput != "" {
+		fmt.Printf("Moved %d question(s), wrote %s\n", report.Moved, calibrateOutput)
+	}
+}
+
+// GetAICmd returns the ai command
+func GetAICmd() *cobra.Command {
+	return aiCmd
+}
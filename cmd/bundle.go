@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/minmaxmean/sigma/siq/bundle"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleFromDir string
+	bundleTimeout time.Duration
+	bundleMaxSize int64
+	bundleWorkers int
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle [input-siq-file] [output-siq-file]",
+	Short: "Embed externally linked media into a SIQ file",
+	Long: `Find content items that link to external media URLs, download the files,
+store them in the media folders of the package and turn the items into
+references to the stored files. Items whose URLs cannot be downloaded are
+left unchanged and reported.
+
+With --from-dir the files are read from a local mirror instead of the
+network: https://example.com/img/cat.png is read from
+<dir>/example.com/img/cat.png.`,
+	Args: cobra.ExactArgs(2),
+	Run:  runBundle,
+}
+
+func init() {
+	bundleCmd.Flags().StringVar(&bundleFromDir, "from-dir", "", "Read files from a local mirror directory instead of downloading them")
+	bundleCmd.Flags().DurationVar(&bundleTimeout, "timeout", 30*time.Second, "Timeout of a single download")
+	bundleCmd.Flags().Int64Var(&bundleMaxSize, "max-size", bundle.DefaultMaxSize, "Largest file size in bytes to download, 0 for no limit")
+	bundleCmd.Flags().IntVarP(&bundleWorkers, "workers", "j", 4, "Number of concurrent downloads")
+}
+
+func runBundle(cmd *cobra.Command, args []string) {
+	var fetcher bundle.Fetcher
+	if bundleFromDir != "" {
+		fetcher = bundle.DirFetcher{Dir: bundleFromDir}
+	} else {
+		httpFetcher := bundle.NewHTTPFetcher(bundleTimeout)
+		httpFetcher.MaxSize = bundleMaxSize
+		fetcher = httpFetcher
+	}
+
+	result, err := bundle.Bundle(context.Background(), args[0], args[1], fetcher, bundle.Options{Workers: bundleWorkers})
+	if err != nil {
+		log.Fatal("Failed to bundle SIQ file:", err)
+	}
+
+	for _, fetched := range result.Fetched {
+		fmt.Printf("Embedded %s as %s (%s, %d item(s))\n", fetched.URL, fetched.Path, formatBytes(fetched.Size), fetched.Items)
+	}
+	for _, failure := range result.Failed {
+		fmt.Fprintf(os.Stderr, "Failed to fetch %s (%d item(s)): %v\n", failure.URL, failure.Items, failure.Err)
+	}
+	for _, value := range result.Skipped {
+		fmt.Fprintf(os.Stderr, "Skipped %s: not an HTTP URL\n", value)
+	}
+	fmt.Printf("\nBundled %s: %d file(s) embedded, %d item(s) rewritten, %d failed\n",
+		args[1], len(result.Fetched), result.Items(), len(result.Failed))
+}
+
+// GetBundleCmd returns the bundle command
+func GetBundleCmd() *cobra.Command {
+	return bundleCmd
+}
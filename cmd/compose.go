@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/minmaxmean/sigma/siq/merge"
+	"github.com/spf13/cobra"
+)
+
+var composeOutput string
+
+var composeCmd = &cobra.Command{
+	Use:   "compose [spec.yaml] -o [output-siq-file]",
+	Short: "Build a SIQ file from questions of other SIQ files",
+	Long: `Build a new package from a YAML spec listing rounds and themes. Questions
+are picked by reference or by query:
+
+  name: Game Night
+  pool: [packs/]            # packages searched by queries
+  seed: 42                  # shuffle query results (optional)
+  rounds:
+    - name: Round 1
+      prices: [100, 200, 300]
+      themes:
+        - name: Animals
+          questions:
+            - ref: packs/animals.siq#Round 1/Cats/100
+            - ref: packs/animals.siq#r1/t2/q3
+            - query:
+                tags: [Animals]
+                type: simple
+                hasMedia: true
+                minDifficulty: 3
+                text: dog
+                count: 2
+
+Prices are assigned in order from the theme or round prices, a question
+entry can override them with price. Referenced media files, authors and
+sources are copied into the new package. Relative paths are resolved
+against the directory of the spec.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runCompose,
+}
+
+func init() {
+	composeCmd.Flags().StringVarP(&composeOutput, "output", "o", "", "Output SIQ file")
+	composeCmd.MarkFlagRequired("output")
+}
+
+func runCompose(cmd *cobra.Command, args []string) {
+	spec, err := merge.LoadSpec(args[0])
+	if err != nil {
+		log.Fatal("Failed to load spec:", err)
+	}
+
+	result, err := merge.Compose(spec, composeOutput)
+	if err != nil {
+		log.Fatal("Failed to compose SIQ file:", err)
+	}
+
+	for _, rename := range result.RenamedFiles {
+		fmt.Printf("Renamed %s from %s to %s\n", rename.Old, rename.Source, rename.New)
+	}
+	for _, missing := range result.Missing {
+		fmt.Fprintf(os.Stderr, "Missing media file %s\n", missing)
+	}
+	fmt.Printf("Composed %s: %d round(s), %d question(s), %d media file(s)\n",
+		composeOutput, result.Rounds, result.Questions, result.Files)
+}
+
+// GetComposeCmd returns the compose command
+func GetComposeCmd() *cobra.Command {
+	return composeCmd
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minmaxmean/sigma/siq"
+	"github.com/minmaxmean/sigma/siq/contactsheet"
+	"github.com/spf13/cobra"
+)
+
+var (
+	contactSheetOptions  = contactsheet.DefaultOptions()
+	contactSheetPerRound bool
+)
+
+var contactSheetCmd = &cobra.Command{
+	Use:   "contact-sheet [siq-file] [output-png-file]",
+	Short: "Render the images of a SIQ file as a grid of thumbnails",
+	Long: `Render every image used by the questions of a SIQ file as a thumbnail
+labeled with its round, theme and price. Answer images are marked as such,
+images that cannot be decoded are shown as placeholders.
+
+With --per-round one more sheet is written for every round, named after the
+output file, for example sheet-round1.png.`,
+	Args: cobra.ExactArgs(2),
+	Run:  runContactSheet,
+}
+
+func init() {
+	contactSheetCmd.Flags().IntVarP(&contactSheetOptions.ThumbSize, "size", "s", contactSheetOptions.ThumbSize, "Thumbnail width and height in pixels")
+	contactSheetCmd.Flags().IntVarP(&contactSheetOptions.Columns, "columns", "c", contactSheetOptions.Columns, "Number of thumbnails per row")
+	contactSheetCmd.Flags().BoolVar(&contactSheetPerRound, "per-round", false, "Also write one sheet per round")
+}
+
+func runContactSheet(cmd *cobra.Command, args []string) {
+	output := args[1]
+
+	reader, err := siq.NewSIQReader(args[0])
+	if err != nil {
+		log.Fatal("Failed to open SIQ file:", err)
+	}
+	defer reader.Close()
+
+	pkg, err := reader.Read()
+	if err != nil {
+		log.Fatal("Failed to read SIQ file:", err)
+	}
+
+	thumbs := contactsheet.Load(reader, pkg, contactSheetOptions.ThumbSize)
+	for _, thumb := range thumbs {
+		if thumb.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", thumb.Ref, thumb.Err)
+		}
+	}
+	if len(thumbs) == 0 {
+		fmt.Println("No images found")
+		return
+	}
+
+	if err := writeContactSheet(output, pkg.Name, thumbs); err != nil {
+		log.Fatal("Failed to write contact sheet:", err)
+	}
+	fmt.Printf("Wrote %s with %d image(s)\n", output, len(thumbs))
+
+	if !contactSheetPerRound {
+		return
+	}
+	ext := filepath.Ext(output)
+	for _, round := range contactsheet.ByRound(thumbs) {
+		ref := round[0].Ref
+		path := fmt.Sprintf("%s-round%d%s", strings.TrimSuffix(output, ext), ref.RoundIndex+1, ext)
+		if err := writeContactSheet(path, pkg.Name+" / "+ref.RoundName, round); err != nil {
+			log.Fatal("Failed to write contact sheet:", err)
+		}
+		fmt.Printf("Wrote %s with %d image(s)\n", path, len(round))
+	}
+}
+
+// writeContactSheet renders thumbnails and saves the sheet as PNG
+func writeContactSheet(path, title string, thumbs []contactsheet.Thumbnail) error {
+	sheet, err := contactsheet.Render(title, thumbs, contactSheetOptions)
+	if err != nil {
+		return err
+	}
+	return writePNG(path, sheet)
+}
+
+func writePNG(path string, img image.Image) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// GetContactSheetCmd returns the contact-sheet command
+func GetContactSheetCmd() *cobra.Command {
+	return contactSheetCmd
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+
+	"github.com/minmaxmean/sigma/siq/dedupe"
+	"github.com/minmaxmean/sigma/siq/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dedupeThreshold float64
+	dedupeNoMedia   bool
+	dedupeWorkers   int
+)
+
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe [siq-file-or-dir...]",
+	Short: "Find duplicate questions and media across SIQ files",
+	Long: `Find duplicate questions and identical media files within and across
+SIQ files. Directories are scanned recursively for .siq files.
+
+Questions are compared by their text and right answers after normalization
+(case, punctuation, diacritics and common stopwords are ignored). Questions
+with the same normalized text are reported as exact duplicates, questions
+whose similarity is at least the threshold are reported as near-duplicates.
+
+Media files are compared by content hash, so renamed copies are found too.`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runDedupe,
+}
+
+func init() {
+	dedupeCmd.Flags().Float64VarP(&dedupeThreshold, "threshold", "t", dedupe.DefaultThreshold, "Minimal similarity (0-1) of near-duplicate questions")
+	dedupeCmd.Flags().BoolVar(&dedupeNoMedia, "no-media", false, "Do not look for identical media files")
+	dedupeCmd.Flags().IntVarP(&dedupeWorkers, "workers", "j", runtime.NumCPU(), "Number of packages read concurrently")
+}
+
+func runDedupe(cmd *cobra.Command, args []string) {
+	if dedupeThreshold <= 0 || dedupeThreshold > 1 {
+		log.Fatal("Threshold must be between 0 and 1")
+	}
+
+	files, err := search.CollectFiles(args)
+	if err != nil {
+		log.Fatal("Failed to collect SIQ files:", err)
+	}
+
+	report := dedupe.Find(files, dedupe.Options{
+		Threshold: dedupeThreshold,
+		Media:     !dedupeNoMedia,
+		Workers:   dedupeWorkers,
+	})
+	for _, err := range report.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	if len(report.Questions) > 0 {
+		fmt.Printf("=== Duplicate Questions ===\n")
+		for i, cluster := range report.Questions {
+			kind := "exact"
+			if !cluster.Exact {
+				kind = fmt.Sprintf("similar, %.0f%%", cluster.Similarity*100)
+			}
+			fmt.Printf("\nCluster %d (%d questions, %s):\n", i+1, len(cluster.Questions), kind)
+			for _, question := range cluster.Questions {
+				fmt.Printf("  %s\n", question.Location())
+				fmt.Printf("    %s\n", question.Text)
+				if len(question.Answers) > 0 {
+					fmt.Printf("    Answer: %s\n", question.Answers[0])
+				}
+			}
+		}
+		fmt.Println()
+	}
+
+	var wasted int64
+	if len(report.Media) > 0 {
+		fmt.Printf("=== Duplicate Media ===\n")
+		for i, cluster := range report.Media {
+			wasted += cluster.Wasted()
+			fmt.Printf("\nMedia %d (%d copies, %s each, sha256 %s):\n", i+1, len(cluster.Files), formatBytes(cluster.Size), cluster.Hash[:12])
+			for _, file := range cluster.Files {
+				fmt.Printf("  %s: %s\n", file.Path, file.Name)
+			}
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("Found %d question cluster(s) and %d media cluster(s) in %d package(s)\n",
+		len(report.Questions), len(report.Media), len(files))
+	if wasted > 0 {
+		fmt.Printf("Redundant media copies take %s\n", formatBytes(wasted))
+	}
+}
+
+// GetDedupeCmd returns the dedupe command
+func GetDedupeCmd() *cobra.Command {
+	return dedupeCmd
+}
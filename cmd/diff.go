@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/minmaxmean/sigma/siq/diff"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffJSON     bool
+	diffTextconv bool
+	diffExitCode bool
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [old-siq-file] [new-siq-file]",
+	Short: "Show differences between two versions of a SIQ file",
+	Long: `Compare two versions of a SIQ file structurally. Rounds and themes are
+matched by name and questions by their text, so reordering is not reported
+as a change. Elements that cannot be matched are paired by position and
+reported as changed. The report lists:
+- Changed package metadata
+- Added, removed and renamed rounds and themes
+- Added, removed and changed questions (price, content, answers, params)
+- Added, removed, modified and renamed media files (compared by content hash)
+
+With --textconv a single file is printed as canonical text, which lets git
+show readable diffs of SIQ files:
+
+  echo '*.siq diff=siq' >> .gitattributes
+  git config diff.siq.textconv "sigma diff --textconv"`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if diffTextconv {
+			return cobra.ExactArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
+	Run: runDiff,
+}
+
+func init() {
+	diffCmd.Flags().BoolVar(&diffJSON, "json", false, "Print the differences as JSON")
+	diffCmd.Flags().BoolVar(&diffTextconv, "textconv", false, "Print a single SIQ file as text for line-based diff tools")
+	diffCmd.Flags().BoolVar(&diffExitCode, "exit-code", false, "Exit with status 1 when the files differ")
+}
+
+func runDiff(cmd *cobra.Command, args []string) {
+	if diffTextconv {
+		snapshot, err := diff.Load(args[0])
+		if err != nil {
+			log.Fatal("Failed to read SIQ file:", err)
+		}
+		if err := diff.Dump(os.Stdout, snapshot); err != nil {
+			log.Fatal("Failed to write text:", err)
+		}
+		return
+	}
+
+	oldSnapshot, err := diff.Load(args[0])
+	if err != nil {
+		log.Fatal("Failed to read old SIQ file:", err)
+	}
+	newSnapshot, err := diff.Load(args[1])
+	if err != nil {
+		log.Fatal("Failed to read new SIQ file:", err)
+	}
+
+	result := diff.Compare(oldSnapshot, newSnapshot)
+	if diffJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			log.Fatal("Failed to encode JSON:", err)
+		}
+	} else {
+		printDiff(args[0], args[1], result)
+	}
+
+	if diffExitCode && !result.IsEmpty() {
+		os.Exit(1)
+	}
+}
+
+// printDiff prints the differences in a human-readable form
+func printDiff(oldPath, newPath string, result *diff.Result) {
+	fmt.Printf("--- %s\n+++ %s\n", oldPath, newPath)
+	if result.IsEmpty() {
+		fmt.Println("\nNo differences")
+		return
+	}
+
+	if len(result.Metadata) > 0 {
+		fmt.Printf("\n=== Metadata ===\n")
+		printFieldChanges(result.Metadata, "  ")
+	}
+
+	if len(result.Rounds) > 0 {
+		fmt.Printf("\n=== Rounds ===\n")
+		for _, change := range result.Rounds {
+			printStructureChange(change, change.Round)
+		}
+	}
+
+	if len(result.Themes) > 0 {
+		fmt.Printf("\n=== Themes ===\n")
+		for _, change := range result.Themes {
+			printStructureChange(change, change.Round+" / "+change.Theme)
+		}
+	}
+
+	if len(result.Questions) > 0 {
+		fmt.Printf("\n=== Questions ===\n")
+		for _, change := range result.Questions {
+			ref := change.Ref()
+			fmt.Printf("%s %s [%s]: %s\n", changeMarker(change.Kind), ref, ref.ID(), change.Text)
+			if change.Kind == diff.Changed && change.OldRef.ID() != change.NewRef.ID() {
+				fmt.Printf("    (was %s [%s])\n", change.OldRef, change.OldRef.ID())
+			}
+			printFieldChanges(change.Fields, "    ")
+		}
+	}
+
+	if len(result.Media) > 0 {
+		fmt.Printf("\n=== Media ===\n")
+		for _, change := range result.Media {
+			switch change.Kind {
+			case diff.Added:
+				fmt.Printf("+ %s (%s)\n", change.Name, formatBytes(change.NewSize))
+			case diff.Removed:
+				fmt.Printf("- %s (%s)\n", change.Name, formatBytes(change.OldSize))
+			case diff.Renamed:
+				fmt.Printf("> %s -> %s\n", change.OldName, change.Name)
+			default:
+				fmt.Printf("~ %s (%s -> %s)\n", change.Name, formatBytes(change.OldSize), formatBytes(change.NewSize))
+			}
+		}
+	}
+}
+
+// printStructureChange prints a round or theme change
+func printStructureChange(change diff.StructureChange, name string) {
+	if change.Kind == diff.Changed {
+		fmt.Printf("%s %s\n", changeMarker(change.Kind), name)
+		printFieldChanges(change.Fields, "    ")
+		return
+	}
+	fmt.Printf("%s %s (%d questions)\n", changeMarker(change.Kind), name, change.Questions)
+}
+
+// printFieldChanges prints old and new values of changed fields
+func printFieldChanges(fields []diff.FieldChange, indent string) {
+	for _, field := range fields {
+		fmt.Printf("%s%s:\n", indent, field.Field)
+		if field.Old != "" {
+			fmt.Printf("%s  - %s\n", indent, strings.ReplaceAll(field.Old, "\n", " "))
+		}
+		if field.New != "" {
+			fmt.Printf("%s  + %s\n", indent, strings.ReplaceAll(field.New, "\n", " "))
+		}
+	}
+}
+
+// changeMarker returns a one-character marker of the change kind
+func changeMarker(kind diff.Kind) string {
+	switch kind {
+	case diff.Added:
+		return "+"
+	case diff.Removed:
+		return "-"
+	case diff.Renamed:
+		return ">"
+	default:
+		return "~"
+	}
+}
+
+// GetDiffCmd returns the diff command
+func GetDiffCmd() *cobra.Command {
+	return diffCmd
+}
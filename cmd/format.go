@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// formatContentItems renders content items as a single line of text.
+// Text items are shown as is, other items as "[type: value]".
+func formatContentItems(items []siq.ContentItem) string {
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.Type == "" || item.Type == siq.ContentTypeText {
+			parts = append(parts, item.Value)
+		} else {
+			parts = append(parts, fmt.Sprintf("[%s: %s]", item.Type, item.Value))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatBytes renders a byte count with a binary unit suffix
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
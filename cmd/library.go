@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/minmaxmean/sigma/library"
+	"github.com/minmaxmean/sigma/siq/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	libraryDir     string
+	libraryWorkers int
+	libraryPrune   bool
+	libraryLimit   int
+)
+
+var libraryCmd = &cobra.Command{
+	Use:   "library",
+	Short: "Manage a persistent index of many SIQ files",
+	Long: `Index SIQ files into an on-disk library and search it:
+- add: index files and directories (only changed files are re-indexed)
+- search: full-text search over package metadata, questions and answers
+- list: list indexed packages
+- stats: show library statistics
+
+The library is stored in $SIGMA_LIBRARY or ~/.sigma/library unless --dir is given.`,
+}
+
+var libraryAddCmd = &cobra.Command{
+	Use:   "add [siq-file-or-dir...]",
+	Short: "Index SIQ files into the library",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   runLibraryAdd,
+}
+
+var librarySearchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search the library (use term* for prefix search)",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   runLibrarySearch,
+}
+
+var libraryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List indexed packages",
+	Args:  cobra.NoArgs,
+	Run:   runLibraryList,
+}
+
+var libraryStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show library statistics",
+	Args:  cobra.NoArgs,
+	Run:   runLibraryStats,
+}
+
+func init() {
+	libraryCmd.PersistentFlags().StringVarP(&libraryDir, "dir", "d", defaultLibraryDir(), "Library directory")
+	libraryAddCmd.Flags().IntVarP(&libraryWorkers, "workers", "j", runtime.NumCPU(), "Number of files indexed concurrently")
+	libraryAddCmd.Flags().BoolVar(&libraryPrune, "prune", false, "Remove packages whose files no longer exist")
+	librarySearchCmd.Flags().IntVarP(&libraryLimit, "limit", "n", 50, "Maximum number of hits (0 for all)")
+
+	libraryCmd.AddCommand(libraryAddCmd)
+	libraryCmd.AddCommand(librarySearchCmd)
+	libraryCmd.AddCommand(libraryListCmd)
+	libraryCmd.AddCommand(libraryStatsCmd)
+}
+
+// defaultLibraryDir returns the library location from the environment or the home directory
+func defaultLibraryDir() string {
+	if dir := os.Getenv("SIGMA_LIBRARY"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".sigma-library"
+	}
+	return filepath.Join(home, ".sigma", "library")
+}
+
+// openLibrary opens the library selected by the --dir flag
+func openLibrary() *library.Library {
+	lib, err := library.Open(libraryDir)
+	if err != nil {
+		log.Fatal("Failed to open library:", err)
+	}
+	return lib
+}
+
+func runLibraryAdd(cmd *cobra.Command, args []string) {
+	lib := openLibrary()
+	defer lib.Close()
+
+	files, err := search.CollectFiles(args)
+	if err != nil {
+		log.Fatal("Failed to collect SIQ files:", err)
+	}
+
+	result := lib.Add(files, libraryWorkers)
+	for _, err := range result.Errors {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	removed := 0
+	if libraryPrune {
+		if removed, err = lib.Prune(); err != nil {
+			log.Fatal("Failed to prune library:", err)
+		}
+	}
+
+	fmt.Printf("Added: %d, updated: %d, unchanged: %d, removed: %d, failed: %d\n",
+		result.Added, result.Updated, result.Unchanged, removed, len(result.Errors))
+}
+
+func runLibrarySearch(cmd *cobra.Command, args []string) {
+	lib := openLibrary()
+	defer lib.Close()
+
+	hits, err := lib.Search(strings.Join(args, " "), libraryLimit)
+	if err != nil {
+		log.Fatal("Failed to search library:", err)
+	}
+	for _, hit := range hits {
+		if hit.Entry == nil {
+			fmt.Printf("%s: package \"%s\"\n", hit.Package.Path, hit.Package.Name)
+			continue
+		}
+		fmt.Printf("%s: %s [%s]\n", hit.Package.Path, hit.Entry.Location, hit.Entry.ID)
+		if hit.Entry.Question != "" {
+			fmt.Printf("  Q: %s\n", hit.Entry.Question)
+		}
+		if len(hit.Entry.Answers) > 0 {
+			fmt.Printf("  A: %s\n", strings.Join(hit.Entry.Answers, "; "))
+		}
+	}
+
+	fmt.Printf("\nFound %d hit(s)\n", len(hits))
+}
+
+func runLibraryList(cmd *cobra.Command, args []string) {
+	lib := openLibrary()
+	defer lib.Close()
+
+	records, err := lib.Packages()
+	if err != nil {
+		log.Fatal("Failed to list packages:", err)
+	}
+	for _, record := range records {
+		fmt.Printf("%s\n", record.Path)
+		fmt.Printf("  Name: %s\n", record.Name)
+		if record.Publisher != "" {
+			fmt.Printf("  Publisher: %s\n", record.Publisher)
+		}
+		if len(record.Authors) > 0 {
+			fmt.Printf("  Authors: %s\n", strings.Join(record.Authors, ", "))
+		}
+		if len(record.Tags) > 0 {
+			fmt.Printf("  Tags: %s\n", strings.Join(record.Tags, ", "))
+		}
+		fmt.Printf("  Rounds: %d, themes: %d, questions: %d, media files: %d\n",
+			record.Rounds, record.Themes, record.Questions, len(record.Media))
+	}
+}
+
+func runLibraryStats(cmd *cobra.Command, args []string) {
+	lib := openLibrary()
+	defer lib.Close()
+
+	stats, err := lib.Stats()
+	if err != nil {
+		log.Fatal("Failed to compute library statistics:", err)
+	}
+
+	fmt.Printf("=== Library Statistics ===\n")
+	fmt.Printf("Packages: %d\n", stats.Packages)
+	fmt.Printf("Rounds: %d\n", stats.Rounds)
+	fmt.Printf("Themes: %d\n", stats.Themes)
+	fmt.Printf("Questions: %d\n", stats.Questions)
+	fmt.Printf("Total size: %s\n", formatBytes(stats.TotalSize))
+
+	fmt.Printf("\n=== Media ===\n")
+	for _, mediaType := range sortedKeys(stats.MediaFiles) {
+		fmt.Printf("%s: %d file(s), %s\n", mediaType, stats.MediaFiles[mediaType], formatBytes(stats.MediaSize[mediaType]))
+	}
+
+	fmt.Printf("\n=== Languages ===\n")
+	for _, language := range sortedKeys(stats.Languages) {
+		fmt.Printf("%s: %d\n", language, stats.Languages[language])
+	}
+
+	fmt.Printf("\n=== Top Tags ===\n")
+	for i, tag := range stats.Tags {
+		if i == 20 {
+			break
+		}
+		fmt.Printf("%s: %d\n", tag.Tag, tag.Count)
+	}
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetLibraryCmd returns the library command
+func GetLibraryCmd() *cobra.Command {
+	return libraryCmd
+}
@@ -6,7 +6,6 @@ import (
 	"os"
 	"strings"
 
-	"github.com/kr/pretty"
 	"github.com/minmaxmean/sigma/siq"
 	"github.com/spf13/cobra"
 )
@@ -49,7 +48,7 @@ func runMarkdown(cmd *cobra.Command, args []string) {
 	}
 
 	// Generate markdown content
-	markdown := generateMarkdown(pkg, reader.GetVersion())
+	markdown := generateMarkdown(pkg)
 
 	// Write to output file
 	err = os.WriteFile(outputFile, []byte(markdown), 0644)
@@ -67,118 +66,107 @@ func hasMediaContent(question *siq.Question) bool {
 		switch item.Type {
 		case siq.ContentTypeImage, siq.ContentTypeAudio, siq.ContentTypeVideo, siq.ContentTypeVoice:
 			return true
-		case "", siq.ContentTypeMarker:
-		default:
-			pretty.Printf("question: %+v\n", item)
+		case "", siq.ContentTypeText, siq.ContentTypeMarker:
+			// Text and markers are not media
 		}
 	}
 	return false
 }
 
-func generateMarkdown(pkg *siq.Package, version int) string {
+func generateMarkdown(pkg *siq.Package) string {
 	var sb strings.Builder
 
-	// Process rounds
-	rounds := pkg.Rounds
-	if version == 4 {
-		// Convert v4 rounds to v5 format for processing
-		rounds = make([]siq.Round, len(pkg.RoundsV4))
-		for i, roundV4 := range pkg.RoundsV4 {
-			rounds[i] = convertV4RoundToV5(roundV4)
+	// Every round gets a header, also when it has no questions. Theme
+	// headers are written before their first question, so themes with no
+	// questions left after filtering are skipped.
+	lastRound, lastTheme := -1, -1
+	// writeRounds writes the headers of the rounds after the last written one up to index
+	writeRounds := func(index int) {
+		for lastRound < index && lastRound+1 < len(pkg.Rounds) {
+			lastRound++
+			fmt.Fprintf(&sb, "## Round %d: %s\n\n", lastRound+1, pkg.Rounds[lastRound].Name)
 		}
 	}
+	questionNumber := 0
 
-	for roundIndex, round := range rounds {
-		fmt.Fprintf(&sb, "## Round %d: %s\n\n", roundIndex+1, round.Name)
-
-		// Process themes
-		for themeIndex, theme := range round.Themes {
-			// Filter questions if skip-media flag is set
-			var filteredQuestions []siq.Question
-			if skipMedia {
-				for _, question := range theme.Questions {
-					if !hasMediaContent(&question) {
-						filteredQuestions = append(filteredQuestions, question)
-					}
-				}
-			} else {
-				filteredQuestions = theme.Questions
-			}
-
-			// Skip themes with no questions after filtering
-			if len(filteredQuestions) == 0 {
-				continue
-			}
+	pkg.Walk(func(ref siq.QuestionRef, question *siq.Question) error {
+		// Filter questions if skip-media flag is set
+		if skipMedia && hasMediaContent(question) {
+			return nil
+		}
 
-			fmt.Fprintf(&sb, "### Theme %d: %s\n\n", themeIndex+1, theme.Name)
-
-			// Process questions with original numbering
-			questionNumber := 1
-			for _, question := range filteredQuestions {
-				fmt.Fprintf(&sb, "#### Question %d\n\n", questionNumber)
-
-				// Question content
-				content := question.GetQuestionContent()
-				if len(content) > 0 {
-					fmt.Fprintf(&sb, "**Content**:\n\n")
-					for _, item := range content {
-						fmt.Fprintf(&sb, "- %s", item.Value)
-						if item.Duration > 0 {
-							fmt.Fprintf(&sb, " (duration: %d)", item.Duration)
-						}
-						if item.Placement != "" && item.Placement != "screen" {
-							fmt.Fprintf(&sb, " (placement: %s)", item.Placement)
-						}
-						fmt.Fprintf(&sb, "\n")
-					}
-					fmt.Fprintf(&sb, "\n")
-				}
-
-				// Right answers
-				if len(question.Right) > 0 {
-					fmt.Fprintf(&sb, "**Right Answer")
-					if len(question.Right) > 1 {
-						fmt.Fprintf(&sb, "s")
-					}
-					fmt.Fprintf(&sb, "**:\n\n")
-
-					if len(question.Right) == 1 {
-						fmt.Fprintf(&sb, "%s\n\n", question.Right[0])
-					} else {
-						for i, answer := range question.Right {
-							fmt.Fprintf(&sb, "%d. %s\n", i+1, answer)
-						}
-						fmt.Fprintf(&sb, "\n")
-					}
-				}
-
-				fmt.Fprintf(&sb, "---\n\n")
-				questionNumber++
-			}
+		if ref.RoundIndex != lastRound {
+			writeRounds(ref.RoundIndex)
+			lastTheme = -1
 		}
-	}
+		if ref.ThemeIndex != lastTheme {
+			fmt.Fprintf(&sb, "### Theme %d: %s\n\n", ref.ThemeIndex+1, ref.ThemeName)
+			lastTheme = ref.ThemeIndex
+			questionNumber = 0
+		}
+
+		questionNumber++
+		writeQuestionMarkdown(&sb, questionNumber, question)
+		return nil
+	})
+	writeRounds(len(pkg.Rounds) - 1)
 
 	return sb.String()
 }
 
-func convertV4RoundToV5(roundV4 siq.RoundV4) siq.Round {
-	themes := make([]siq.Theme, len(roundV4.Themes))
-	for i, themeV4 := range roundV4.Themes {
-		questions := make([]siq.Question, len(themeV4.Questions))
-		for j, questionV4 := range themeV4.Questions {
-			questions[j] = siq.ConvertV4ToV5Question(questionV4)
+// writeQuestionMarkdown writes a single question with its content, options and answers
+func writeQuestionMarkdown(sb *strings.Builder, number int, question *siq.Question) {
+	fmt.Fprintf(sb, "#### Question %d\n\n", number)
+
+	// Question content
+	content := question.GetQuestionContent()
+	if len(content) > 0 {
+		fmt.Fprintf(sb, "**Content**:\n\n")
+		for _, item := range content {
+			fmt.Fprintf(sb, "- %s", item.Value)
+			if item.Duration > 0 {
+				fmt.Fprintf(sb, " (duration: %d)", item.Duration)
+			}
+			if item.GetPlacement() != siq.PlacementScreen {
+				fmt.Fprintf(sb, " (placement: %s)", item.Placement)
+			}
+			fmt.Fprintf(sb, "\n")
 		}
-		themes[i] = siq.Theme{
-			Name:      themeV4.Name,
-			Info:      themeV4.Info,
-			Questions: questions,
+		fmt.Fprintf(sb, "\n")
+	}
+
+	// Answer options of select questions
+	if options := question.AnswerOptions(); len(options) > 0 {
+		fmt.Fprintf(sb, "**Options**:\n\n")
+		for _, option := range options {
+			fmt.Fprintf(sb, "- %s) %s", option.Label, formatContentItems(option.Items))
+			if question.IsRightOption(option.Label) {
+				fmt.Fprintf(sb, " **(right)**")
+			}
+			fmt.Fprintf(sb, "\n")
 		}
+		fmt.Fprintf(sb, "\n")
 	}
-	return siq.Round{
-		Name:   roundV4.Name,
-		Info:   roundV4.Info,
-		Themes: themes,
+
+	// Right answers
+	if len(question.Right) > 0 {
+		fmt.Fprintf(sb, "**Right Answer")
+		if len(question.Right) > 1 {
+			fmt.Fprintf(sb, "s")
+		}
+		fmt.Fprintf(sb, "**:\n\n")
+
+		if len(question.Right) == 1 {
+			fmt.Fprintf(sb, "%s\n\n", question.Right[0])
+		} else {
+			for i, answer := range question.Right {
+				fmt.Fprintf(sb, "%d. %s\n", i+1, answer)
+			}
+			fmt.Fprintf(sb, "\n")
+		}
 	}
+
+	fmt.Fprintf(sb, "---\n\n")
 }
 
 // GetMarkdownCmd returns the markdown command
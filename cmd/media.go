@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/minmaxmean/sigma/siq"
+	"github.com/minmaxmean/sigma/siq/inventory"
+	"github.com/minmaxmean/sigma/siq/media"
+	"github.com/spf13/cobra"
+)
+
+var mediaProbe bool
+
+var mediaCmd = &cobra.Command{
+	Use:   "media [siq-file]",
+	Short: "List media files of a SIQ file and the questions using them",
+	Long: `Cross-reference the media files of a SIQ file with the content items
+and the logo referring to them. The report shows:
+- Every media file with its size and the questions using it
+- Referenced files missing from the archive
+- Archive files nothing refers to
+- Media items pointing to external URLs
+- File count and size per content type
+
+URI-encoded names are decoded and media folder names are matched
+case-insensitively, both for version 5 items and version 4 "@" atoms.
+
+With --probe the files are also read to show their actual format,
+dimensions, duration and bitrate, and to flag mismatches: a file whose
+extension does not match its content, an item whose type does not match the
+file it refers to and a declared duration that differs from the file.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runMedia,
+}
+
+func init() {
+	mediaCmd.Flags().BoolVarP(&mediaProbe, "probe", "p", false, "Read file headers and check types and durations")
+}
+
+func runMedia(cmd *cobra.Command, args []string) {
+	reader, err := siq.NewSIQReader(args[0])
+	if err != nil {
+		log.Fatal("Failed to open SIQ file:", err)
+	}
+	defer reader.Close()
+
+	inv, err := inventory.Build(reader)
+	if err != nil {
+		log.Fatal("Failed to read SIQ file:", err)
+	}
+
+	issueCount := 0
+	fmt.Printf("=== Media Files ===\n")
+	for _, asset := range inv.Files {
+		if !mediaProbe {
+			fmt.Printf("%s (%s)\n", asset.Path, formatBytes(asset.Size))
+			printUses(asset.Uses)
+			continue
+		}
+
+		issues, info, err := probeAsset(reader, asset)
+		if err != nil {
+			fmt.Printf("%s (%s, %v)\n", asset.Path, formatBytes(asset.Size), err)
+		} else {
+			fmt.Printf("%s (%s, %s)\n", asset.Path, formatBytes(asset.Size), info)
+		}
+		printUses(asset.Uses)
+		for _, issue := range issues {
+			fmt.Printf("  ! %s\n", issue)
+		}
+		issueCount += len(issues)
+	}
+
+	if len(inv.Missing) > 0 {
+		fmt.Printf("\n=== Missing Files ===\n")
+		for _, asset := range inv.Missing {
+			if asset.Hint != "" {
+				fmt.Printf("%s (found %s)\n", asset.Path, asset.Hint)
+			} else {
+				fmt.Printf("%s\n", asset.Path)
+			}
+			printUses(asset.Uses)
+		}
+	}
+
+	if unreferenced := inv.Unreferenced(); len(unreferenced) > 0 {
+		fmt.Printf("\n=== Unreferenced Files ===\n")
+		for _, asset := range unreferenced {
+			fmt.Printf("%s (%s)\n", asset.Path, formatBytes(asset.Size))
+		}
+	}
+
+	if len(inv.External) > 0 {
+		fmt.Printf("\n=== External Files ===\n")
+		for _, asset := range inv.External {
+			fmt.Printf("%s [%s]\n", asset.Path, asset.Type)
+			printUses(asset.Uses)
+		}
+	}
+
+	fmt.Printf("\n=== Summary ===\n")
+	var files int
+	var size int64
+	for _, stats := range inv.Types {
+		fmt.Printf("%-6s %4d file(s) %10s", stats.Type, stats.Files, formatBytes(stats.Size))
+		if stats.Unreferenced > 0 {
+			fmt.Printf(", %d unreferenced", stats.Unreferenced)
+		}
+		fmt.Println()
+		files += stats.Files
+		size += stats.Size
+	}
+	fmt.Printf("Total: %d file(s), %s, %d missing, %d external\n",
+		files, formatBytes(size), len(inv.Missing), len(inv.External))
+	if mediaProbe {
+		fmt.Printf("Issues: %d\n", issueCount)
+	}
+}
+
+// probeAsset reads an archive file and checks it against its name and the
+// items referring to it
+func probeAsset(reader *siq.SIQReader, asset inventory.Asset) ([]media.Issue, media.Info, error) {
+	file, err := reader.GetFile(asset.Entry)
+	if err != nil {
+		return nil, media.Info{}, err
+	}
+	// Files with broken headers are still checked against their format
+	info, err := media.ProbeFile(file)
+	if err != nil && info.Format == media.FormatUnknown {
+		return nil, info, err
+	}
+
+	issues := media.CheckFile(asset.Path, info)
+	for _, use := range asset.Uses {
+		for _, issue := range media.CheckItem(use.Item, info) {
+			issue.Message += " at " + use.String()
+			issues = append(issues, issue)
+		}
+	}
+	return issues, info, err
+}
+
+// printUses prints the places referring to a media file
+func printUses(uses []inventory.Use) {
+	if len(uses) == 0 {
+		fmt.Printf("  (unreferenced)\n")
+	}
+	for _, use := range uses {
+		fmt.Printf("  %s\n", use)
+	}
+}
+
+// GetMediaCmd returns the media command
+func GetMediaCmd() *cobra.Command {
+	return mediaCmd
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/minmaxmean/sigma/siq/merge"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mergeOutput     string
+	mergeInterleave bool
+	mergeName       string
+	splitOutputDir  string
+	splitPerRound   bool
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge [siq-file...] -o [output-siq-file]",
+	Short: "Merge several SIQ files into one",
+	Long: `Merge several SIQ files into a single package. Rounds are concatenated
+in the order of the files (or interleaved with --interleave), final rounds
+are moved to the end.
+
+Global authors and sources are merged: entries with the same name are stored
+once and colliding ids are renamed. Media files with the same name but
+different content are renamed and the questions referencing them are
+rewritten, identical files are stored once.`,
+	Args: cobra.MinimumNArgs(2),
+	Run:  runMerge,
+}
+
+var splitCmd = &cobra.Command{
+	Use:   "split [siq-file] --per-round",
+	Short: "Split a SIQ file into one package per round",
+	Long: `Split a SIQ file into one package per round. Each package keeps the
+metadata of the original and contains only the media files its round uses.
+Packages are named after the input file, for example pack-round1.siq.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSplit,
+}
+
+func init() {
+	mergeCmd.Flags().StringVarP(&mergeOutput, "output", "o", "", "Output SIQ file")
+	mergeCmd.Flags().BoolVar(&mergeInterleave, "interleave", false, "Take rounds from the files in turn instead of concatenating them")
+	mergeCmd.Flags().StringVarP(&mergeName, "name", "n", "", "Name of the merged package (default: names of the inputs joined)")
+	mergeCmd.MarkFlagRequired("output")
+
+	splitCmd.Flags().StringVarP(&splitOutputDir, "output-dir", "o", ".", "Directory for the resulting SIQ files")
+	splitCmd.Flags().BoolVar(&splitPerRound, "per-round", false, "Write one package per round")
+	splitCmd.MarkFlagRequired("per-round")
+}
+
+func runMerge(cmd *cobra.Command, args []string) {
+	result, err := merge.Merge(args, mergeOutput, merge.Options{
+		Interleave: mergeInterleave,
+		Name:       mergeName,
+	})
+	if err != nil {
+		log.Fatal("Failed to merge SIQ files:", err)
+	}
+
+	for _, rename := range result.RenamedFiles {
+		fmt.Printf("Renamed %s from %s to %s\n", rename.Old, rename.Source, rename.New)
+	}
+	for _, rename := range result.RenamedIDs {
+		fmt.Printf("Renamed global id %s from %s to %s\n", rename.Old, rename.Source, rename.New)
+	}
+	for _, missing := range result.Missing {
+		fmt.Fprintf(os.Stderr, "Missing media file %s\n", missing)
+	}
+
+	fmt.Printf("Merged %d package(s) into %s: %d round(s), %d question(s), %d media file(s)",
+		len(args), mergeOutput, result.Rounds, result.Questions, result.Files)
+	if result.SharedFiles > 0 {
+		fmt.Printf(", %d identical file(s) stored once", result.SharedFiles)
+	}
+	fmt.Println()
+}
+
+func runSplit(cmd *cobra.Command, args []string) {
+	if err := os.MkdirAll(splitOutputDir, 0755); err != nil {
+		log.Fatal("Failed to create output directory:", err)
+	}
+
+	results, err := merge.Split(args[0], splitOutputDir)
+	for _, result := range results {
+		fmt.Printf("%s: %s (%d question(s), %d media file(s))\n", result.Path, result.Round, result.Questions, result.Files)
+		for _, missing := range result.Missing {
+			fmt.Fprintf(os.Stderr, "  Missing media file %s\n", missing)
+		}
+	}
+	if err != nil {
+		log.Fatal("Failed to split SIQ file:", err)
+	}
+}
+
+// GetMergeCmd returns the merge command
+func GetMergeCmd() *cobra.Command {
+	return mergeCmd
+}
+
+// GetSplitCmd returns the split command
+func GetSplitCmd() *cobra.Command {
+	return splitCmd
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/minmaxmean/sigma/siq/optimize"
+	"github.com/spf13/cobra"
+)
+
+var optimizeOptions = optimize.DefaultOptions()
+
+var optimizeCmd = &cobra.Command{
+	Use:   "optimize [input-siq-file] [output-siq-file]",
+	Short: "Reduce the size of a SIQ file",
+	Long: `Write a smaller copy of a SIQ file:
+- Images larger than the maximal dimension are resized
+- JPEG and PNG images are re-encoded, the original is kept when it is smaller
+- Opaque PNG images are converted to JPEG with --png-to-jpeg
+- Media files no question refers to are removed
+- Identical media files are stored once
+
+Already compressed images, audio and video are stored without compression,
+other files are compressed with the given deflate level.`,
+	Args: cobra.ExactArgs(2),
+	Run:  runOptimize,
+}
+
+func init() {
+	optimizeCmd.Flags().IntVar(&optimizeOptions.MaxDimension, "max-dimension", optimizeOptions.MaxDimension, "Maximal image width and height in pixels, 0 disables resizing")
+	optimizeCmd.Flags().IntVarP(&optimizeOptions.JPEGQuality, "quality", "q", optimizeOptions.JPEGQuality, "Quality of re-encoded JPEG images (1-100)")
+	optimizeCmd.Flags().BoolVar(&optimizeOptions.PNGToJPEG, "png-to-jpeg", false, "Convert opaque PNG images to JPEG")
+	optimizeCmd.Flags().BoolVar(&optimizeOptions.KeepUnreferenced, "keep-unreferenced", false, "Keep media files no question refers to")
+	optimizeCmd.Flags().StringSliceVar(&optimizeOptions.Store, "store", optimizeOptions.Store, "Content types stored without compression")
+	optimizeCmd.Flags().IntVarP(&optimizeOptions.Level, "level", "l", optimizeOptions.Level, "Deflate level of compressed files (-2-9)")
+}
+
+func runOptimize(cmd *cobra.Command, args []string) {
+	report, err := optimize.Optimize(args[0], args[1], optimizeOptions)
+	if err != nil {
+		log.Fatal("Failed to optimize SIQ file:", err)
+	}
+
+	for _, file := range report.Files {
+		switch file.Action {
+		case optimize.ActionKept:
+			continue
+		case optimize.ActionUnreferenced:
+			fmt.Printf("%-12s %s (%s)\n", file.Action, file.Name, formatBytes(file.OldSize))
+		case optimize.ActionDuplicate:
+			fmt.Printf("%-12s %s -> %s (%s)\n", file.Action, file.Name, file.NewName, formatBytes(file.OldSize))
+		default:
+			name := file.Name
+			if file.NewName != file.Name {
+				name += " -> " + file.NewName
+			}
+			fmt.Printf("%-12s %s (%s -> %s, saved %s)\n", file.Action, name,
+				formatBytes(file.OldSize), formatBytes(file.NewSize), formatBytes(file.Saved()))
+		}
+	}
+
+	saved := report.OldSize - report.NewSize
+	percent := 0.0
+	if report.OldSize > 0 {
+		percent = float64(saved) / float64(report.OldSize) * 100
+	}
+	fmt.Printf("\n%s: %s -> %s (saved %s, %.1f%%)\n", args[1],
+		formatBytes(report.OldSize), formatBytes(report.NewSize), formatBytes(saved), percent)
+}
+
+// GetOptimizeCmd returns the optimize command
+func GetOptimizeCmd() *cobra.Command {
+	return optimizeCmd
+}
@@ -41,7 +41,7 @@ func runRead(cmd *cobra.Command, args []string) {
 	fmt.Printf("Name: %s\n", pkg.Name)
 	fmt.Printf("ID: %s\n", pkg.ID)
 	fmt.Printf("Version: %s\n", pkg.Version)
-	fmt.Printf("Detected Format: v%d\n", reader.GetVersion())
+	fmt.Printf("Detected Format: v%d\n", pkg.FormatVersion)
 	fmt.Printf("Difficulty: %d/10\n", pkg.Difficulty)
 	fmt.Printf("Language: %s\n", pkg.Language)
 	fmt.Printf("Publisher: %s\n", pkg.Publisher)
@@ -53,6 +53,12 @@ func runRead(cmd *cobra.Command, args []string) {
 	fmt.Printf("Themes: %d\n", pkg.GetThemeCount())
 	fmt.Printf("Questions: %d\n", pkg.GetQuestionCount())
 
+	// Display parameter validation problems
+	if err := pkg.Validate(); err != nil {
+		fmt.Printf("\n=== Validation Warnings ===\n")
+		fmt.Printf("%v\n", err)
+	}
+
 	// Display files in archive
 	fmt.Printf("\n=== Files in Archive ===\n")
 	files := reader.ListFiles()
@@ -62,9 +68,13 @@ func runRead(cmd *cobra.Command, args []string) {
 
 	// Display questions summary
 	fmt.Printf("\n=== Questions Summary ===\n")
-	questions := pkg.GetAllQuestions()
-	for i, question := range questions {
-		fmt.Printf("Question %d:\n", i+1)
+	questionNumber := 0
+	pkg.Walk(func(ref siq.QuestionRef, question *siq.Question) error {
+		questionNumber++
+		fmt.Printf("Question %d [%s]:\n", questionNumber, ref.ID())
+		fmt.Printf("  Round %d: %s\n", ref.RoundIndex+1, ref.RoundName)
+		fmt.Printf("  Theme %d: %s\n", ref.ThemeIndex+1, ref.ThemeName)
+		fmt.Printf("  Price: %s\n", ref.Price)
 		fmt.Printf("  Type: %s\n", question.Type)
 		fmt.Printf("  Right answers: %d\n", len(question.Right))
 		fmt.Printf("  Wrong answers: %d\n", len(question.Wrong))
@@ -93,8 +103,21 @@ func runRead(cmd *cobra.Command, args []string) {
 				fmt.Printf("    Item %d: %s (%s)\n", j+1, item.Type, item.Value)
 			}
 		}
+
+		// Show answer options of select questions
+		if options := question.AnswerOptions(); len(options) > 0 {
+			fmt.Printf("  Answer options:\n")
+			for _, option := range options {
+				mark := ""
+				if question.IsRightOption(option.Label) {
+					mark = " [right]"
+				}
+				fmt.Printf("    %s) %s%s\n", option.Label, formatContentItems(option.Items), mark)
+			}
+		}
 		fmt.Println()
-	}
+		return nil
+	})
 
 	fmt.Println("SIQ file processed successfully!")
 }
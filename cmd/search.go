@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+
+	"github.com/minmaxmean/sigma/siq/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchMode    string
+	searchWorkers int
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search [query] [siq-file-or-dir...]",
+	Short: "Search questions and answers across SIQ files",
+	Long: `Search one or many SIQ files for a query. Directories are scanned
+recursively for .siq files. The search looks at:
+- Question text items and answer text items
+- Right and wrong answers
+- Theme and round names
+
+Matching is case- and diacritic-insensitive (for example "елка" matches "Ёлка").
+Modes: substring (default), regex, fuzzy (tolerates small typos).`,
+	Args: cobra.MinimumNArgs(2),
+	Run:  runSearch,
+}
+
+func init() {
+	searchCmd.Flags().StringVarP(&searchMode, "mode", "m", search.ModeSubstring, "Matching mode: substring, regex or fuzzy")
+	searchCmd.Flags().IntVarP(&searchWorkers, "workers", "j", runtime.NumCPU(), "Number of packages searched concurrently")
+}
+
+func runSearch(cmd *cobra.Command, args []string) {
+	query := args[0]
+
+	matcher, err := search.NewMatcher(query, searchMode)
+	if err != nil {
+		log.Fatal("Invalid query:", err)
+	}
+
+	files, err := search.CollectFiles(args[1:])
+	if err != nil {
+		log.Fatal("Failed to collect SIQ files:", err)
+	}
+
+	hitCount, packCount := 0, 0
+	for _, result := range search.SearchFiles(files, matcher, searchWorkers) {
+		if result.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", result.Path, result.Err)
+		}
+		if len(result.Hits) == 0 {
+			continue
+		}
+
+		packCount++
+		for _, hit := range result.Hits {
+			hitCount++
+			fmt.Printf("%s: %s [%s] %s\n", hit.Path, hit.Location(), hit.Field, hit.Text)
+		}
+	}
+
+	fmt.Printf("\nFound %d hit(s) in %d of %d package(s)\n", hitCount, packCount, len(files))
+}
+
+// GetSearchCmd returns the search command
+func GetSearchCmd() *cobra.Command {
+	return searchCmd
+}
@@ -0,0 +1,54 @@
+// Package siqtest creates SIQ package files for tests
+package siqtest
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// CreateFile writes a SIQ file named name in dir with the given content.xml
+// and media files, keyed by their archive path, and returns its path
+func CreateFile(t testing.TB, dir, name, contentXML string, media map[string]string) string {
+	t.Helper()
+	files := map[string][]byte{"content.xml": []byte(contentXML)}
+	for fileName, data := range media {
+		files[fileName] = []byte(data)
+	}
+	path := filepath.Join(dir, name)
+	WriteArchive(t, path, files)
+	return path
+}
+
+// WriteArchive writes a zip archive with the given files to path. Files are
+// stored in name order, so archives with equal files are identical.
+func WriteArchive(t testing.TB, path string, files map[string][]byte) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal("Failed to create test file:", err)
+	}
+	defer file.Close()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zipWriter := zip.NewWriter(file)
+	for _, name := range names {
+		writer, err := zipWriter.Create(name)
+		if err != nil {
+			t.Fatal("Failed to create file in zip:", err)
+		}
+		if _, err := writer.Write(files[name]); err != nil {
+			t.Fatal("Failed to write file in zip:", err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal("Failed to close zip:", err)
+	}
+}
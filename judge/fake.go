@@ -0,0 +1,37 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/minmaxmean/sigma/ollama"
+)
+
+// FakeClient is a Generator returning prepared responses, for tests
+type FakeClient struct {
+	mu sync.Mutex
+	// Responses are returned in order, one per request
+	Responses []string
+	// Err is returned instead of a response when set
+	Err error
+	// Requests records the received requests
+	Requests []ollama.GenerateRequest
+}
+
+// Generate records the request and returns the next prepared response
+func (f *FakeClient) Generate(ctx context.Context, req ollama.GenerateRequest) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Requests = append(f.Requests, req)
+	if f.Err != nil {
+		return "", f.Err
+	}
+	if len(f.Responses) == 0 {
+		return "", errors.New("no prepared response left")
+	}
+	response := f.Responses[0]
+	f.Responses = f.Responses[1:]
+	return response, nil
+}
@@ -0,0 +1,170 @@
+// Package judge decides whether a typed player answer matches the right
+// answers of a question. Clear cases are decided by normalized and fuzzy
+// matching, ambiguous ones are passed to a language model.
+package judge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/minmaxmean/sigma/ollama"
+)
+
+// Verdict is the decision about a player answer
+type Verdict string
+
+// Possible verdicts
+const (
+	VerdictCorrect   Verdict = "correct"
+	VerdictIncorrect Verdict = "incorrect"
+	// VerdictUnsure is returned for ambiguous answers when no model is configured
+	VerdictUnsure Verdict = "unsure"
+)
+
+// Default similarity thresholds of fuzzy matching
+const (
+	DefaultAcceptThreshold = 0.85
+	DefaultRejectThreshold = 0.4
+)
+
+// Generator generates text for a prompt, *ollama.Client implements it
+type Generator interface {
+	Generate(ctx context.Context, req ollama.GenerateRequest) (string, error)
+}
+
+// Judge checks player answers
+type Judge struct {
+	client Generator
+	model  string
+	// AcceptThreshold is the similarity to a right answer from which an
+	// answer is accepted without asking the model
+	AcceptThreshold float64
+	// RejectThreshold is the similarity to all right answers below which
+	// an answer is rejected without asking the model
+	RejectThreshold float64
+}
+
+// New creates a judge asking model through client on ambiguous answers.
+// With a nil client ambiguous answers get VerdictUnsure.
+func New(client Generator, model string) *Judge {
+	return &Judge{
+		client:          client,
+		model:           model,
+		AcceptThreshold: DefaultAcceptThreshold,
+		RejectThreshold: DefaultRejectThreshold,
+	}
+}
+
+// systemPrompt instructs the model how to judge
+const systemPrompt = `You are the judge of a trivia game. Decide whether the player's answer
+means the same as one of the right answers. Accept typos, transliterations,
+synonyms, missing first names and different word order. Reject answers that
+are too vague, name a different thing or match one of the wrong answers.
+Respond with JSON only.`
+
+// responseSchema is the JSON schema of the model response
+var responseSchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"verdict": {"type": "string", "enum": ["correct", "incorrect"]},
+		"confidence": {"type": "number", "minimum": 0, "maximum": 1},
+		"rationale": {"type": "string"}
+	},
+	"required": ["verdict", "confidence", "rationale"]
+}`)
+
+// modelResponse is the structured response of the model
+type modelResponse struct {
+	Verdict    Verdict `json:"verdict"`
+	Confidence float64 `json:"confidence"`
+	Rationale  string  `json:"rationale"`
+}
+
+// Judge decides whether playerAnswer is a right answer to the question and
+// returns the verdict, the confidence from 0 to 1 and a short rationale
+func (j *Judge) Judge(ctx context.Context, question string, rightAnswers, wrongAnswers []string, playerAnswer string) (Verdict, float64, string, error) {
+	player := normalize(playerAnswer)
+	if player == "" {
+		return VerdictIncorrect, 1, "empty answer", nil
+	}
+
+	// Numbers are compared by value before punctuation is dropped, so "03"
+	// and "3" or "2.5" and "2,50" are the same answer but "-5" and "5" are not
+	if value, ok := parseNumber(playerAnswer); ok && hasNumbers(rightAnswers) {
+		if match := numberMatch(rightAnswers, value); match != "" {
+			return VerdictCorrect, 1, fmt.Sprintf("same number as right answer %q", match), nil
+		}
+		return VerdictIncorrect, 1, fmt.Sprintf("number differs from right answer %q", rightAnswers[0]), nil
+	}
+
+	right, rightScore := bestMatch(rightAnswers, player)
+	wrong, wrongScore := bestMatch(wrongAnswers, player)
+
+	switch {
+	case rightScore == 1:
+		return VerdictCorrect, 1, fmt.Sprintf("matches right answer %q", right), nil
+	case wrongScore == 1:
+		return VerdictIncorrect, 1, fmt.Sprintf("matches wrong answer %q", wrong), nil
+	case rightScore >= j.AcceptThreshold && rightScore > wrongScore:
+		return VerdictCorrect, rightScore, fmt.Sprintf("close to right answer %q", right), nil
+	case wrongScore >= j.AcceptThreshold && wrongScore > rightScore:
+		return VerdictIncorrect, wrongScore, fmt.Sprintf("close to wrong answer %q", wrong), nil
+	case rightScore < j.RejectThreshold && !sharesWord(rightAnswers, player):
+		return VerdictIncorrect, 1 - rightScore, "not similar to any right answer", nil
+	}
+
+	if j.client == nil {
+		return VerdictUnsure, rightScore, fmt.Sprintf("partially matches right answer %q", right), nil
+	}
+	return j.ask(ctx, question, rightAnswers, wrongAnswers, playerAnswer)
+}
+
+// ask passes an ambiguous answer to the model
+func (j *Judge) ask(ctx context.Context, question string, rightAnswers, wrongAnswers []string, playerAnswer string) (Verdict, float64, string, error) {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Question: %s\n", question)
+	fmt.Fprintf(&prompt, "Right answers: %s\n", strings.Join(rightAnswers, "; "))
+	if len(wrongAnswers) > 0 {
+		fmt.Fprintf(&prompt, "Wrong answers: %s\n", strings.Join(wrongAnswers, "; "))
+	}
+	fmt.Fprintf(&prompt, "Player answer: %s\n", playerAnswer)
+
+	text, err := j.client.Generate(ctx, ollama.GenerateRequest{
+		Model:        j.model,
+		Prompt:       prompt.String(),
+		SystemPrompt: systemPrompt,
+		Format:       responseSchema,
+	})
+	if err != nil {
+		return "", 0, "", err
+	}
+
+	var response modelResponse
+	if err := json.Unmarshal([]byte(text), &response); err != nil {
+		return "", 0, "", fmt.Errorf("invalid model response %q: %w", text, err)
+	}
+	if response.Verdict != VerdictCorrect && response.Verdict != VerdictIncorrect {
+		return "", 0, "", fmt.Errorf("invalid verdict %q in model response", response.Verdict)
+	}
+	return response.Verdict, min(max(response.Confidence, 0), 1), response.Rationale, nil
+}
+
+// sharesWord reports whether the player answer contains a word of one of
+// the answers, such as a surname given without the first name
+func sharesWord(answers []string, player string) bool {
+	words := strings.Fields(player)
+	for _, answer := range answers {
+		for _, variant := range variants(answer) {
+			for _, word := range strings.Fields(variant) {
+				for _, playerWord := range words {
+					if len([]rune(word)) > 2 && similarity(word, playerWord) >= DefaultAcceptThreshold {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
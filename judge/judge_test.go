@@ -0,0 +1,152 @@
+package judge
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVariants(t *testing.T) {
+	expected := []string{"pushkin alexander", "pushkin", "alexander", "ivanov"}
+	got := variants("Pushkin (Alexander) / Ivanov")
+	if strings.Join(got, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected variants %v, got %v", expected, got)
+	}
+	if normalized := normalize("  The Beatles!"); normalized != "beatles" {
+		t.Errorf("Expected article and punctuation to be dropped, got %q", normalized)
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		text  string
+		value float64
+		ok    bool
+	}{
+		{"42", 42, true},
+		{"-5", -5, true},
+		{"−5", -5, true},
+		{"+5", 5, true},
+		{"2.50", 2.5, true},
+		{"2,5", 2.5, true},
+		{"1 000", 1000, true},
+		{"1'000'000", 1000000, true},
+		{"-1 234,5", -1234.5, true},
+		{"1 2", 0, false},
+		{"10 00", 0, false},
+		{"1.2.3", 0, false},
+		{"5 lbs", 0, false},
+	}
+	for _, test := range tests {
+		value, ok := parseNumber(test.text)
+		if ok != test.ok || value != test.value {
+			t.Errorf("parseNumber(%q) = %v %v, expected %v %v", test.text, value, ok, test.value, test.ok)
+		}
+	}
+}
+
+func TestJudgeDeterministic(t *testing.T) {
+	client := &FakeClient{}
+	judge := New(client, "model")
+	ctx := context.Background()
+
+	tests := []struct {
+		right, wrong []string
+		answer       string
+		verdict      Verdict
+	}{
+		{[]string{"Ёлка"}, nil, "елка", VerdictCorrect},
+		{[]string{"The Beatles"}, nil, "beatles", VerdictCorrect},
+		{[]string{"Pushkin (Alexander)"}, nil, "Pushkin", VerdictCorrect},
+		{[]string{"Shakespeare"}, nil, "Shakespear", VerdictCorrect},
+		{[]string{"Paris"}, []string{"Lyon"}, "Lyon", VerdictIncorrect},
+		{[]string{"1945"}, nil, "1946", VerdictIncorrect},
+		{[]string{"3"}, nil, "03", VerdictCorrect},
+		{[]string{"007"}, nil, "7", VerdictCorrect},
+		{[]string{"-5"}, nil, "5", VerdictIncorrect},
+		{[]string{"-5"}, nil, "−5", VerdictCorrect},
+		{[]string{"5"}, nil, "+5", VerdictCorrect},
+		{[]string{"2.5"}, nil, "2.50", VerdictCorrect},
+		{[]string{"2,5"}, nil, "2.5", VerdictCorrect},
+		{[]string{"2.5"}, nil, "25", VerdictIncorrect},
+		{[]string{"1 000"}, nil, "1000", VerdictCorrect},
+		{[]string{"1000000"}, nil, "1 000 000", VerdictCorrect},
+		{[]string{"1 000,5"}, nil, "1000.5", VerdictCorrect},
+		{[]string{"Moscow"}, nil, "Tokyo", VerdictIncorrect},
+		{[]string{"Moscow"}, nil, "   ", VerdictIncorrect},
+	}
+
+	for _, test := range tests {
+		verdict, confidence, rationale, err := judge.Judge(ctx, "Question?", test.right, test.wrong, test.answer)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.answer, err)
+			continue
+		}
+		if verdict != test.verdict {
+			t.Errorf("%q: expected %s, got %s (%s)", test.answer, test.verdict, verdict, rationale)
+		}
+		if confidence <= 0 || confidence > 1 || rationale == "" {
+			t.Errorf("%q: expected confidence in (0, 1] and a rationale, got %f %q", test.answer, confidence, rationale)
+		}
+	}
+
+	if len(client.Requests) != 0 {
+		t.Errorf("Expected no model requests for clear cases, got %d", len(client.Requests))
+	}
+}
+
+func TestJudgeAsksModel(t *testing.T) {
+	client := &FakeClient{Responses: []string{
+		`{"verdict": "correct", "confidence": 0.8, "rationale": "Surname of the right answer"}`,
+	}}
+	judge := New(client, "gemma3:12b")
+
+	verdict, confidence, rationale, err := judge.Judge(context.Background(),
+		"Who wrote War and Peace?", []string{"Leo Tolstoy"}, []string{"Dostoevsky"}, "Count Tolstoy")
+	if err != nil {
+		t.Fatal("Failed to judge answer:", err)
+	}
+	if verdict != VerdictCorrect || confidence != 0.8 || rationale != "Surname of the right answer" {
+		t.Errorf("Expected the model verdict, got %s %f %q", verdict, confidence, rationale)
+	}
+
+	if len(client.Requests) != 1 {
+		t.Fatalf("Expected one model request, got %d", len(client.Requests))
+	}
+	req := client.Requests[0]
+	if req.Model != "gemma3:12b" || len(req.Format) == 0 {
+		t.Errorf("Expected request with model and JSON format, got %+v", req)
+	}
+	for _, part := range []string{"War and Peace", "Leo Tolstoy", "Dostoevsky", "Count Tolstoy"} {
+		if !strings.Contains(req.Prompt, part) {
+			t.Errorf("Expected prompt to contain %q, got %s", part, req.Prompt)
+		}
+	}
+}
+
+func TestJudgeModelErrors(t *testing.T) {
+	ctx := context.Background()
+	right := []string{"Leo Tolstoy"}
+
+	for _, response := range []string{`not json`, `{"verdict": "maybe", "confidence": 1}`} {
+		judge := New(&FakeClient{Responses: []string{response}}, "model")
+		if _, _, _, err := judge.Judge(ctx, "Question?", right, nil, "Tolstoy L."); err == nil {
+			t.Errorf("Expected error for model response %s", response)
+		}
+	}
+
+	failure := errors.New("connection refused")
+	judge := New(&FakeClient{Err: failure}, "model")
+	if _, _, _, err := judge.Judge(ctx, "Question?", right, nil, "Tolstoy L."); !errors.Is(err, failure) {
+		t.Errorf("Expected client error, got %v", err)
+	}
+}
+
+func TestJudgeWithoutModel(t *testing.T) {
+	judge := New(nil, "")
+	verdict, _, _, err := judge.Judge(context.Background(), "Question?", []string{"Leo Tolstoy"}, nil, "Tolstoy L.")
+	if err != nil || verdict != VerdictUnsure {
+		t.Errorf("Expected unsure verdict without a model, got %s (%v)", verdict, err)
+	}
+}
@@ -0,0 +1,164 @@
+package judge
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/minmaxmean/sigma/textnorm"
+)
+
+// articles are leading words ignored when comparing answers
+var articles = map[string]bool{
+	"a": true, "an": true, "the": true,
+}
+
+// parenthesized matches optional parts of answers such as "Pushkin (Alexander)"
+var parenthesized = regexp.MustCompile(`\(([^()]*)\)|\[([^\[\]]*)\]`)
+
+// numberPattern matches a folded number with an optional sign, digits
+// optionally grouped in threes by spaces or apostrophes and an optional
+// decimal part after a point or a comma, for example "-1 000,5"
+var numberPattern = regexp.MustCompile(`^([+\-−]?)(\d{1,3}(?:[ '’]\d{3})+|\d+)(?:[.,](\d+))?$`)
+
+// normalize folds case and diacritics, drops punctuation and leading
+// articles and joins the remaining words with single spaces
+func normalize(text string) string {
+	words := strings.FieldsFunc(textnorm.Fold(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > 1 && articles[words[0]] {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// forms returns the texts an answer may be given as: the full answer, the
+// answer without parenthesized parts and the contents of each parenthesized
+// part. Answers listing alternatives with "/" or ";" are split into them.
+func forms(answer string) []string {
+	var result []string
+	for _, alternative := range strings.FieldsFunc(answer, func(r rune) bool { return r == '/' || r == ';' }) {
+		result = append(result, alternative, parenthesized.ReplaceAllString(alternative, ""))
+		for _, match := range parenthesized.FindAllStringSubmatch(alternative, -1) {
+			result = append(result, match[1]+match[2])
+		}
+	}
+	return result
+}
+
+// variants returns the distinct normalized forms of an answer, for example
+// "Ivanov (Ivan)" gives "ivanov ivan", "ivanov" and "ivan"
+func variants(answer string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, form := range forms(answer) {
+		if normalized := normalize(form); normalized != "" && !seen[normalized] {
+			seen[normalized] = true
+			result = append(result, normalized)
+		}
+	}
+	return result
+}
+
+// similarity returns 1 minus the edit distance of two strings divided by
+// the length of the longer one, 1 for equal strings
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(editDistance(ra, rb))/float64(longest)
+}
+
+// editDistance returns the Levenshtein distance of two rune slices
+func editDistance(a, b []rune) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current := min(row[j]+1, row[j-1]+1, diagonal+cost)
+			diagonal = row[j]
+			row[j] = current
+		}
+	}
+	return row[len(b)]
+}
+
+// parseNumber returns the value of a text that is a single number. It is
+// parsed before punctuation is dropped, so the sign and the decimal part
+// are kept.
+func parseNumber(text string) (float64, bool) {
+	match := numberPattern.FindStringSubmatch(strings.TrimSpace(textnorm.Fold(text)))
+	if match == nil {
+		return 0, false
+	}
+	number := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, match[2])
+	if match[1] != "" && match[1] != "+" {
+		number = "-" + number
+	}
+	if match[3] != "" {
+		number += "." + match[3]
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	return value, err == nil
+}
+
+// numberMatch returns the answer that has a form equal in value to the
+// player answer, or an empty string when there is none
+func numberMatch(answers []string, player float64) string {
+	for _, answer := range answers {
+		for _, form := range forms(answer) {
+			if value, ok := parseNumber(form); ok && value == player {
+				return answer
+			}
+		}
+	}
+	return ""
+}
+
+// bestMatch returns the answer that has the variant most similar to the
+// player answer and the similarity of that variant
+func bestMatch(answers []string, player string) (string, float64) {
+	best, bestScore := "", 0.0
+	for _, answer := range answers {
+		for _, variant := range variants(answer) {
+			if score := similarity(variant, player); score > bestScore {
+				best, bestScore = answer, score
+			}
+		}
+	}
+	return best, bestScore
+}
+
+// hasNumbers reports whether every non-empty form of the answers is a number
+func hasNumbers(answers []string) bool {
+	found := false
+	for _, answer := range answers {
+		for _, form := range forms(answer) {
+			if normalize(form) == "" {
+				continue
+			}
+			if _, ok := parseNumber(form); !ok {
+				return false
+			}
+			found = true
+		}
+	}
+	return found
+}
@@ -0,0 +1,118 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/minmaxmean/sigma/textnorm"
+)
+
+// SearchHit is a package or question matching a search query
+type SearchHit struct {
+	Package *PackageRecord
+	// Entry is the matched question or nil when the package metadata matched
+	Entry *Entry
+}
+
+// tokenize splits text into folded terms
+func tokenize(text string) []string {
+	return strings.FieldsFunc(textnorm.Fold(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Search returns packages and questions containing all query terms.
+// A term ending with "*" matches any term with that prefix. A question
+// matches when each term is in the question, its answers or location or in
+// the metadata of its package, and at least one term is in the question
+// itself. The package matches when all terms are in its metadata.
+// At most limit hits are returned, a non-positive limit returns all hits.
+func (l *Library) Search(query string, limit int) ([]SearchHit, error) {
+	var exprs []string
+	for _, field := range strings.Fields(query) {
+		prefix := strings.HasSuffix(field, "*")
+		for _, term := range tokenize(strings.TrimSuffix(field, "*")) {
+			// Terms only contain letters and digits, so quoting makes them safe
+			expr := `"` + term + `"`
+			if prefix {
+				expr += "*"
+			}
+			exprs = append(exprs, expr)
+		}
+	}
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+
+	rows, err := l.db.Query(`
+		WITH matched AS (
+			SELECT rowid AS doc FROM docs
+			WHERE docs MATCH ?1
+			AND ((rowid & ?2) = 0 OR rowid IN (SELECT rowid FROM docs WHERE docs MATCH ?3))
+		)
+		SELECT p.key, matched.doc FROM matched JOIN packages p ON p.key = matched.doc >> ?4
+		ORDER BY p.path, matched.doc
+		LIMIT ?5`,
+		strings.Join(exprs, " AND "), int64(1)<<entryBits-1, "{body}: ("+strings.Join(exprs, " OR ")+")", entryBits, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search library: %w", err)
+	}
+
+	type match struct {
+		pack  int64
+		entry int32
+	}
+	var matches []match
+	for rows.Next() {
+		var pack, doc int64
+		if err := rows.Scan(&pack, &doc); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		matches = append(matches, match{pack: pack, entry: int32(doc&(1<<entryBits-1)) - 1})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hits := make([]SearchHit, 0, len(matches))
+	packages := make(map[int64]*PackageRecord)
+	for _, m := range matches {
+		record, ok := packages[m.pack]
+		if !ok {
+			if record, err = l.lookupKey(m.pack); err != nil {
+				return nil, err
+			}
+			packages[m.pack] = record
+		}
+		hit := SearchHit{Package: record}
+		if m.entry >= 0 {
+			if hit.Entry, err = l.entry(m.pack, m.entry); err != nil {
+				return nil, err
+			}
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+// entry loads an indexed question of a package
+func (l *Library) entry(pack int64, position int32) (*Entry, error) {
+	var entry Entry
+	var answers string
+	err := l.db.QueryRow("SELECT id, location, question, answers FROM entries WHERE pack = ? AND position = ?", pack, position).
+		Scan(&entry.ID, &entry.Location, &entry.Question, &answers)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(answers), &entry.Answers); err != nil {
+		return nil, fmt.Errorf("invalid answers of %s: %w", entry.ID, err)
+	}
+	return &entry, nil
+}
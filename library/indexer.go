@@ -0,0 +1,301 @@
+package library
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// AddResult summarizes an indexing run
+type AddResult struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	Errors    []error
+}
+
+// indexJob is a file that has to be hashed and possibly re-indexed
+type indexJob struct {
+	path     string
+	info     os.FileInfo
+	previous *PackageRecord
+}
+
+// indexOutcome is the result of processing an index job
+type indexOutcome struct {
+	job    indexJob
+	hash   string
+	record *PackageRecord // nil when the content did not change
+	err    error
+}
+
+// Add indexes package files. Files whose size and modification time did not
+// change since the last run are skipped. Files whose content hash did not
+// change only get their modification time updated. Up to workers files are
+// processed concurrently.
+func (l *Library) Add(paths []string, workers int) AddResult {
+	var result AddResult
+	var jobs []indexJob
+
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		info, err := os.Stat(absPath)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+
+		previous, err := l.lookupPath(absPath)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		if previous != nil && previous.Size == info.Size() && previous.ModTime.Equal(info.ModTime()) {
+			result.Unchanged++
+			continue
+		}
+		jobs = append(jobs, indexJob{path: absPath, info: info, previous: previous})
+	}
+
+	for _, outcome := range runJobs(jobs, workers) {
+		job := outcome.job
+		switch {
+		case outcome.err != nil:
+			result.Errors = append(result.Errors, fmt.Errorf("%s: %w", job.path, outcome.err))
+		case outcome.record == nil:
+			// Same content, only the file metadata changed
+			job.previous.ModTime = job.info.ModTime()
+			job.previous.Size = job.info.Size()
+			if err := l.touch(job.previous); err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("%s: %w", job.path, err))
+				continue
+			}
+			result.Unchanged++
+		default:
+			outcome.record.Hash = outcome.hash
+			outcome.record.ModTime = job.info.ModTime()
+			outcome.record.Size = job.info.Size()
+			if err := l.put(outcome.record); err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("%s: %w", job.path, err))
+				continue
+			}
+			if job.previous != nil {
+				result.Updated++
+			} else {
+				result.Added++
+			}
+		}
+	}
+
+	return result
+}
+
+// Prune removes packages whose files no longer exist and returns their number
+func (l *Library) Prune() (int, error) {
+	var missing []int64
+	err := l.eachRow("SELECT key, path FROM packages", func(scan func(dest ...any) error) error {
+		var key int64
+		var path string
+		if err := scan(&key, &path); err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			missing = append(missing, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	for _, key := range missing {
+		if err := remove(tx, key); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(missing), nil
+}
+
+// runJobs hashes and indexes files concurrently, keeping the order of jobs
+func runJobs(jobs []indexJob, workers int) []indexOutcome {
+	if workers < 1 {
+		workers = 1
+	}
+
+	outcomes := make([]indexOutcome, len(jobs))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(workers, len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				outcomes[index] = processJob(jobs[index])
+			}
+		}()
+	}
+
+	for index := range jobs {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	return outcomes
+}
+
+func processJob(job indexJob) indexOutcome {
+	hash, err := hashFile(job.path)
+	if err != nil {
+		return indexOutcome{job: job, err: err}
+	}
+	if job.previous != nil && job.previous.Hash == hash {
+		return indexOutcome{job: job, hash: hash}
+	}
+
+	record, err := indexFile(job.path)
+	return indexOutcome{job: job, hash: hash, record: record, err: err}
+}
+
+// hashFile returns the hex-encoded SHA-256 of the file content
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// indexFile reads a package in streaming mode and builds its record
+func indexFile(path string) (*PackageRecord, error) {
+	reader, err := siq.NewSIQReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	record := &PackageRecord{Path: path}
+	ref := siq.QuestionRef{RoundIndex: -1}
+
+	err = reader.Stream(siq.StreamHandler{
+		Package: func(pkg *siq.Package) error {
+			record.ID = pkg.ID
+			record.Name = pkg.Name
+			record.Publisher = pkg.Publisher
+			record.Language = pkg.Language
+			record.Date = pkg.Date
+			record.Difficulty = pkg.Difficulty
+			if pkg.Tags != nil {
+				record.Tags = pkg.Tags.Tags
+			}
+			if pkg.Info != nil {
+				record.Authors = resolveAuthors(pkg, pkg.Info.Authors)
+			}
+			return nil
+		},
+		Round: func(round *siq.Round) error {
+			record.Rounds++
+			ref = siq.QuestionRef{RoundIndex: ref.RoundIndex + 1, RoundName: round.Name, ThemeIndex: -1}
+			return nil
+		},
+		Theme: func(round *siq.Round, theme *siq.Theme) error {
+			record.Themes++
+			ref.ThemeIndex++
+			ref.ThemeName = theme.Name
+			ref.QuestionIndex = -1
+			return nil
+		},
+		Question: func(round *siq.Round, theme *siq.Theme, question *siq.Question) error {
+			record.Questions++
+			ref.QuestionIndex++
+			ref.Price = question.Price()
+			record.Entries = append(record.Entries, Entry{
+				ID:       ref.ID(),
+				Location: ref.String(),
+				Question: questionText(question),
+				Answers:  question.Right,
+			})
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range reader.ListFiles() {
+		mediaType := siq.MediaType(name)
+		if mediaType == "" {
+			continue
+		}
+		file, err := reader.GetFile(name)
+		if err != nil {
+			return nil, err
+		}
+		record.Media = append(record.Media, MediaRecord{
+			Name: name,
+			Type: mediaType,
+			Size: int64(file.UncompressedSize64),
+		})
+	}
+
+	return record, nil
+}
+
+// resolveAuthors replaces references to global authors such as "@1" with their names
+func resolveAuthors(pkg *siq.Package, authors []string) []string {
+	resolved := make([]string, 0, len(authors))
+	for _, author := range authors {
+		if name, err := pkg.ResolveReference(author); err == nil {
+			author = name
+		}
+		resolved = append(resolved, author)
+	}
+	return resolved
+}
+
+// questionText joins the text items of the question content and answer options
+func questionText(question *siq.Question) string {
+	var parts []string
+	for _, item := range question.GetQuestionContent() {
+		if item.GetType() == siq.ContentTypeText {
+			parts = append(parts, item.Value)
+		}
+	}
+	for _, option := range question.AnswerOptions() {
+		for _, item := range option.Items {
+			if item.GetType() == siq.ContentTypeText {
+				parts = append(parts, option.Label+") "+item.Value)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
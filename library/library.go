@@ -0,0 +1,359 @@
+// Package library maintains a persistent on-disk index of many SIQ packages
+// with their metadata, questions, answers and media inventory
+package library
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// storeFile is the name of the database inside the library directory
+const storeFile = "library.db"
+
+// storeVersion is stored as the database user_version and incremented when the schema changes
+const storeVersion = 1
+
+// schema creates the library tables. Every package has a metadata document
+// and one document per question in the full-text table, see docID.
+const schema = `
+CREATE TABLE packages (
+	key        INTEGER PRIMARY KEY,
+	path       TEXT NOT NULL UNIQUE,
+	hash       TEXT NOT NULL,
+	mod_time   INTEGER NOT NULL,
+	size       INTEGER NOT NULL,
+	id         TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	publisher  TEXT NOT NULL,
+	language   TEXT NOT NULL,
+	date       TEXT NOT NULL,
+	difficulty INTEGER NOT NULL,
+	tags       TEXT NOT NULL,
+	authors    TEXT NOT NULL,
+	rounds     INTEGER NOT NULL,
+	themes     INTEGER NOT NULL,
+	questions  INTEGER NOT NULL
+);
+CREATE TABLE media (
+	pack INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL,
+	size INTEGER NOT NULL
+);
+CREATE INDEX media_pack ON media (pack);
+CREATE TABLE entries (
+	pack     INTEGER NOT NULL,
+	position INTEGER NOT NULL,
+	id       TEXT NOT NULL,
+	location TEXT NOT NULL,
+	question TEXT NOT NULL,
+	answers  TEXT NOT NULL,
+	PRIMARY KEY (pack, position)
+);
+CREATE VIRTUAL TABLE docs USING fts5(meta, body, tokenize = 'unicode61 remove_diacritics 0');
+`
+
+// entryBits is the number of low document id bits holding the entry position,
+// which limits packages to 2^24 - 1 questions
+const entryBits = 24
+
+// docID returns the full-text document id of a package entry, -1 for the
+// package metadata. Documents of a package form a contiguous id range, so
+// they are removed without scanning the index.
+func docID(pack int64, entry int) int64 {
+	return pack<<entryBits + int64(entry) + 1
+}
+
+// MediaRecord describes a media file of a package
+type MediaRecord struct {
+	Name string
+	Type string // image, audio, video, html or other
+	Size int64
+}
+
+// Entry is an indexed question of a package
+type Entry struct {
+	ID       string // stable question path, for example "r1/t2/q3"
+	Location string // round / theme / price
+	Question string
+	Answers  []string
+}
+
+// PackageRecord holds everything indexed for a package file
+type PackageRecord struct {
+	Key     int64
+	Path    string
+	Hash    string
+	ModTime time.Time
+	Size    int64
+
+	ID         string
+	Name       string
+	Publisher  string
+	Language   string
+	Date       string
+	Difficulty int
+	Tags       []string
+	Authors    []string
+	Rounds     int
+	Themes     int
+	Questions  int
+
+	Media []MediaRecord
+	// Entries are only filled while indexing, search hits load their entry
+	Entries []Entry
+}
+
+// Library is a persistent index of SIQ packages
+type Library struct {
+	dir string
+	db  *sql.DB
+}
+
+// Open opens the library stored in dir, creating an empty one if it does not exist
+func Open(dir string) (*Library, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create library directory %s: %w", dir, err)
+	}
+
+	db, err := sql.Open("sqlite", filepath.Join(dir, storeFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open library: %w", err)
+	}
+	// Writes are serialized anyway, a single connection avoids busy errors
+	db.SetMaxOpenConns(1)
+
+	l := &Library{dir: dir, db: db}
+	if err := l.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// migrate creates the schema of a new library and checks the version of an existing one
+func (l *Library) migrate() error {
+	var version int
+	if err := l.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to open library: %w", err)
+	}
+	switch version {
+	case storeVersion:
+		return nil
+	case 0:
+		if _, err := l.db.Exec(schema + fmt.Sprintf("PRAGMA user_version = %d;", storeVersion)); err != nil {
+			return fmt.Errorf("failed to create library: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported library version %d, re-create the library", version)
+}
+
+// Close closes the library database
+func (l *Library) Close() error {
+	return l.db.Close()
+}
+
+// packageColumns are the packages columns read by scanPackage
+const packageColumns = `key, path, hash, mod_time, size, id, name, publisher, language, date, difficulty, tags, authors, rounds, themes, questions`
+
+// scanPackage reads a packages row selected with packageColumns
+func scanPackage(row interface{ Scan(dest ...any) error }) (*PackageRecord, error) {
+	var record PackageRecord
+	var modTime int64
+	var tags, authors string
+	err := row.Scan(&record.Key, &record.Path, &record.Hash, &modTime, &record.Size,
+		&record.ID, &record.Name, &record.Publisher, &record.Language, &record.Date, &record.Difficulty,
+		&tags, &authors, &record.Rounds, &record.Themes, &record.Questions)
+	if err != nil {
+		return nil, err
+	}
+	record.ModTime = time.Unix(0, modTime)
+	if err := json.Unmarshal([]byte(tags), &record.Tags); err != nil {
+		return nil, fmt.Errorf("invalid tags of %s: %w", record.Path, err)
+	}
+	if err := json.Unmarshal([]byte(authors), &record.Authors); err != nil {
+		return nil, fmt.Errorf("invalid authors of %s: %w", record.Path, err)
+	}
+	return &record, nil
+}
+
+// Packages returns all indexed packages with their media sorted by path
+func (l *Library) Packages() ([]*PackageRecord, error) {
+	rows, err := l.db.Query("SELECT " + packageColumns + " FROM packages ORDER BY path")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*PackageRecord
+	byKey := make(map[int64]*PackageRecord)
+	for rows.Next() {
+		record, err := scanPackage(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+		byKey[record.Key] = record
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	media, err := l.db.Query("SELECT pack, name, type, size FROM media ORDER BY pack, rowid")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list media: %w", err)
+	}
+	defer media.Close()
+	for media.Next() {
+		var pack int64
+		var file MediaRecord
+		if err := media.Scan(&pack, &file.Name, &file.Type, &file.Size); err != nil {
+			return nil, err
+		}
+		if record, ok := byKey[pack]; ok {
+			record.Media = append(record.Media, file)
+		}
+	}
+	return records, media.Err()
+}
+
+// eachRow runs a query and calls fn with the scan function of every result row
+func (l *Library) eachRow(query string, fn func(scan func(dest ...any) error) error) error {
+	rows, err := l.db.Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to query library: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows.Scan); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// lookupPath returns the package indexed for path, or nil when there is none
+func (l *Library) lookupPath(path string) (*PackageRecord, error) {
+	record, err := scanPackage(l.db.QueryRow("SELECT "+packageColumns+" FROM packages WHERE path = ?", path))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return record, err
+}
+
+// lookupKey returns the indexed package with the key without its media
+func (l *Library) lookupKey(key int64) (*PackageRecord, error) {
+	return scanPackage(l.db.QueryRow("SELECT "+packageColumns+" FROM packages WHERE key = ?", key))
+}
+
+// touch updates the file metadata of a package whose content did not change
+func (l *Library) touch(record *PackageRecord) error {
+	_, err := l.db.Exec("UPDATE packages SET mod_time = ?, size = ? WHERE key = ?",
+		record.ModTime.UnixNano(), record.Size, record.Key)
+	return err
+}
+
+// put stores a freshly indexed record, replacing the previous version of the package
+func (l *Library) put(record *PackageRecord) error {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var previous int64
+	err = tx.QueryRow("SELECT key FROM packages WHERE path = ?", record.Path).Scan(&previous)
+	switch {
+	case err == nil:
+		if err := remove(tx, previous); err != nil {
+			return err
+		}
+	case err != sql.ErrNoRows:
+		return err
+	}
+
+	tags, _ := json.Marshal(orEmpty(record.Tags))
+	authors, _ := json.Marshal(orEmpty(record.Authors))
+	result, err := tx.Exec(`INSERT INTO packages (path, hash, mod_time, size, id, name, publisher, language,
+		date, difficulty, tags, authors, rounds, themes, questions) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.Path, record.Hash, record.ModTime.UnixNano(), record.Size, record.ID, record.Name, record.Publisher,
+		record.Language, record.Date, record.Difficulty, string(tags), string(authors),
+		record.Rounds, record.Themes, record.Questions)
+	if err != nil {
+		return err
+	}
+	if record.Key, err = result.LastInsertId(); err != nil {
+		return err
+	}
+
+	for _, file := range record.Media {
+		if _, err := tx.Exec("INSERT INTO media (pack, name, type, size) VALUES (?, ?, ?, ?)",
+			record.Key, file.Name, file.Type, file.Size); err != nil {
+			return err
+		}
+	}
+
+	// Every question document also holds the package metadata, so queries
+	// mixing metadata and question terms match the question
+	meta := terms(append([]string{record.Name, record.Publisher}, append(record.Tags, record.Authors...)...)...)
+	if _, err := tx.Exec("INSERT INTO docs (rowid, meta, body) VALUES (?, ?, '')", docID(record.Key, -1), meta); err != nil {
+		return err
+	}
+	for i, entry := range record.Entries {
+		answers, _ := json.Marshal(orEmpty(entry.Answers))
+		if _, err := tx.Exec("INSERT INTO entries (pack, position, id, location, question, answers) VALUES (?, ?, ?, ?, ?, ?)",
+			record.Key, i, entry.ID, entry.Location, entry.Question, string(answers)); err != nil {
+			return err
+		}
+		body := terms(append([]string{entry.Location, entry.Question}, entry.Answers...)...)
+		if _, err := tx.Exec("INSERT INTO docs (rowid, meta, body) VALUES (?, ?, ?)", docID(record.Key, i), meta, body); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// remove drops a package with its media, entries and documents
+func remove(tx *sql.Tx, key int64) error {
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{"DELETE FROM docs WHERE rowid BETWEEN ? AND ?", []any{docID(key, -1), docID(key, -1) + 1<<entryBits - 1}},
+		{"DELETE FROM entries WHERE pack = ?", []any{key}},
+		{"DELETE FROM media WHERE pack = ?", []any{key}},
+		{"DELETE FROM packages WHERE key = ?", []any{key}},
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement.query, statement.args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// terms returns the folded terms of texts separated by spaces
+func terms(texts ...string) string {
+	var all []string
+	for _, text := range texts {
+		all = append(all, tokenize(text)...)
+	}
+	return strings.Join(all, " ")
+}
+
+// orEmpty returns an empty slice instead of nil so that lists are stored as "[]"
+func orEmpty(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
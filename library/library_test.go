@@ -0,0 +1,208 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minmaxmean/sigma/internal/siqtest"
+)
+
+const testContentXML = `<?xml version="1.0" encoding="utf-8"?>
+<package id="lib" name="Library Package" version="5" publisher="Club" language="ru">
+	<info><authors><author>@a1</author></authors></info>
+	<tags>
+		<tag>Литература</tag>
+	</tags>
+	<global><authors><author id="a1">Иван Петров</author></authors></global>
+	<round name="Round 1">
+		<theme name="Писатели">
+			<question price="100">
+				<params>
+					<param name="question" type="content">
+						<item>Автор романа «Идиот»</item>
+						<item type="image" isRef="True">idiot.png</item>
+					</param>
+				</params>
+				<right><answer>Фёдор Достоевский</answer></right>
+			</question>
+			<question price="200">
+				<params>
+					<param name="question" type="content">
+						<item>Автор «Войны и мира»</item>
+					</param>
+				</params>
+				<right><answer>Лев Толстой</answer></right>
+			</question>
+		</theme>
+	</round>
+</package>`
+
+func TestAddAndSearch(t *testing.T) {
+	dir := t.TempDir()
+	packPath := siqtest.CreateFile(t, dir, "pack.siq", testContentXML, map[string]string{"Images/idiot.png": "png-data"})
+
+	lib, err := Open(filepath.Join(dir, "library"))
+	if err != nil {
+		t.Fatal("Failed to open library:", err)
+	}
+	defer lib.Close()
+
+	result := lib.Add([]string{packPath}, 2)
+	if len(result.Errors) != 0 || result.Added != 1 {
+		t.Fatalf("Unexpected add result %+v", result)
+	}
+
+	records, err := lib.Packages()
+	if err != nil {
+		t.Fatal("Failed to list packages:", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 package, got %d", len(records))
+	}
+	record := records[0]
+	if record.Name != "Library Package" || record.Questions != 2 || record.Themes != 1 || record.Rounds != 1 {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	if len(record.Authors) != 1 || record.Authors[0] != "Иван Петров" {
+		t.Errorf("Expected global author reference to be resolved, got %v", record.Authors)
+	}
+	if len(record.Media) != 1 || record.Media[0].Type != "image" || record.Media[0].Size != int64(len("png-data")) {
+		t.Errorf("Unexpected media inventory %+v", record.Media)
+	}
+
+	hits := search(t, lib, "федор достоевский", 0)
+	if len(hits) != 1 || hits[0].Entry == nil || hits[0].Entry.ID != "r1/t1/q1" {
+		t.Fatalf("Unexpected hits %+v", hits)
+	}
+	if answers := hits[0].Entry.Answers; len(answers) != 1 || answers[0] != "Фёдор Достоевский" {
+		t.Errorf("Expected stored answers, got %v", answers)
+	}
+
+	hits = search(t, lib, "литература петров", 0)
+	if len(hits) != 1 || hits[0].Entry != nil {
+		t.Errorf("Expected a package metadata hit, got %+v", hits)
+	}
+
+	// Metadata terms combine with question terms
+	hits = search(t, lib, "петров толст*", 0)
+	if len(hits) != 1 || hits[0].Entry == nil || hits[0].Entry.ID != "r1/t1/q2" {
+		t.Errorf("Expected the question of the author's package, got %+v", hits)
+	}
+
+	hits = search(t, lib, "автор*", 0)
+	if len(hits) != 2 {
+		t.Errorf("Expected 2 prefix hits, got %d", len(hits))
+	}
+	if hits := search(t, lib, "автор*", 1); len(hits) != 1 {
+		t.Errorf("Expected limit to apply, got %d hits", len(hits))
+	}
+
+	if hits := search(t, lib, "достоевский толстой", 0); len(hits) != 0 {
+		t.Errorf("Expected no hits for terms in different questions, got %d", len(hits))
+	}
+}
+
+// search runs a library search and fails the test on errors
+func search(t *testing.T, lib *Library, query string, limit int) []SearchHit {
+	hits, err := lib.Search(query, limit)
+	if err != nil {
+		t.Fatalf("Failed to search %q: %v", query, err)
+	}
+	return hits
+}
+
+func TestPersistenceAndIncrementalIndexing(t *testing.T) {
+	dir := t.TempDir()
+	libraryDir := filepath.Join(dir, "library")
+	packPath := siqtest.CreateFile(t, dir, "pack.siq", testContentXML, nil)
+
+	lib, err := Open(libraryDir)
+	if err != nil {
+		t.Fatal("Failed to open library:", err)
+	}
+	lib.Add([]string{packPath}, 1)
+	if err := lib.Close(); err != nil {
+		t.Fatal("Failed to close library:", err)
+	}
+
+	// Reopen and add again: nothing changed
+	lib, err = Open(libraryDir)
+	if err != nil {
+		t.Fatal("Failed to reopen library:", err)
+	}
+	defer lib.Close()
+	if len(search(t, lib, "толстой", 0)) != 1 {
+		t.Error("Expected index to be persisted")
+	}
+	result := lib.Add([]string{packPath}, 1)
+	if result.Unchanged != 1 || result.Added != 0 || result.Updated != 0 {
+		t.Errorf("Expected unchanged file to be skipped, got %+v", result)
+	}
+
+	// Touch the file without changing content: hash matches
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(packPath, later, later); err != nil {
+		t.Fatal("Failed to touch file:", err)
+	}
+	result = lib.Add([]string{packPath}, 1)
+	if result.Unchanged != 1 || result.Updated != 0 {
+		t.Errorf("Expected same-content file to be skipped, got %+v", result)
+	}
+
+	// Change the content: the package is re-indexed and old terms disappear
+	siqtest.CreateFile(t, dir, "pack.siq", `<package name="Changed"><round name="R"><theme name="T"><question price="100">
+		<params><param name="question" type="content"><item>Новый вопрос</item></param></params>
+		<right><answer>Пушкин</answer></right></question></theme></round></package>`, nil)
+	result = lib.Add([]string{packPath}, 1)
+	if result.Updated != 1 {
+		t.Errorf("Expected changed file to be re-indexed, got %+v", result)
+	}
+	if len(search(t, lib, "толстой", 0)) != 0 || len(search(t, lib, "пушкин", 0)) != 1 {
+		t.Error("Expected index to reflect the new content")
+	}
+
+	// Deleted files are pruned
+	if err := os.Remove(packPath); err != nil {
+		t.Fatal("Failed to remove file:", err)
+	}
+	if removed, err := lib.Prune(); err != nil || removed != 1 {
+		t.Errorf("Expected 1 pruned package, got %d (%v)", removed, err)
+	}
+	if records, _ := lib.Packages(); len(records) != 0 || len(search(t, lib, "пушкин", 0)) != 0 {
+		t.Error("Expected pruned package to be removed from the index")
+	}
+}
+
+func TestStats(t *testing.T) {
+	dir := t.TempDir()
+	first := siqtest.CreateFile(t, dir, "first.siq", testContentXML, map[string]string{"Images/a.png": "aaaa", "Audio/b.mp3": "bb"})
+	second := siqtest.CreateFile(t, dir, "second.siq", testContentXML, map[string]string{"Images/c.png": "cc"})
+
+	lib, err := Open(filepath.Join(dir, "library"))
+	if err != nil {
+		t.Fatal("Failed to open library:", err)
+	}
+	defer lib.Close()
+	if result := lib.Add([]string{first, second, filepath.Join(dir, "missing.siq")}, 2); len(result.Errors) != 1 {
+		t.Errorf("Expected 1 error for the missing file, got %v", result.Errors)
+	}
+
+	stats, err := lib.Stats()
+	if err != nil {
+		t.Fatal("Failed to compute stats:", err)
+	}
+	if stats.Packages != 2 || stats.Questions != 4 {
+		t.Errorf("Unexpected totals %+v", stats)
+	}
+	if stats.MediaFiles["image"] != 2 || stats.MediaSize["image"] != 6 || stats.MediaFiles["audio"] != 1 {
+		t.Errorf("Unexpected media stats %+v %+v", stats.MediaFiles, stats.MediaSize)
+	}
+	if stats.Languages["ru"] != 2 {
+		t.Errorf("Unexpected languages %+v", stats.Languages)
+	}
+	if len(stats.Tags) != 1 || stats.Tags[0].Count != 2 {
+		t.Errorf("Unexpected tags %+v", stats.Tags)
+	}
+}
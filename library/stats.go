@@ -0,0 +1,79 @@
+package library
+
+import (
+	"fmt"
+)
+
+// Stats summarizes the library content
+type Stats struct {
+	Packages   int
+	Rounds     int
+	Themes     int
+	Questions  int
+	TotalSize  int64
+	MediaFiles map[string]int   // by media type
+	MediaSize  map[string]int64 // by media type
+	Languages  map[string]int
+	Tags       []TagCount
+}
+
+// TagCount is the number of packages having a tag
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+// Stats computes statistics over all indexed packages
+func (l *Library) Stats() (Stats, error) {
+	stats := Stats{
+		MediaFiles: make(map[string]int),
+		MediaSize:  make(map[string]int64),
+		Languages:  make(map[string]int),
+	}
+
+	err := l.db.QueryRow(`SELECT COUNT(*), COALESCE(SUM(rounds), 0), COALESCE(SUM(themes), 0),
+		COALESCE(SUM(questions), 0), COALESCE(SUM(size), 0) FROM packages`).
+		Scan(&stats.Packages, &stats.Rounds, &stats.Themes, &stats.Questions, &stats.TotalSize)
+	if err != nil {
+		return stats, fmt.Errorf("failed to compute library statistics: %w", err)
+	}
+
+	err = l.eachRow("SELECT type, COUNT(*), SUM(size) FROM media GROUP BY type", func(scan func(dest ...any) error) error {
+		var mediaType string
+		var count int
+		var size int64
+		if err := scan(&mediaType, &count, &size); err != nil {
+			return err
+		}
+		stats.MediaFiles[mediaType] = count
+		stats.MediaSize[mediaType] = size
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	err = l.eachRow("SELECT language, COUNT(*) FROM packages WHERE language != '' GROUP BY language", func(scan func(dest ...any) error) error {
+		var language string
+		var count int
+		if err := scan(&language, &count); err != nil {
+			return err
+		}
+		stats.Languages[language] = count
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	err = l.eachRow(`SELECT tag.value, COUNT(*) AS count FROM packages, json_each(packages.tags) AS tag
+		GROUP BY tag.value ORDER BY count DESC, tag.value`, func(scan func(dest ...any) error) error {
+		var tag TagCount
+		if err := scan(&tag.Tag, &tag.Count); err != nil {
+			return err
+		}
+		stats.Tags = append(stats.Tags, tag)
+		return nil
+	})
+	return stats, err
+}
@@ -0,0 +1,121 @@
+// Package llm defines a provider-agnostic interface to large language
+// models with implementations for Ollama, OpenAI-compatible servers such as
+// llama.cpp and vLLM, and a scripted provider for tests
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// FormatJSON asks for any valid JSON response, a JSON schema object can be
+// used instead to constrain the response structure
+var FormatJSON = json.RawMessage(`"json"`)
+
+// Message is a chat message
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Options are sampling and context parameters. Zero values keep the
+// defaults of the provider.
+type Options struct {
+	Temperature *float64
+	Seed        *int
+	// ContextSize is the context window in tokens, only used by Ollama
+	ContextSize int
+	// MaxTokens limits the length of the response
+	MaxTokens int
+}
+
+// GenerateRequest is a single prompt completion request
+type GenerateRequest struct {
+	Model  string
+	System string
+	Prompt string
+	// Format constrains the response to JSON, see FormatJSON
+	Format  json.RawMessage
+	Options Options
+}
+
+// ChatRequest is a completion request for a conversation
+type ChatRequest struct {
+	Model    string
+	Messages []Message
+	// Format constrains the response to JSON, see FormatJSON
+	Format  json.RawMessage
+	Options Options
+}
+
+// EmbedRequest asks for embedding vectors of texts
+type EmbedRequest struct {
+	Model string
+	Input []string
+}
+
+// Response is a completed generation
+type Response struct {
+	Text  string
+	Model string
+	// PromptTokens and CompletionTokens are 0 when the provider does not report them
+	PromptTokens     int
+	CompletionTokens int
+	Duration         time.Duration
+}
+
+// TokenFunc receives streamed response tokens, returning an error stops the stream
+type TokenFunc func(token string) error
+
+// Provider is a language model backend
+type Provider interface {
+	// Generate completes a single prompt
+	Generate(ctx context.Context, req GenerateRequest) (*Response, error)
+	// Chat completes a conversation
+	Chat(ctx context.Context, req ChatRequest) (*Response, error)
+	// ChatStream completes a conversation, passing tokens to fn as they
+	// arrive. The returned response holds the whole text.
+	ChatStream(ctx context.Context, req ChatRequest, fn TokenFunc) (*Response, error)
+	// Embed returns one embedding vector per input text
+	Embed(ctx context.Context, req EmbedRequest) ([][]float32, error)
+}
+
+// GenerateJSON generates a structured response and decodes it into v. The
+// request asks for any JSON when it has no format set.
+func GenerateJSON(ctx context.Context, provider Provider, req GenerateRequest, v any) error {
+	if len(req.Format) == 0 {
+		req.Format = FormatJSON
+	}
+	resp, err := provider.Generate(ctx, req)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(resp.Text), v); err != nil {
+		return fmt.Errorf("invalid JSON response %q: %w", resp.Text, err)
+	}
+	return nil
+}
+
+// messages converts a generate request into chat messages
+func (r GenerateRequest) messages() []Message {
+	var messages []Message
+	if r.System != "" {
+		messages = append(messages, Message{Role: RoleSystem, Content: r.System})
+	}
+	return append(messages, Message{Role: RoleUser, Content: r.Prompt})
+}
+
+// isFormatJSON reports whether format asks for any JSON rather than a schema
+func isFormatJSON(format json.RawMessage) bool {
+	var name string
+	return json.Unmarshal(format, &name) == nil && name == "json"
+}
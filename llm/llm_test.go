@@ -0,0 +1,218 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/minmaxmean/sigma/ollama"
+	"github.com/ollama/ollama/api"
+)
+
+func TestScripted(t *testing.T) {
+	provider := NewScripted("first answer", `{"city":"Paris"}`)
+	ctx := context.Background()
+
+	var tokens []string
+	resp, err := provider.ChatStream(ctx, ChatRequest{
+		Model:    "model",
+		Messages: []Message{{Role: RoleUser, Content: "hello there"}},
+	}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to chat: %v", err)
+	}
+	if resp.Text != "first answer" || strings.Join(tokens, "") != resp.Text || len(tokens) != 2 {
+		t.Errorf("Expected 2 tokens of %q, got %q", resp.Text, tokens)
+	}
+	if resp.PromptTokens != 2 || resp.CompletionTokens != 2 {
+		t.Errorf("Expected 2 prompt and 2 completion tokens, got %d and %d", resp.PromptTokens, resp.CompletionTokens)
+	}
+
+	var result struct{ City string }
+	if err := GenerateJSON(ctx, provider, GenerateRequest{System: "Be brief", Prompt: "Capital?"}, &result); err != nil {
+		t.Fatalf("Failed to generate JSON: %v", err)
+	}
+	if result.City != "Paris" {
+		t.Errorf("Expected city Paris, got %q", result.City)
+	}
+	last := provider.Requests[1]
+	if len(last.Messages) != 2 || last.Messages[0].Role != RoleSystem || !isFormatJSON(last.Format) {
+		t.Errorf("Expected system message and JSON format to be recorded, got %+v", last)
+	}
+
+	if _, err := provider.Generate(ctx, GenerateRequest{Prompt: "more"}); err == nil {
+		t.Errorf("Expected error when responses run out")
+	}
+
+	embeddings, err := provider.Embed(ctx, EmbedRequest{Input: []string{"a", "b", "a"}})
+	if err != nil {
+		t.Fatalf("Failed to embed: %v", err)
+	}
+	if len(embeddings[0]) != ScriptedEmbeddingSize || !slices.Equal(embeddings[0], embeddings[2]) || slices.Equal(embeddings[0], embeddings[1]) {
+		t.Errorf("Expected deterministic distinct embeddings, got %v", embeddings)
+	}
+
+	provider.Err = errors.New("offline")
+	if _, err := provider.Chat(ctx, ChatRequest{}); err == nil || err.Error() != "offline" {
+		t.Errorf("Expected scripted error, got %v", err)
+	}
+}
+
+func TestGenerateJSONInvalid(t *testing.T) {
+	var result map[string]any
+	err := GenerateJSON(context.Background(), NewScripted("not json"), GenerateRequest{Prompt: "x"}, &result)
+	if err == nil {
+		t.Errorf("Expected error for invalid JSON response")
+	}
+}
+
+func TestOpenAIChat(t *testing.T) {
+	var received map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Expected chat completions path, got %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Expected bearer token, got %q", r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		io.WriteString(w, `{"model":"m","choices":[{"message":{"role":"assistant","content":"Paris"}}],"usage":{"prompt_tokens":12,"completion_tokens":1}}`)
+	}))
+	defer server.Close()
+
+	temperature := 0.5
+	provider := NewOpenAI(server.URL+"/v1/", "secret")
+	resp, err := provider.Generate(context.Background(), GenerateRequest{
+		Model:   "m",
+		System:  "Be brief",
+		Prompt:  "Capital of France?",
+		Format:  json.RawMessage(`{"type":"object"}`),
+		Options: Options{Temperature: &temperature, MaxTokens: 10},
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+	if resp.Text != "Paris" || resp.PromptTokens != 12 || resp.CompletionTokens != 1 {
+		t.Errorf("Expected Paris with 12+1 tokens, got %+v", resp)
+	}
+
+	if messages := received["messages"].([]any); len(messages) != 2 {
+		t.Errorf("Expected system and user messages, got %v", messages)
+	}
+	if received["temperature"] != 0.5 || received["max_tokens"] != 10.0 {
+		t.Errorf("Expected temperature and max_tokens, got %v", received)
+	}
+	format := received["response_format"].(map[string]any)
+	if format["type"] != "json_schema" {
+		t.Errorf("Expected json_schema response format, got %v", format)
+	}
+	if _, ok := received["seed"]; ok {
+		t.Errorf("Expected unset seed to be omitted")
+	}
+}
+
+func TestOpenAIChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var received map[string]any
+		json.NewDecoder(r.Body).Decode(&received)
+		if received["stream"] != true {
+			t.Errorf("Expected streaming request, got %v", received)
+		}
+		io.WriteString(w, "data: {\"model\":\"m\",\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		io.WriteString(w, ": keep-alive\n\n")
+		io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n")
+		io.WriteString(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2}}\n\n")
+		io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	var tokens []string
+	resp, err := NewOpenAI(server.URL, "").ChatStream(context.Background(), ChatRequest{
+		Messages: []Message{{Role: RoleUser, Content: "Hi"}},
+	}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to stream: %v", err)
+	}
+	if resp.Text != "Hello" || len(tokens) != 2 || resp.Model != "m" || resp.CompletionTokens != 2 {
+		t.Errorf("Expected Hello in 2 tokens with usage, got %+v, tokens %q", resp, tokens)
+	}
+}
+
+func TestOpenAIEmbedAndErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/embeddings" {
+			io.WriteString(w, `{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error":{"message":"model not found"}}`)
+	}))
+	defer server.Close()
+
+	provider := NewOpenAI(server.URL, "")
+	embeddings, err := provider.Embed(context.Background(), EmbedRequest{Input: []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("Failed to embed: %v", err)
+	}
+	if embeddings[0][0] != 1 || embeddings[1][1] != 1 {
+		t.Errorf("Expected embeddings sorted by index, got %v", embeddings)
+	}
+
+	_, err = provider.Chat(context.Background(), ChatRequest{Model: "missing"})
+	if err == nil || !strings.Contains(err.Error(), "model not found") {
+		t.Errorf("Expected error with server message, got %v", err)
+	}
+}
+
+func TestOllamaChat(t *testing.T) {
+	var received api.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Expected chat path, got %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		io.WriteString(w, `{"model":"m","message":{"role":"assistant","content":"Hel"},"done":false}`+"\n")
+		io.WriteString(w, `{"model":"m","message":{"role":"assistant","content":"lo"},"done":true,"prompt_eval_count":4,"eval_count":2}`+"\n")
+	}))
+	defer server.Close()
+
+	base, _ := url.Parse(server.URL)
+	provider := NewOllamaWithClient(ollama.NewClientWithAPI(api.NewClient(base, server.Client())))
+
+	seed := 7
+	var tokens []string
+	resp, err := provider.ChatStream(context.Background(), ChatRequest{
+		Model:    "m",
+		Messages: []Message{{Role: RoleUser, Content: "Hi"}},
+		Format:   FormatJSON,
+		Options:  Options{Seed: &seed, ContextSize: 4096},
+	}, func(token string) error {
+		tokens = append(tokens, token)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to chat: %v", err)
+	}
+	if resp.Text != "Hello" || len(tokens) != 2 || resp.PromptTokens != 4 || resp.CompletionTokens != 2 {
+		t.Errorf("Expected Hello in 2 tokens with counts, got %+v, tokens %q", resp, tokens)
+	}
+	if received.Options["seed"] != 7.0 || received.Options["num_ctx"] != 4096.0 {
+		t.Errorf("Expected seed and num_ctx options, got %v", received.Options)
+	}
+	if string(received.Format) != `"json"` || len(received.Messages) != 1 {
+		t.Errorf("Expected JSON format and one message, got %+v", received)
+	}
+}
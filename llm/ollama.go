@@ -0,0 +1,110 @@
+package llm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/minmaxmean/sigma/ollama"
+)
+
+// Ollama is a provider backed by an Ollama server
+type Ollama struct {
+	client *ollama.Client
+}
+
+// NewOllama creates an Ollama provider using environment configuration
+// (OLLAMA_HOST)
+func NewOllama() (*Ollama, error) {
+	client, err := ollama.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewOllamaWithClient(client), nil
+}
+
+// NewOllamaWithClient creates an Ollama provider using an existing client
+func NewOllamaWithClient(client *ollama.Client) *Ollama {
+	return &Ollama{client: client}
+}
+
+// Generate completes a prompt with the generate endpoint
+func (o *Ollama) Generate(ctx context.Context, req GenerateRequest) (*Response, error) {
+	resp, err := o.client.GenerateFull(ctx, ollama.GenerateRequest{
+		Model:        req.Model,
+		Prompt:       req.Prompt,
+		SystemPrompt: req.System,
+		Format:       req.Format,
+		Options:      ollamaOptions(req.Options),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ollamaResponse(resp.Response, resp.Model, resp.Stats), nil
+}
+
+// Chat completes a conversation with the chat endpoint
+func (o *Ollama) Chat(ctx context.Context, req ChatRequest) (*Response, error) {
+	resp, err := o.client.Chat(ctx, ollamaChatRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	return ollamaResponse(resp.Message.Content, resp.Model, resp.Stats), nil
+}
+
+// ChatStream completes a conversation, passing tokens to fn as they arrive
+func (o *Ollama) ChatStream(ctx context.Context, req ChatRequest, fn TokenFunc) (*Response, error) {
+	var text strings.Builder
+	var final ollama.ChatResponse
+	for chunk, err := range o.client.ChatStream(ctx, ollamaChatRequest(req)) {
+		if err != nil {
+			return nil, err
+		}
+		if chunk.Done {
+			final = chunk
+		}
+		if chunk.Message.Content == "" {
+			continue
+		}
+		text.WriteString(chunk.Message.Content)
+		if err := fn(chunk.Message.Content); err != nil {
+			return nil, err
+		}
+	}
+	return ollamaResponse(text.String(), final.Model, final.Stats), nil
+}
+
+// Embed returns embedding vectors from the embed endpoint
+func (o *Ollama) Embed(ctx context.Context, req EmbedRequest) ([][]float32, error) {
+	return o.client.Embed(ctx, req.Model, req.Input)
+}
+
+func ollamaChatRequest(req ChatRequest) ollama.ChatRequest {
+	chatReq := ollama.ChatRequest{
+		Model:   req.Model,
+		Format:  req.Format,
+		Options: ollamaOptions(req.Options),
+	}
+	for _, message := range req.Messages {
+		chatReq.Messages = append(chatReq.Messages, ollama.Message{Role: message.Role, Content: message.Content})
+	}
+	return chatReq
+}
+
+func ollamaOptions(options Options) ollama.Options {
+	return ollama.Options{
+		Temperature: options.Temperature,
+		Seed:        options.Seed,
+		NumCtx:      options.ContextSize,
+		NumPredict:  options.MaxTokens,
+	}
+}
+
+func ollamaResponse(text, model string, stats ollama.Stats) *Response {
+	return &Response{
+		Text:             text,
+		Model:            model,
+		PromptTokens:     stats.PromptTokens,
+		CompletionTokens: stats.ResponseTokens,
+		Duration:         stats.TotalDuration,
+	}
+}
@@ -0,0 +1,259 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAI is a provider for servers implementing the OpenAI chat completions
+// and embeddings API, such as llama.cpp, vLLM and OpenAI itself
+type OpenAI struct {
+	// BaseURL is the API root including the version, for example
+	// "http://localhost:8080/v1"
+	BaseURL string
+	// APIKey is sent as a bearer token when set
+	APIKey string
+	Client *http.Client
+}
+
+// NewOpenAI creates an OpenAI-compatible provider
+func NewOpenAI(baseURL, apiKey string) *OpenAI {
+	return &OpenAI{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		APIKey:  apiKey,
+		Client:  http.DefaultClient,
+	}
+}
+
+// openAIMessage is a chat message in the OpenAI wire format
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []openAIMessage `json:"messages"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	Seed           *int            `json:"seed,omitempty"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+	Stream         bool            `json:"stream,omitempty"`
+	StreamOptions  any             `json:"stream_options,omitempty"`
+	ResponseFormat any             `json:"response_format,omitempty"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type openAIChatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+		Delta   openAIMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+type openAIEmbedResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Generate completes a prompt as a conversation of a system and a user message
+func (o *OpenAI) Generate(ctx context.Context, req GenerateRequest) (*Response, error) {
+	return o.Chat(ctx, ChatRequest{
+		Model:    req.Model,
+		Messages: req.messages(),
+		Format:   req.Format,
+		Options:  req.Options,
+	})
+}
+
+// Chat completes a conversation with the chat completions endpoint
+func (o *OpenAI) Chat(ctx context.Context, req ChatRequest) (*Response, error) {
+	start := time.Now()
+	body, err := o.post(ctx, "/chat/completions", o.chatRequest(req, false))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp openAIChatResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, errors.New("response has no choices")
+	}
+
+	result := &Response{Text: resp.Choices[0].Message.Content, Model: resp.Model, Duration: time.Since(start)}
+	if resp.Usage != nil {
+		result.PromptTokens = resp.Usage.PromptTokens
+		result.CompletionTokens = resp.Usage.CompletionTokens
+	}
+	return result, nil
+}
+
+// ChatStream completes a conversation reading server-sent events, passing
+// tokens to fn as they arrive
+func (o *OpenAI) ChatStream(ctx context.Context, req ChatRequest, fn TokenFunc) (*Response, error) {
+	start := time.Now()
+	body, err := o.post(ctx, "/chat/completions", o.chatRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	result := &Response{}
+	var text strings.Builder
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to decode stream chunk: %w", err)
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			result.PromptTokens = chunk.Usage.PromptTokens
+			result.CompletionTokens = chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		token := chunk.Choices[0].Delta.Content
+		text.WriteString(token)
+		if err := fn(token); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	result.Text = text.String()
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// Embed returns embedding vectors from the embeddings endpoint
+func (o *OpenAI) Embed(ctx context.Context, req EmbedRequest) ([][]float32, error) {
+	body, err := o.post(ctx, "/embeddings", map[string]any{"model": req.Model, "input": req.Input})
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var resp openAIEmbedResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(resp.Data) != len(req.Input) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(req.Input), len(resp.Data))
+	}
+
+	embeddings := make([][]float32, len(req.Input))
+	for _, item := range resp.Data {
+		if item.Index < 0 || item.Index >= len(embeddings) {
+			return nil, fmt.Errorf("invalid embedding index %d", item.Index)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+	return embeddings, nil
+}
+
+// chatRequest converts a chat request to the OpenAI wire format
+func (o *OpenAI) chatRequest(req ChatRequest, stream bool) openAIChatRequest {
+	wire := openAIChatRequest{
+		Model:       req.Model,
+		Temperature: req.Options.Temperature,
+		Seed:        req.Options.Seed,
+		MaxTokens:   req.Options.MaxTokens,
+		Stream:      stream,
+	}
+	for _, message := range req.Messages {
+		wire.Messages = append(wire.Messages, openAIMessage(message))
+	}
+	if stream {
+		wire.StreamOptions = map[string]bool{"include_usage": true}
+	}
+	switch {
+	case len(req.Format) == 0:
+	case isFormatJSON(req.Format):
+		wire.ResponseFormat = map[string]string{"type": "json_object"}
+	default:
+		wire.ResponseFormat = map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name":   "response",
+				"schema": req.Format,
+				"strict": true,
+			},
+		}
+	}
+	return wire
+}
+
+// post sends a JSON request and returns the body of a successful response
+func (o *OpenAI) post(ctx context.Context, path string, payload any) (io.ReadCloser, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.BaseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	}
+
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, apiError(resp)
+	}
+	return resp.Body, nil
+}
+
+// apiError builds an error from an unsuccessful response, using the error
+// message of the body when there is one
+func apiError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var payload struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Error.Message != "" {
+		return fmt.Errorf("%s: %s", resp.Status, payload.Error.Message)
+	}
+	return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/minmaxmean/sigma/siq"
+	"github.com/minmaxmean/sigma/siq/packtext"
+)
+
+// DefaultContextSize is the context window assumed when Options.ContextSize
+// is not set, the Ollama default
+const DefaultContextSize = 2048
+
+// DefaultResponseTokens is the part of the context window kept free for
+// the response when Options.MaxTokens is not set
+const DefaultResponseTokens = 512
+
+// promptOverheadTokens covers the prompt template around the package text
+const promptOverheadTokens = 32
+
+// PackOptions configure GenerateWithPackage
+type PackOptions struct {
+	// Scope limits the questions sent, by default the whole package
+	Scope packtext.Scope
+	// MaxTokens is the token budget of the package text of a request. By
+	// default it is what the context window leaves after the prompts and
+	// the response.
+	MaxTokens int
+	// HideAnswers and HidePrices leave answers and prices out of the text
+	HideAnswers bool
+	HidePrices  bool
+	// Progress is called after every request with the number of sent and
+	// total requests
+	Progress func(done, total int)
+}
+
+// GenerateWithPackage serializes the questions of the package within the
+// scope and generates a response for them. Packages that do not fit the
+// token budget together with the prompt are split into chunks, one request
+// per chunk, and the responses are returned in chunk order.
+func GenerateWithPackage(ctx context.Context, provider Provider, req GenerateRequest, pkg *siq.Package, options PackOptions) ([]*Response, error) {
+	budget := options.MaxTokens
+	if budget <= 0 {
+		contextSize := req.Options.ContextSize
+		if contextSize <= 0 {
+			contextSize = DefaultContextSize
+		}
+		responseTokens := req.Options.MaxTokens
+		if responseTokens <= 0 {
+			responseTokens = DefaultResponseTokens
+		}
+		budget = contextSize - responseTokens - promptOverheadTokens -
+			packtext.EstimateTokens(req.System) - packtext.EstimateTokens(req.Prompt)
+		if budget <= 0 {
+			return nil, fmt.Errorf("prompt does not leave room for package data in a context of %d tokens", contextSize)
+		}
+	}
+
+	chunks, err := packtext.Render(pkg, options.Scope, packtext.Options{
+		MaxTokens:   budget,
+		HideAnswers: options.HideAnswers,
+		HidePrices:  options.HidePrices,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize package: %w", err)
+	}
+
+	responses := make([]*Response, len(chunks))
+	for i, chunk := range chunks {
+		data := chunk
+		if len(chunks) > 1 {
+			data = fmt.Sprintf("part %d of %d\n%s", i+1, len(chunks), chunk)
+		}
+		chunkReq := req
+		chunkReq.Prompt = fmt.Sprintf("data: %s\nprompt: %s\n", data, req.Prompt)
+
+		resp, err := provider.Generate(ctx, chunkReq)
+		if err != nil {
+			return nil, fmt.Errorf("failed to process part %d of %d: %w", i+1, len(chunks), err)
+		}
+		responses[i] = resp
+		if options.Progress != nil {
+			options.Progress(i+1, len(chunks))
+		}
+	}
+	return responses, nil
+}
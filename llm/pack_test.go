@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/minmaxmean/sigma/siq"
+	"github.com/minmaxmean/sigma/siq/packtext"
+)
+
+func TestGenerateWithPackage(t *testing.T) {
+	pkg := &siq.Package{Name: "Pack", Rounds: []siq.Round{{Name: "Round", Themes: []siq.Theme{{Name: "Theme"}}}}}
+	for i := 1; i <= 20; i++ {
+		pkg.Rounds[0].Themes[0].Questions = append(pkg.Rounds[0].Themes[0].Questions, siq.Question{
+			BasePrice: i * 100,
+			Params: []siq.Param{{
+				Name:  siq.ParamNameQuestion,
+				Type:  siq.ParamTypeContent,
+				Items: []siq.ContentItem{{Type: siq.ContentTypeText, Value: strings.Repeat("question text ", 10)}},
+			}},
+			Right: []string{"answer"},
+		})
+	}
+	prompts := func(provider *Scripted) []string {
+		var result []string
+		for _, req := range provider.Requests {
+			result = append(result, req.Messages[len(req.Messages)-1].Content)
+		}
+		return result
+	}
+	ctx := context.Background()
+
+	provider := NewScripted(strings.Split(strings.Repeat("ok,", 20), ",")...)
+	req := GenerateRequest{Model: "m", Prompt: "Summarize the questions", Options: Options{ContextSize: 400, MaxTokens: 100}}
+	progress := 0
+	responses, err := GenerateWithPackage(ctx, provider, req, pkg, PackOptions{Progress: func(done, total int) { progress = done }})
+	if err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+	sent := prompts(provider)
+	if len(responses) < 2 || len(responses) != len(sent) || progress != len(sent) {
+		t.Fatalf("Expected one request per chunk and several chunks, got %d responses for %d requests", len(responses), len(sent))
+	}
+	for i, prompt := range sent {
+		if !strings.Contains(prompt, fmt.Sprintf("part %d of %d", i+1, len(sent))) || !strings.HasSuffix(prompt, "prompt: Summarize the questions\n") {
+			t.Errorf("Expected numbered part with the prompt, got %q", prompt)
+		}
+		if tokens := packtext.EstimateTokens(prompt); tokens > 400-100 {
+			t.Errorf("Expected prompt to fit the context, got %d tokens", tokens)
+		}
+	}
+
+	provider = NewScripted("ok")
+	responses, err = GenerateWithPackage(ctx, provider, req, pkg, PackOptions{Scope: packtext.Scope{Round: 1, Theme: 1, Question: 2}, HidePrices: true})
+	sent = prompts(provider)
+	if err != nil || len(responses) != 1 || strings.Contains(sent[0], "part 1") || !strings.Contains(sent[0], "[r1/t1/q2]") || strings.Contains(sent[0], "200") {
+		t.Errorf("Expected a single request for one question without its price, got %q (%v)", sent, err)
+	}
+
+	req.Prompt = strings.Repeat("long prompt ", 200)
+	if _, err := GenerateWithPackage(ctx, NewScripted(), req, pkg, PackOptions{}); err == nil {
+		t.Errorf("Expected error when the prompt fills the context")
+	}
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"strings"
+	"sync"
+)
+
+// ScriptedEmbeddingSize is the length of the vectors Scripted embeds by default
+const ScriptedEmbeddingSize = 8
+
+// Scripted is an in-memory provider returning prepared responses, for tests
+type Scripted struct {
+	mu sync.Mutex
+	// Responses are returned in order, one per Generate or Chat call
+	Responses []string
+	// Err is returned instead of a response when set
+	Err error
+	// Requests records the received requests, generate requests are
+	// recorded as chats of a system and a user message
+	Requests []ChatRequest
+	// EmbedFunc returns the vector of a text, by default a deterministic
+	// vector derived from the text hash
+	EmbedFunc func(text string) []float32
+}
+
+// NewScripted creates a scripted provider returning responses in order
+func NewScripted(responses ...string) *Scripted {
+	return &Scripted{Responses: responses}
+}
+
+// Generate records the request and returns the next response
+func (s *Scripted) Generate(ctx context.Context, req GenerateRequest) (*Response, error) {
+	return s.Chat(ctx, ChatRequest{
+		Model:    req.Model,
+		Messages: req.messages(),
+		Format:   req.Format,
+		Options:  req.Options,
+	})
+}
+
+// Chat records the request and returns the next response
+func (s *Scripted) Chat(ctx context.Context, req ChatRequest) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Requests = append(s.Requests, req)
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	if len(s.Responses) == 0 {
+		return nil, errors.New("no scripted response left")
+	}
+	text := s.Responses[0]
+	s.Responses = s.Responses[1:]
+
+	var prompt int
+	for _, message := range req.Messages {
+		prompt += len(strings.Fields(message.Content))
+	}
+	return &Response{
+		Text:             text,
+		Model:            req.Model,
+		PromptTokens:     prompt,
+		CompletionTokens: len(strings.Fields(text)),
+	}, nil
+}
+
+// ChatStream returns the next response, passing it to fn word by word
+func (s *Scripted) ChatStream(ctx context.Context, req ChatRequest, fn TokenFunc) (*Response, error) {
+	resp, err := s.Chat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range strings.SplitAfter(resp.Text, " ") {
+		if token == "" {
+			continue
+		}
+		if err := fn(token); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// Embed returns a vector for every input text
+func (s *Scripted) Embed(ctx context.Context, req EmbedRequest) ([][]float32, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	embed := s.EmbedFunc
+	if embed == nil {
+		embed = hashEmbedding
+	}
+	embeddings := make([][]float32, len(req.Input))
+	for i, text := range req.Input {
+		embeddings[i] = embed(text)
+	}
+	return embeddings, nil
+}
+
+// hashEmbedding returns a deterministic vector with components in [-1, 1)
+func hashEmbedding(text string) []float32 {
+	vector := make([]float32, ScriptedEmbeddingSize)
+	for i := range vector {
+		hash := fnv.New32a()
+		hash.Write([]byte{byte(i)})
+		hash.Write([]byte(text))
+		vector[i] = float32(hash.Sum32())/(1<<31) - 1
+	}
+	return vector
+}
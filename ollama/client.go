@@ -2,12 +2,27 @@ package ollama
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/ollama/ollama/api"
 )
 
+// Message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// errStopped ends a streaming request when the consumer stops iterating
+var errStopped = errors.New("stream stopped")
+
 // Client wraps the Ollama API client with simplified methods
 type Client struct {
 	apiClient *api.Client
@@ -22,38 +37,221 @@ func NewClient() (*Client, error) {
 	return &Client{apiClient: apiClient}, nil
 }
 
+// NewClientWithAPI creates a client wrapping an existing API client, for
+// example one pointing at a custom host
+func NewClientWithAPI(apiClient *api.Client) *Client {
+	return &Client{apiClient: apiClient}
+}
+
+// Options are model parameters. Zero values keep the model defaults.
+type Options struct {
+	Temperature *float64
+	Seed        *int
+	// NumCtx is the context window size in tokens
+	NumCtx int
+	// NumPredict limits the number of generated tokens
+	NumPredict int
+}
+
 // GenerateRequest represents a request to generate text
 type GenerateRequest struct {
 	Model        string
 	Prompt       string
 	SystemPrompt string
+	// Format constrains the response to JSON: either the string "json"
+	// encoded as JSON or a JSON schema object. Empty means free text.
+	Format  json.RawMessage
+	Options Options
+}
+
+// Stats are token counts and timings reported with the final response
+type Stats struct {
+	PromptTokens     int
+	ResponseTokens   int
+	TotalDuration    time.Duration
+	LoadDuration     time.Duration
+	PromptDuration   time.Duration
+	ResponseDuration time.Duration
 }
 
-// GenerateResponse represents the response from text generation
+// GenerateResponse represents the response from text generation. When
+// streaming, Response holds one chunk and only the final chunk has Done set
+// and Stats filled in.
 type GenerateResponse struct {
+	Model    string
 	Response string
 	Done     bool
+	Stats    Stats
+}
+
+// Message is a chat message
+type Message struct {
+	Role    string
+	Content string
+}
+
+// ChatRequest represents a request to continue a conversation
+type ChatRequest struct {
+	Model    string
+	Messages []Message
+	// Format constrains the response to JSON, see GenerateRequest
+	Format  json.RawMessage
+	Options Options
+}
+
+// ChatResponse represents the assistant reply to a conversation. When
+// streaming, Message holds one chunk and only the final chunk has Done set
+// and Stats filled in.
+type ChatResponse struct {
+	Model   string
+	Message Message
+	Done    bool
+	Stats   Stats
 }
 
 // Generate sends a text generation request to Ollama
 func (c *Client) Generate(ctx context.Context, req GenerateRequest) (string, error) {
-	apiReq := &api.GenerateRequest{
-		Model:  req.Model,
-		Prompt: req.Prompt,
-		System: req.SystemPrompt,
+	resp, err := c.GenerateFull(ctx, req)
+	if err != nil {
+		return "", err
 	}
+	return resp.Response, nil
+}
 
-	var response string
-	respFunc := func(resp api.GenerateResponse) error {
-		response += resp.Response
-		return nil
+// GenerateFull sends a text generation request and returns the whole
+// response with its stats
+func (c *Client) GenerateFull(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	var result GenerateResponse
+	var response strings.Builder
+	for chunk, err := range c.generate(ctx, req, false) {
+		if err != nil {
+			return nil, err
+		}
+		response.WriteString(chunk.Response)
+		if chunk.Done {
+			result = chunk
+		}
+	}
+	if !result.Done {
+		return nil, errors.New("failed to generate response: response ended before done")
 	}
+	result.Response = response.String()
+	return &result, nil
+}
+
+// GenerateStream sends a text generation request and yields response chunks
+// as they arrive. The final chunk has Done set and carries the stats.
+// Breaking out of the loop cancels the request.
+func (c *Client) GenerateStream(ctx context.Context, req GenerateRequest) iter.Seq2[GenerateResponse, error] {
+	return c.generate(ctx, req, true)
+}
 
-	if err := c.apiClient.Generate(ctx, apiReq, respFunc); err != nil {
-		return "", fmt.Errorf("failed to generate response: %w", err)
+func (c *Client) generate(ctx context.Context, req GenerateRequest, stream bool) iter.Seq2[GenerateResponse, error] {
+	return func(yield func(GenerateResponse, error) bool) {
+		apiReq := &api.GenerateRequest{
+			Model:   req.Model,
+			Prompt:  req.Prompt,
+			System:  req.SystemPrompt,
+			Format:  req.Format,
+			Stream:  &stream,
+			Options: req.Options.apiOptions(),
+		}
+
+		done := false
+		err := c.apiClient.Generate(ctx, apiReq, func(resp api.GenerateResponse) error {
+			if done {
+				return nil
+			}
+			done = resp.Done
+			chunk := GenerateResponse{Model: resp.Model, Response: resp.Response, Done: resp.Done}
+			if resp.Done {
+				chunk.Stats = newStats(resp.Metrics)
+			}
+			if !yield(chunk, nil) {
+				return errStopped
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopped) {
+			yield(GenerateResponse{}, fmt.Errorf("failed to generate response: %w", err))
+		}
 	}
+}
 
-	return response, nil
+// Chat sends a conversation to Ollama and returns the whole assistant reply
+func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	var result ChatResponse
+	var content strings.Builder
+	for chunk, err := range c.chat(ctx, req, false) {
+		if err != nil {
+			return nil, err
+		}
+		content.WriteString(chunk.Message.Content)
+		if chunk.Done {
+			result = chunk
+		}
+	}
+	if !result.Done {
+		return nil, errors.New("failed to chat: response ended before done")
+	}
+	result.Message = Message{Role: RoleAssistant, Content: content.String()}
+	return &result, nil
+}
+
+// ChatStream sends a conversation to Ollama and yields reply chunks as they
+// arrive. The final chunk has Done set and carries the stats. Breaking out
+// of the loop cancels the request.
+func (c *Client) ChatStream(ctx context.Context, req ChatRequest) iter.Seq2[ChatResponse, error] {
+	return c.chat(ctx, req, true)
+}
+
+func (c *Client) chat(ctx context.Context, req ChatRequest, stream bool) iter.Seq2[ChatResponse, error] {
+	return func(yield func(ChatResponse, error) bool) {
+		apiReq := &api.ChatRequest{
+			Model:   req.Model,
+			Format:  req.Format,
+			Stream:  &stream,
+			Options: req.Options.apiOptions(),
+		}
+		for _, message := range req.Messages {
+			apiReq.Messages = append(apiReq.Messages, api.Message{Role: message.Role, Content: message.Content})
+		}
+
+		done := false
+		err := c.apiClient.Chat(ctx, apiReq, func(resp api.ChatResponse) error {
+			if done {
+				return nil
+			}
+			done = resp.Done
+			chunk := ChatResponse{
+				Model:   resp.Model,
+				Message: Message{Role: resp.Message.Role, Content: resp.Message.Content},
+				Done:    resp.Done,
+			}
+			if resp.Done {
+				chunk.Stats = newStats(resp.Metrics)
+			}
+			if !yield(chunk, nil) {
+				return errStopped
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopped) {
+			yield(ChatResponse{}, fmt.Errorf("failed to chat: %w", err))
+		}
+	}
+}
+
+// Embed returns one embedding vector per input text
+func (c *Client) Embed(ctx context.Context, model string, input []string) ([][]float32, error) {
+	resp, err := c.apiClient.Embed(ctx, &api.EmbedRequest{Model: model, Input: input})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed: %w", err)
+	}
+	if len(resp.Embeddings) != len(input) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(input), len(resp.Embeddings))
+	}
+	return resp.Embeddings, nil
 }
 
 // GenerateWithFile combines file content with a prompt and generates a response
@@ -73,3 +271,32 @@ func (c *Client) GenerateWithFile(ctx context.Context, req GenerateRequest, file
 
 	return c.Generate(ctx, newReq)
 }
+
+// apiOptions converts options to the API options map, leaving out unset values
+func (o Options) apiOptions() map[string]any {
+	options := make(map[string]any)
+	if o.Temperature != nil {
+		options["temperature"] = *o.Temperature
+	}
+	if o.Seed != nil {
+		options["seed"] = *o.Seed
+	}
+	if o.NumCtx > 0 {
+		options["num_ctx"] = o.NumCtx
+	}
+	if o.NumPredict > 0 {
+		options["num_predict"] = o.NumPredict
+	}
+	return options
+}
+
+func newStats(metrics api.Metrics) Stats {
+	return Stats{
+		PromptTokens:     metrics.PromptEvalCount,
+		ResponseTokens:   metrics.EvalCount,
+		TotalDuration:    metrics.TotalDuration,
+		LoadDuration:     metrics.LoadDuration,
+		PromptDuration:   metrics.PromptEvalDuration,
+		ResponseDuration: metrics.EvalDuration,
+	}
+}
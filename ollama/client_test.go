@@ -2,8 +2,16 @@ package ollama
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/ollama/ollama/api"
 )
 
 func TestNewClient(t *testing.T) {
@@ -49,3 +57,121 @@ func TestGenerateRequest(t *testing.T) {
 		t.Log("Note: Response doesn't contain 'hello' - this might be expected depending on the model")
 	}
 }
+
+// newTestClient returns a client for a server answering every request with
+// the given newline-delimited JSON lines and records the request body
+func newTestClient(t *testing.T, lines ...string) (*Client, *map[string]any) {
+	t.Helper()
+	received := map[string]any{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		for _, line := range lines {
+			io.WriteString(w, line+"\n")
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	base, _ := url.Parse(server.URL)
+	return NewClientWithAPI(api.NewClient(base, server.Client())), &received
+}
+
+func TestGenerateFullStats(t *testing.T) {
+	client, received := newTestClient(t,
+		`{"model":"m","response":"Hel","done":false}`,
+		`{"model":"m","response":"lo","done":true,"prompt_eval_count":5,"eval_count":2,"total_duration":3000000}`,
+	)
+
+	temperature := 0.1
+	resp, err := client.GenerateFull(context.Background(), GenerateRequest{
+		Model:   "m",
+		Prompt:  "Hi",
+		Format:  json.RawMessage(`"json"`),
+		Options: Options{Temperature: &temperature, NumCtx: 2048},
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+	if resp.Response != "Hello" || !resp.Done {
+		t.Errorf("Expected done response Hello, got %+v", resp)
+	}
+	if resp.Stats.PromptTokens != 5 || resp.Stats.ResponseTokens != 2 || resp.Stats.TotalDuration != 3*time.Millisecond {
+		t.Errorf("Expected stats from the final response, got %+v", resp.Stats)
+	}
+
+	options := (*received)["options"].(map[string]any)
+	if options["temperature"] != 0.1 || options["num_ctx"] != 2048.0 {
+		t.Errorf("Expected temperature and num_ctx options, got %v", options)
+	}
+	if _, ok := options["seed"]; ok {
+		t.Errorf("Expected unset seed to be omitted, got %v", options)
+	}
+	if (*received)["format"] != "json" || (*received)["stream"] != false {
+		t.Errorf("Expected JSON format without streaming, got %v", *received)
+	}
+}
+
+func TestGenerateIncomplete(t *testing.T) {
+	client, _ := newTestClient(t, `{"model":"m","response":"Hel","done":false}`)
+	if _, err := client.Generate(context.Background(), GenerateRequest{Model: "m"}); err == nil {
+		t.Errorf("Expected error for a response without done")
+	}
+}
+
+func TestGenerateStream(t *testing.T) {
+	client, received := newTestClient(t,
+		`{"response":"a","done":false}`,
+		`{"response":"b","done":false}`,
+		`{"response":"","done":true,"eval_count":2}`,
+	)
+
+	var chunks []string
+	var final GenerateResponse
+	for chunk, err := range client.GenerateStream(context.Background(), GenerateRequest{Model: "m"}) {
+		if err != nil {
+			t.Fatalf("Failed to stream: %v", err)
+		}
+		chunks = append(chunks, chunk.Response)
+		final = chunk
+	}
+	if strings.Join(chunks, "") != "ab" || !final.Done || final.Stats.ResponseTokens != 2 {
+		t.Errorf("Expected chunks a, b and a final done chunk, got %q, %+v", chunks, final)
+	}
+	if (*received)["stream"] != true {
+		t.Errorf("Expected streaming request, got %v", *received)
+	}
+
+	count := 0
+	for range client.GenerateStream(context.Background(), GenerateRequest{Model: "m"}) {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("Expected iteration to stop after break, got %d chunks", count)
+	}
+}
+
+func TestChat(t *testing.T) {
+	client, received := newTestClient(t,
+		`{"model":"m","message":{"role":"assistant","content":"Par"},"done":false}`,
+		`{"model":"m","message":{"role":"assistant","content":"is"},"done":true,"prompt_eval_count":9}`,
+	)
+
+	seed := 1
+	resp, err := client.Chat(context.Background(), ChatRequest{
+		Model: "m",
+		Messages: []Message{
+			{Role: RoleSystem, Content: "Be brief"},
+			{Role: RoleUser, Content: "Capital of France?"},
+		},
+		Options: Options{Seed: &seed},
+	})
+	if err != nil {
+		t.Fatalf("Failed to chat: %v", err)
+	}
+	if resp.Message.Content != "Paris" || resp.Message.Role != RoleAssistant || resp.Stats.PromptTokens != 9 {
+		t.Errorf("Expected assistant reply Paris with stats, got %+v", resp)
+	}
+	if messages := (*received)["messages"].([]any); len(messages) != 2 {
+		t.Errorf("Expected message history to be sent, got %v", messages)
+	}
+}
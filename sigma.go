@@ -18,6 +18,18 @@ It provides detailed information about SIG packages including questions, answers
 func init() {
 	rootCmd.AddCommand(cmd.GetReadCmd())
 	rootCmd.AddCommand(cmd.GetMarkdownCmd())
+	rootCmd.AddCommand(cmd.GetSearchCmd())
+	rootCmd.AddCommand(cmd.GetLibraryCmd())
+	rootCmd.AddCommand(cmd.GetDedupeCmd())
+	rootCmd.AddCommand(cmd.GetDiffCmd())
+	rootCmd.AddCommand(cmd.GetMergeCmd())
+	rootCmd.AddCommand(cmd.GetSplitCmd())
+	rootCmd.AddCommand(cmd.GetComposeCmd())
+	rootCmd.AddCommand(cmd.GetOptimizeCmd())
+	rootCmd.AddCommand(cmd.GetMediaCmd())
+	rootCmd.AddCommand(cmd.GetBundleCmd())
+	rootCmd.AddCommand(cmd.GetContactSheetCmd())
+	rootCmd.AddCommand(cmd.GetAICmd())
 }
 
 func main() {
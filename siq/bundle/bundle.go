@@ -0,0 +1,304 @@
+// Package bundle embeds externally linked media into a SIQ package so it
+// keeps working offline and after the linked sites disappear
+package bundle
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/minmaxmean/sigma/siq"
+	"github.com/minmaxmean/sigma/siq/media"
+)
+
+// Options configures bundling
+type Options struct {
+	// Workers is the number of URLs fetched concurrently
+	Workers int
+}
+
+// Fetched is an external file embedded into the package
+type Fetched struct {
+	URL string
+	// Path is the archive path of the embedded file
+	Path  string
+	Size  int64
+	Items int
+}
+
+// Failure is an external file that could not be embedded
+type Failure struct {
+	URL   string
+	Items int
+	Err   error
+}
+
+// Result summarizes a bundling run
+type Result struct {
+	Fetched []Fetched
+	Failed  []Failure
+	// Skipped are external values that are not HTTP or HTTPS URLs
+	Skipped []string
+}
+
+// Items returns the number of content items rewritten to file references
+func (r *Result) Items() int {
+	total := 0
+	for _, fetched := range r.Fetched {
+		total += fetched.Items
+	}
+	return total
+}
+
+// link is an external URL together with everything referring to it
+type link struct {
+	url    string
+	folder string
+	items  []*siq.ContentItem
+	logo   bool
+}
+
+// fetchOutcome is the result of fetching a link
+type fetchOutcome struct {
+	data []byte
+	err  error
+}
+
+// formatExtensions are the file extensions added to URLs without one
+var formatExtensions = map[string]string{
+	media.FormatPNG:  ".png",
+	media.FormatJPEG: ".jpg",
+	media.FormatGIF:  ".gif",
+	media.FormatWebP: ".webp",
+	media.FormatMP3:  ".mp3",
+	media.FormatOGG:  ".ogg",
+	media.FormatWAV:  ".wav",
+	media.FormatMP4:  ".mp4",
+	media.FormatWebM: ".webm",
+}
+
+// Bundle fetches the external media of the package at input and writes a
+// copy to output in which the fetched files are stored in their media
+// folders and the items refer to them. Items whose URLs cannot be fetched
+// are left unchanged. Identical downloads are stored once. Output may be
+// the input file.
+func Bundle(ctx context.Context, input, output string, fetcher Fetcher, options Options) (*Result, error) {
+	reader, err := siq.NewSIQReader(input)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	pkg, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	links := collectLinks(pkg, result)
+	outcomes := fetchAll(ctx, fetcher, links, options.Workers)
+
+	used := make(map[string]bool)
+	for _, name := range reader.ListFiles() {
+		used[strings.ToLower(siq.DecodeFileName(name))] = true
+	}
+	type content struct {
+		folder string
+		hash   [sha256.Size]byte
+	}
+	stored := make(map[content]string)
+	added := make(map[string][]byte)
+	var order []string
+
+	for i, link := range links {
+		outcome := outcomes[i]
+		if outcome.err != nil {
+			result.Failed = append(result.Failed, Failure{URL: link.url, Items: link.count(), Err: outcome.err})
+			continue
+		}
+
+		key := content{folder: link.folder, hash: sha256.Sum256(outcome.data)}
+		filePath, ok := stored[key]
+		if !ok {
+			filePath = uniquePath(link.folder+"/"+fileName(link.url, outcome.data), used)
+			stored[key] = filePath
+			added[filePath] = outcome.data
+			order = append(order, filePath)
+		}
+
+		name := path.Base(filePath)
+		for _, item := range link.items {
+			item.IsRef = true
+			item.Value = name
+		}
+		if link.logo {
+			pkg.Logo = "@" + name
+		}
+		result.Fetched = append(result.Fetched, Fetched{
+			URL:   link.url,
+			Path:  filePath,
+			Size:  int64(len(outcome.data)),
+			Items: link.count(),
+		})
+	}
+
+	err = siq.ReplaceFile(output, func(writer *siq.SIQWriter) error {
+		return writeFiles(writer, reader, pkg, order, added)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// collectLinks groups the external media of the package by URL. Files of
+// different content types behind the same URL are fetched separately since
+// they go to different folders.
+func collectLinks(pkg *siq.Package, result *Result) []*link {
+	var links []*link
+	byKey := make(map[string]*link)
+	get := func(rawURL, folder string) *link {
+		key := folder + " " + rawURL
+		if l, ok := byKey[key]; ok {
+			return l
+		}
+		l := &link{url: rawURL, folder: folder}
+		byKey[key] = l
+		links = append(links, l)
+		return l
+	}
+
+	if pkg.Logo != "" && !strings.HasPrefix(pkg.Logo, "@") {
+		if isURL(pkg.Logo) {
+			get(pkg.Logo, siq.FolderImages).logo = true
+		} else {
+			result.Skipped = append(result.Skipped, pkg.Logo)
+		}
+	}
+
+	pkg.Walk(func(_ siq.QuestionRef, question *siq.Question) error {
+		for item := range question.ContentItems() {
+			folder := siq.MediaFolder(item.GetType())
+			if item.IsRef || folder == "" || item.Value == "" {
+				continue
+			}
+			if !isURL(item.Value) {
+				result.Skipped = append(result.Skipped, item.Value)
+				continue
+			}
+			l := get(item.Value, folder)
+			l.items = append(l.items, item)
+		}
+		return nil
+	})
+	return links
+}
+
+// count returns the number of items and logos referring to the link
+func (l *link) count() int {
+	if l.logo {
+		return len(l.items) + 1
+	}
+	return len(l.items)
+}
+
+// isURL reports whether value is an HTTP or HTTPS URL
+func isURL(value string) bool {
+	lower := strings.ToLower(value)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// fetchAll fetches links concurrently, keeping the order of links
+func fetchAll(ctx context.Context, fetcher Fetcher, links []*link, workers int) []fetchOutcome {
+	if workers < 1 {
+		workers = 1
+	}
+
+	outcomes := make([]fetchOutcome, len(links))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(workers, len(links)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				data, err := fetcher.Fetch(ctx, links[index].url)
+				outcomes[index] = fetchOutcome{data: data, err: err}
+			}
+		}()
+	}
+
+	for index := range links {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	return outcomes
+}
+
+// fileName derives an archive file name from the last path segment of a
+// URL. Characters that are unsafe in archive names are replaced and an
+// extension is added from the content when the URL has none.
+func fileName(rawURL string, data []byte) string {
+	name := ""
+	if u, err := url.Parse(rawURL); err == nil && !strings.HasSuffix(u.Path, "/") {
+		name = path.Base(u.Path)
+	}
+	name = strings.Map(func(r rune) rune {
+		if r < ' ' || strings.ContainsRune(`%/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." {
+		name = "file"
+	}
+
+	if path.Ext(name) == "" {
+		name += formatExtensions[media.Sniff(data)]
+	}
+	return name
+}
+
+// uniquePath returns filePath or a numbered variant of it that is not used
+// yet and marks it as used. Names are compared case-insensitively.
+func uniquePath(filePath string, used map[string]bool) string {
+	unique := filePath
+	for n := 2; used[strings.ToLower(unique)]; n++ {
+		unique = siq.RenameFile(filePath, n)
+	}
+	used[strings.ToLower(unique)] = true
+	return unique
+}
+
+// writeFiles writes the package, copies the original archive files and
+// adds the fetched ones
+func writeFiles(writer *siq.SIQWriter, reader *siq.SIQReader, pkg *siq.Package, order []string, added map[string][]byte) error {
+	if err := writer.WritePackage(pkg); err != nil {
+		return err
+	}
+	for _, name := range reader.ListFiles() {
+		if name == "content.xml" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		file, err := reader.GetFile(name)
+		if err != nil {
+			return err
+		}
+		if err := writer.CopyFile(file, name); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", name, err)
+		}
+	}
+	for _, filePath := range order {
+		if err := writer.WriteFile(filePath, added[filePath]); err != nil {
+			return fmt.Errorf("failed to write %s: %w", filePath, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package bundle
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minmaxmean/sigma/internal/siqtest"
+	"github.com/minmaxmean/sigma/siq"
+)
+
+const externalPackageXML = `<?xml version="1.0" encoding="utf-8"?>
+<package id="ext" name="External" version="5" logo="https://example.com/logo.png">
+	<round name="Round 1">
+		<theme name="Theme 1">
+			<question price="100">
+				<params>
+					<param name="question" type="content">
+						<item type="image">https://example.com/img/cat.png</item>
+						<item type="audio">https://cdn.example.org/sound?id=1</item>
+						<item type="video">https://example.com/missing.mp4</item>
+						<item type="image">local.png</item>
+					</param>
+					<param name="answer" type="content">
+						<item type="image">https://example.com/img/cat.png</item>
+						<item type="image">https://mirror.example.net/copy.png</item>
+					</param>
+				</params>
+				<right><answer>Cat</answer></right>
+			</question>
+		</theme>
+	</round>
+</package>`
+
+// createMirror writes files into a directory laid out for DirFetcher
+func createMirror(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal("Failed to create mirror directory:", err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal("Failed to write mirror file:", err)
+		}
+	}
+	return dir
+}
+
+func readFile(t *testing.T, reader *siq.SIQReader, name string) string {
+	file, err := reader.GetFile(name)
+	if err != nil {
+		t.Fatalf("Expected %s in package: %v", name, err)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		t.Fatal("Failed to open file:", err)
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(rc)
+	return string(data)
+}
+
+func TestBundle(t *testing.T) {
+	dir := t.TempDir()
+	input := siqtest.CreateFile(t, dir, "in.siq", externalPackageXML, map[string]string{
+		"Images/cat.png":   "existing cat",
+		"Images/local.png": "local",
+	})
+	mirror := createMirror(t, map[string]string{
+		"example.com/logo.png":        "logo",
+		"example.com/img/cat.png":     "remote cat",
+		"mirror.example.net/copy.png": "remote cat",
+		"cdn.example.org/sound":       "RIFF\x00\x00\x00\x00WAVEfmt ",
+	})
+	output := filepath.Join(dir, "out.siq")
+
+	result, err := Bundle(context.Background(), input, output, DirFetcher{Dir: mirror}, Options{Workers: 2})
+	if err != nil {
+		t.Fatal("Failed to bundle package:", err)
+	}
+
+	if len(result.Fetched) != 4 || result.Items() != 5 {
+		t.Errorf("Expected 4 fetched URLs used by 5 items, got %d and %d", len(result.Fetched), result.Items())
+	}
+	if len(result.Failed) != 1 || result.Failed[0].URL != "https://example.com/missing.mp4" {
+		t.Errorf("Expected missing.mp4 to fail, got %+v", result.Failed)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != "local.png" {
+		t.Errorf("Expected local.png to be skipped, got %v", result.Skipped)
+	}
+
+	reader, err := siq.NewSIQReader(output)
+	if err != nil {
+		t.Fatal("Failed to open bundled package:", err)
+	}
+	defer reader.Close()
+	pkg, err := reader.Read()
+	if err != nil {
+		t.Fatal("Failed to read bundled package:", err)
+	}
+
+	if pkg.Logo != "@logo.png" || readFile(t, reader, "Images/logo.png") != "logo" {
+		t.Errorf("Expected logo to be embedded, got %s", pkg.Logo)
+	}
+
+	question := pkg.Rounds[0].Themes[0].Questions[0]
+	items := question.GetQuestionContent()
+	expected := []siq.ContentItem{
+		{Type: siq.ContentTypeImage, IsRef: true, Value: "cat_2.png"},
+		{Type: siq.ContentTypeAudio, IsRef: true, Value: "sound.wav"},
+		{Type: siq.ContentTypeVideo, Value: "https://example.com/missing.mp4"},
+		{Type: siq.ContentTypeImage, Value: "local.png"},
+	}
+	for i, item := range expected {
+		if items[i].Type != item.Type || items[i].IsRef != item.IsRef || items[i].Value != item.Value {
+			t.Errorf("Expected item %d to be %+v, got %+v", i, item, items[i])
+		}
+	}
+
+	// Both answer images have the same content and share one file
+	for _, item := range question.AnswerContent() {
+		if !item.IsRef || item.Value != "cat_2.png" {
+			t.Errorf("Expected answer item to reference cat_2.png, got %+v", item)
+		}
+	}
+
+	if readFile(t, reader, "Images/cat.png") != "existing cat" || readFile(t, reader, "Images/cat_2.png") != "remote cat" {
+		t.Error("Expected existing file to be kept next to the fetched one")
+	}
+	if len(reader.ListFiles()) != 6 {
+		t.Errorf("Expected 6 files in bundled package, got %v", reader.ListFiles())
+	}
+}
+
+func TestBundleInPlace(t *testing.T) {
+	dir := t.TempDir()
+	input := siqtest.CreateFile(t, dir, "in.siq", externalPackageXML, map[string]string{
+		"Images/cat.png": "existing cat",
+	})
+	mirror := createMirror(t, map[string]string{"example.com/logo.png": "logo"})
+
+	if _, err := Bundle(context.Background(), input, input, DirFetcher{Dir: mirror}, Options{Workers: 1}); err != nil {
+		t.Fatal("Failed to bundle package in place:", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected only the bundled package to remain, got %d files", len(entries))
+	}
+
+	reader, err := siq.NewSIQReader(input)
+	if err != nil {
+		t.Fatal("Failed to open bundled package:", err)
+	}
+	defer reader.Close()
+	pkg, err := reader.Read()
+	if err != nil {
+		t.Fatal("Failed to read bundled package:", err)
+	}
+	if pkg.Logo != "@logo.png" || readFile(t, reader, "Images/logo.png") != "logo" {
+		t.Errorf("Expected logo to be embedded in place, got %s", pkg.Logo)
+	}
+	if readFile(t, reader, "Images/cat.png") != "existing cat" {
+		t.Error("Expected existing file to be kept in place")
+	}
+}
+
+func TestDirFetcherStaysInsideDir(t *testing.T) {
+	fetcher := DirFetcher{Dir: createMirror(t, map[string]string{"example.com/a.png": "a"})}
+
+	if data, err := fetcher.Fetch(context.Background(), "https://example.com/../../a.png"); err != nil || string(data) != "a" {
+		t.Errorf("Expected dot segments to be cleaned, got %q (%v)", data, err)
+	}
+	if _, err := fetcher.Fetch(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Error("Expected error for URL without host")
+	}
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok.png":
+			w.Write([]byte("image data"))
+		case "/large.png":
+			w.Write(make([]byte, 100))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(0)
+	fetcher.MaxSize = 50
+
+	if data, err := fetcher.Fetch(context.Background(), server.URL+"/ok.png"); err != nil || string(data) != "image data" {
+		t.Errorf("Expected image data, got %q (%v)", data, err)
+	}
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/missing.png"); err == nil {
+		t.Error("Expected error for 404 response")
+	}
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/large.png"); err == nil {
+		t.Error("Expected error for response larger than MaxSize")
+	}
+}
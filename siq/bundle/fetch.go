@@ -0,0 +1,90 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Fetcher downloads the content of an external media URL
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) ([]byte, error)
+}
+
+// DefaultMaxSize is the largest file HTTPFetcher downloads by default
+const DefaultMaxSize = 100 << 20
+
+// HTTPFetcher downloads media over HTTP and HTTPS
+type HTTPFetcher struct {
+	Client *http.Client
+	// MaxSize is the largest accepted response body, 0 means no limit
+	MaxSize int64
+}
+
+// NewHTTPFetcher creates an HTTP fetcher with a per-request timeout
+func NewHTTPFetcher(timeout time.Duration) *HTTPFetcher {
+	return &HTTPFetcher{
+		Client:  &http.Client{Timeout: timeout},
+		MaxSize: DefaultMaxSize,
+	}
+}
+
+// Fetch downloads rawURL and fails on non-2xx responses and bodies larger
+// than MaxSize
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "sigma")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body := io.Reader(resp.Body)
+	if f.MaxSize > 0 {
+		body = io.LimitReader(resp.Body, f.MaxSize+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if f.MaxSize > 0 && int64(len(data)) > f.MaxSize {
+		return nil, fmt.Errorf("file is larger than %d bytes", f.MaxSize)
+	}
+	return data, nil
+}
+
+// DirFetcher reads media from a local mirror instead of the network. The
+// URL "https://example.com/img/cat.png" is read from the file
+// "example.com/img/cat.png" inside Dir, the layout "wget -x" creates.
+type DirFetcher struct {
+	Dir string
+}
+
+// Fetch reads the mirrored file of rawURL
+func (f DirFetcher) Fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" || u.Host == ".." || strings.ContainsAny(u.Host, `/\`) {
+		return nil, fmt.Errorf("invalid host in %s", rawURL)
+	}
+	// Cleaning the rooted path keeps lookups inside Dir
+	name := filepath.Join(f.Dir, u.Host, filepath.FromSlash(filepath.Clean("/"+u.Path)))
+	return os.ReadFile(name)
+}
@@ -0,0 +1,239 @@
+// Package calibrate checks question prices against difficulty estimated by
+// a language model. Questions of a theme should get harder as their price
+// grows; pairs where the more expensive question is easier are reported as
+// inversions and can be fixed by reordering the questions of the theme.
+package calibrate
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/minmaxmean/sigma/llm"
+	"github.com/minmaxmean/sigma/siq"
+	"github.com/minmaxmean/sigma/siq/packtext"
+)
+
+// MaxDifficulty is the highest difficulty score, the lowest is 1
+const MaxDifficulty = 10
+
+// systemPrompt describes the scoring task to the model
+const systemPrompt = `You rate the difficulty of quiz game questions for an average group of players.
+Every question is given on one line starting with its ID in brackets, followed by the question, its options and its answers.
+Rate each question from 1 (almost everyone answers) to 10 (almost nobody answers) and give a short reason. Rate questions independently of their order.`
+
+// Options configures calibration
+type Options struct {
+	Model string
+	// Scope limits the questions scored, by default the whole package
+	Scope packtext.Scope
+	// ChunkTokens is the estimated token budget of the questions of a request
+	ChunkTokens int
+	// MinGap is the smallest difficulty difference reported as an inversion
+	MinGap int
+	// Reorder makes CalibrateFile reorder themes with inversions
+	Reorder bool
+	// Progress is called after every request with the number of sent and
+	// total requests
+	Progress func(done, total int)
+}
+
+// DefaultOptions returns the default calibration options
+func DefaultOptions() Options {
+	return Options{
+		ChunkTokens: 1500,
+		MinGap:      1,
+	}
+}
+
+// Score is the estimated difficulty of a question
+type Score struct {
+	Ref  siq.QuestionRef
+	Text string
+	// Difficulty is between 1 and MaxDifficulty, 0 when the model gave no score
+	Difficulty int
+	Reason     string
+}
+
+// Inversion is a pair of questions of a theme where the more expensive
+// question was rated easier
+type Inversion struct {
+	Cheaper   Score
+	Expensive Score
+}
+
+// Gap returns by how much the expensive question was rated easier
+func (i Inversion) Gap() int {
+	return i.Cheaper.Difficulty - i.Expensive.Difficulty
+}
+
+// Theme holds the scores of the questions of a theme in theme order
+type Theme struct {
+	RoundIndex int // zero-based
+	ThemeIndex int // zero-based, within the round
+	RoundName  string
+	ThemeName  string
+	Scores     []Score
+	Inversions []Inversion
+}
+
+// Report is the result of scoring a package
+type Report struct {
+	Themes []Theme
+	// Unscored lists the IDs of questions the model gave no score for
+	Unscored []string
+	// Moved is the number of questions moved by CalibrateFile
+	Moved    int
+	Requests int
+	// PromptTokens and CompletionTokens are the totals reported by the provider
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Inversions returns the number of inversions of all themes
+func (r *Report) Inversions() int {
+	count := 0
+	for _, theme := range r.Themes {
+		count += len(theme.Inversions)
+	}
+	return count
+}
+
+// scoreResponse is the JSON structure the model responds with
+type scoreResponse struct {
+	Scores []struct {
+		ID         string `json:"id"`
+		Difficulty int    `json:"difficulty"`
+		Reason     string `json:"reason"`
+	} `json:"scores"`
+}
+
+// Estimate asks the model to score the questions within the scope and
+// reports inversions of prices and scores within themes. Questions are sent
+// without prices so the scores are not biased by them.
+func Estimate(ctx context.Context, provider llm.Provider, pkg *siq.Package, options Options) (*Report, error) {
+	schema, err := responseSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	responses, err := llm.GenerateWithPackage(ctx, provider, llm.GenerateRequest{
+		Model:  options.Model,
+		System: systemPrompt,
+		Prompt: "Rate the difficulty of every question.",
+		Format: schema,
+	}, pkg, llm.PackOptions{
+		Scope:      options.Scope,
+		MaxTokens:  options.ChunkTokens,
+		HidePrices: true,
+		Progress:   options.Progress,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to score questions: %w", err)
+	}
+
+	report := &Report{Requests: len(responses)}
+	scores := make(map[string]scoreEntry)
+	for _, resp := range responses {
+		report.PromptTokens += resp.PromptTokens
+		report.CompletionTokens += resp.CompletionTokens
+
+		var payload scoreResponse
+		if err := json.Unmarshal([]byte(resp.Text), &payload); err != nil {
+			return nil, fmt.Errorf("failed to parse scores: %w", err)
+		}
+		// Unknown IDs and scores out of range are ignored, the questions are
+		// reported as unscored
+		for _, score := range payload.Scores {
+			if score.Difficulty >= 1 && score.Difficulty <= MaxDifficulty {
+				scores[score.ID] = scoreEntry{score.Difficulty, score.Reason}
+			}
+		}
+	}
+
+	err = pkg.Walk(func(ref siq.QuestionRef, question *siq.Question) error {
+		if !options.Scope.Contains(ref) {
+			return nil
+		}
+		last := len(report.Themes) - 1
+		if last < 0 || report.Themes[last].RoundIndex != ref.RoundIndex || report.Themes[last].ThemeIndex != ref.ThemeIndex {
+			report.Themes = append(report.Themes, Theme{
+				RoundIndex: ref.RoundIndex,
+				ThemeIndex: ref.ThemeIndex,
+				RoundName:  ref.RoundName,
+				ThemeName:  ref.ThemeName,
+			})
+			last++
+		}
+		entry, ok := scores[ref.ID()]
+		if !ok {
+			report.Unscored = append(report.Unscored, ref.ID())
+		}
+		report.Themes[last].Scores = append(report.Themes[last].Scores, Score{
+			Ref:        ref,
+			Text:       packtext.ItemsText(question.GetQuestionContent()),
+			Difficulty: entry.difficulty,
+			Reason:     entry.reason,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range report.Themes {
+		report.Themes[i].Inversions = inversions(report.Themes[i].Scores, max(options.MinGap, 1))
+	}
+	return report, nil
+}
+
+type scoreEntry struct {
+	difficulty int
+	reason     string
+}
+
+// inversions returns the pairs of scored questions where the more expensive
+// question is easier by at least minGap, largest gaps first. Prices selected
+// from a number set are not comparable and are skipped.
+func inversions(scores []Score, minGap int) []Inversion {
+	var result []Inversion
+	for _, a := range scores {
+		for _, b := range scores {
+			if a.Difficulty == 0 || b.Difficulty == 0 || a.Ref.Price.IsSet() || b.Ref.Price.IsSet() ||
+				a.Ref.Price.Value >= b.Ref.Price.Value {
+				continue
+			}
+			if a.Difficulty-b.Difficulty >= minGap {
+				result = append(result, Inversion{Cheaper: a, Expensive: b})
+			}
+		}
+	}
+	slices.SortStableFunc(result, func(x, y Inversion) int {
+		return cmp.Compare(y.Gap(), x.Gap())
+	})
+	return result
+}
+
+// responseSchema returns the JSON schema of the scores
+func responseSchema() (json.RawMessage, error) {
+	return json.Marshal(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"scores": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"id":         map[string]string{"type": "string"},
+						"difficulty": map[string]any{"type": "integer", "minimum": 1, "maximum": MaxDifficulty},
+						"reason":     map[string]string{"type": "string"},
+					},
+					"required": []string{"id", "difficulty", "reason"},
+				},
+			},
+		},
+		"required": []string{"scores"},
+	})
+}
@@ -0,0 +1,261 @@
+package calibrate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/minmaxmean/sigma/internal/siqtest"
+	"github.com/minmaxmean/sigma/llm"
+	"github.com/minmaxmean/sigma/siq"
+	"github.com/minmaxmean/sigma/siq/packtext"
+)
+
+func textQuestion(price int, text string, right ...string) siq.Question {
+	return siq.Question{
+		Type:      siq.QuestionTypeSimple,
+		BasePrice: price,
+		Params: []siq.Param{{
+			Name:  siq.ParamNameQuestion,
+			Type:  siq.ParamTypeContent,
+			Items: []siq.ContentItem{{Type: siq.ContentTypeText, Value: text}},
+		}},
+		Right: right,
+	}
+}
+
+func testPackage() *siq.Package {
+	return &siq.Package{
+		Name: "Pack",
+		Rounds: []siq.Round{{
+			Name: "Round 1",
+			Themes: []siq.Theme{
+				{Name: "Capitals", Questions: []siq.Question{
+					textQuestion(100, "Capital of Burkina Faso", "Ouagadougou"),
+					textQuestion(200, "Capital of Germany", "Berlin"),
+					textQuestion(300, "Capital of France", "Paris"),
+				}},
+				{Name: "Rivers", Questions: []siq.Question{
+					textQuestion(100, "River of Paris", "Seine"),
+					textQuestion(200, "Longest river of Africa", "Nile"),
+				}},
+			},
+		}},
+	}
+}
+
+// scoreProvider scores the questions of a prompt from a table of IDs
+type scoreProvider struct {
+	*llm.Scripted
+	difficulty map[string]int
+	prompts    []string
+}
+
+var idPattern = regexp.MustCompile(`(?m)^\[(r\d+/t\d+/q\d+)\]`)
+
+func (p *scoreProvider) Generate(ctx context.Context, req llm.GenerateRequest) (*llm.Response, error) {
+	p.prompts = append(p.prompts, req.Prompt)
+	type score struct {
+		ID         string `json:"id"`
+		Difficulty int    `json:"difficulty"`
+		Reason     string `json:"reason"`
+	}
+	var scores []score
+	for _, match := range idPattern.FindAllStringSubmatch(req.Prompt, -1) {
+		if difficulty, ok := p.difficulty[match[1]]; ok {
+			scores = append(scores, score{match[1], difficulty, "because"})
+		}
+	}
+	data, _ := json.Marshal(map[string]any{"scores": scores})
+	return &llm.Response{Text: string(data), PromptTokens: 10, CompletionTokens: 5}, nil
+}
+
+func TestEstimate(t *testing.T) {
+	provider := &scoreProvider{Scripted: llm.NewScripted(), difficulty: map[string]int{
+		"r1/t1/q1": 9, "r1/t1/q2": 3, "r1/t1/q3": 2,
+		"r1/t2/q1": 4, "r1/t2/q2": 11,
+	}}
+	options := DefaultOptions()
+	options.ChunkTokens = 60
+
+	report, err := Estimate(context.Background(), provider, testPackage(), options)
+	if err != nil {
+		t.Fatalf("Failed to estimate: %v", err)
+	}
+	if report.Requests < 2 || report.Requests != len(provider.prompts) {
+		t.Errorf("Expected questions split into several requests, got %d", report.Requests)
+	}
+	for _, prompt := range provider.prompts {
+		if strings.Contains(prompt, "100") || strings.Contains(prompt, "300") {
+			t.Errorf("Expected questions without prices, got %q", prompt)
+		}
+	}
+
+	if len(report.Themes) != 2 || report.Themes[0].ThemeName != "Capitals" || len(report.Themes[0].Scores) != 3 {
+		t.Fatalf("Expected 2 themes, got %+v", report.Themes)
+	}
+	capitals := report.Themes[0]
+	if capitals.Scores[0].Difficulty != 9 || capitals.Scores[0].Reason != "because" || capitals.Scores[0].Text != "Capital of Burkina Faso" {
+		t.Errorf("Unexpected score %+v", capitals.Scores[0])
+	}
+	// 100 is harder than 200 and 300, 200 is harder than 300
+	if len(capitals.Inversions) != 3 || report.Inversions() != 3 {
+		t.Fatalf("Expected 3 inversions, got %+v", capitals.Inversions)
+	}
+	first := capitals.Inversions[0]
+	if first.Cheaper.Ref.Price.Value != 100 || first.Expensive.Ref.Price.Value != 300 || first.Gap() != 7 {
+		t.Errorf("Expected largest gap first, got %+v", first)
+	}
+	if strings.Join(report.Unscored, ",") != "r1/t2/q2" || len(report.Themes[1].Inversions) != 0 {
+		t.Errorf("Expected out of range score to be unscored, got %v", report.Unscored)
+	}
+
+	options.MinGap = 2
+	options.Scope = packtext.Scope{Round: 1, Theme: 1}
+	report, err = Estimate(context.Background(), provider, testPackage(), options)
+	if err != nil {
+		t.Fatalf("Failed to estimate: %v", err)
+	}
+	if len(report.Themes) != 1 || report.Inversions() != 2 {
+		t.Errorf("Expected 2 inversions of at least 2 in the scope, got %+v", report.Themes)
+	}
+
+	if _, err := Estimate(context.Background(), llm.NewScripted("not json"), testPackage(), DefaultOptions()); err == nil {
+		t.Errorf("Expected error for an invalid response")
+	}
+}
+
+func TestReorder(t *testing.T) {
+	pkg := testPackage()
+	provider := &scoreProvider{Scripted: llm.NewScripted(), difficulty: map[string]int{
+		"r1/t1/q1": 9, "r1/t1/q2": 3, "r1/t1/q3": 3,
+		"r1/t2/q1": 4,
+	}}
+	report, err := Estimate(context.Background(), provider, pkg, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Failed to estimate: %v", err)
+	}
+
+	if moved := Reorder(pkg, report); moved != 3 {
+		t.Errorf("Expected 3 moved questions, got %d", moved)
+	}
+	capitals := pkg.Rounds[0].Themes[0].Questions
+	var order []string
+	for _, question := range capitals {
+		order = append(order, question.Right[0])
+	}
+	// Equally difficult Berlin and Paris keep their order
+	if strings.Join(order, ",") != "Berlin,Paris,Ouagadougou" {
+		t.Errorf("Expected questions sorted by difficulty, got %v", order)
+	}
+	if capitals[0].BasePrice != 100 || capitals[1].BasePrice != 200 || capitals[2].BasePrice != 300 {
+		t.Errorf("Expected prices to stay in place, got %d %d %d", capitals[0].BasePrice, capitals[1].BasePrice, capitals[2].BasePrice)
+	}
+	if rivers := pkg.Rounds[0].Themes[1].Questions; rivers[0].Right[0] != "Seine" {
+		t.Errorf("Expected theme with unscored questions to be kept")
+	}
+}
+
+func TestStakePrices(t *testing.T) {
+	stake := textQuestion(0, "Capital of Canada", "Ottawa")
+	stake.Params = append(stake.Params, siq.Param{
+		Name:      siq.ParamNamePrice,
+		Type:      siq.ParamTypeNumberSet,
+		NumberSet: &siq.NumberSet{Minimum: 100, Maximum: 500, Step: 100},
+	})
+	pkg := testPackage()
+	capitals := &pkg.Rounds[0].Themes[0]
+	capitals.Questions = append(capitals.Questions, stake)
+	provider := &scoreProvider{Scripted: llm.NewScripted(), difficulty: map[string]int{
+		"r1/t1/q1": 9, "r1/t1/q2": 3, "r1/t1/q3": 3, "r1/t1/q4": 10,
+	}}
+	report, err := Estimate(context.Background(), provider, pkg, DefaultOptions())
+	if err != nil {
+		t.Fatalf("Failed to estimate: %v", err)
+	}
+
+	// The stake question has no comparable price
+	for _, inversion := range report.Themes[0].Inversions {
+		if inversion.Cheaper.Ref.Price.IsSet() || inversion.Expensive.Ref.Price.IsSet() {
+			t.Errorf("Expected no inversions with a number set price, got %+v", inversion)
+		}
+	}
+	if report.Inversions() != 2 {
+		t.Errorf("Expected 2 inversions between fixed prices, got %d", report.Inversions())
+	}
+	if moved := Reorder(pkg, report); moved != 0 || capitals.Questions[0].Right[0] != "Ouagadougou" {
+		t.Errorf("Expected theme with a stake question to be kept, %d moved", moved)
+	}
+}
+
+func TestCalibrateFile(t *testing.T) {
+	dir := t.TempDir()
+	input := siqtest.CreateFile(t, dir, "pack.siq", `<?xml version="1.0" encoding="utf-8"?>
+<package id="p" name="Pack" version="5">
+	<round name="Round 1"><theme name="Cats">
+		<question price="100">
+			<params><param name="question" type="content"><item type="image" isRef="True">cat.png</item></param></params>
+			<right><answer>Siamese</answer></right>
+		</question>
+		<question price="200">
+			<params><param name="question" type="content"><item>Domestic feline</item></param></params>
+			<right><answer>Cat</answer></right>
+		</question>
+	</theme></round>
+</package>`, map[string]string{"Images/cat.png": "png"})
+	output := filepath.Join(dir, "calibrated.siq")
+
+	provider := &scoreProvider{Scripted: llm.NewScripted(), difficulty: map[string]int{"r1/t1/q1": 6, "r1/t1/q2": 1}}
+	options := DefaultOptions()
+	options.Reorder = true
+	report, err := CalibrateFile(context.Background(), provider, input, output, options)
+	if err != nil {
+		t.Fatalf("Failed to calibrate: %v", err)
+	}
+	if report.Inversions() != 1 || report.Moved != 2 {
+		t.Errorf("Expected 1 inversion and 2 moved questions, got %+v", report)
+	}
+	if !strings.Contains(provider.prompts[0], "[image: cat.png]") {
+		t.Errorf("Expected media to be named in the prompt, got %q", provider.prompts[0])
+	}
+
+	reader, err := siq.NewSIQReader(output)
+	if err != nil {
+		t.Fatalf("Failed to open output: %v", err)
+	}
+	defer reader.Close()
+	pkg, err := reader.Read()
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	questions := pkg.Rounds[0].Themes[0].Questions
+	if questions[0].Right[0] != "Cat" || questions[0].BasePrice != 100 || questions[1].Right[0] != "Siamese" {
+		t.Errorf("Expected reordered questions, got %v at %d", questions[0].Right, questions[0].BasePrice)
+	}
+	if _, err := reader.GetFile("Images/cat.png"); err != nil {
+		t.Errorf("Expected media to be copied: %v", err)
+	}
+
+	// The input is reordered in place with its media
+	if _, err := CalibrateFile(context.Background(), provider, input, input, options); err != nil {
+		t.Fatalf("Failed to calibrate in place: %v", err)
+	}
+	inPlace, err := siq.NewSIQReader(input)
+	if err != nil {
+		t.Fatalf("Failed to open package calibrated in place: %v", err)
+	}
+	defer inPlace.Close()
+	if pkg, err = inPlace.Read(); err != nil || pkg.Rounds[0].Themes[0].Questions[0].Right[0] != "Cat" {
+		t.Errorf("Expected the input to be reordered, got %v", err)
+	}
+	if _, err := inPlace.GetFile("Images/cat.png"); err != nil {
+		t.Errorf("Expected media to be kept in place: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("Expected no temporary files left, got %d entries", len(entries))
+	}
+}
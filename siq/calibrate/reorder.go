@@ -0,0 +1,98 @@
+package calibrate
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/minmaxmean/sigma/llm"
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// Reorder sorts the questions of themes with inversions by difficulty. The
+// prices of the theme stay in place in ascending order, so the easiest
+// question gets the lowest price. Themes with unscored questions, only
+// partly within the scope of the report, from version 4 rounds or with
+// prices given by a price parameter, such as stakes selected from a number
+// set, are left as they are. It returns the number of moved questions.
+func Reorder(pkg *siq.Package, report *Report) int {
+	moved := 0
+	for _, reported := range report.Themes {
+		if len(reported.Inversions) == 0 || reported.RoundIndex >= len(pkg.Rounds) {
+			continue
+		}
+		theme := &pkg.Rounds[reported.RoundIndex].Themes[reported.ThemeIndex]
+		if len(reported.Scores) != len(theme.Questions) || slices.ContainsFunc(reported.Scores, func(score Score) bool {
+			return score.Difficulty == 0
+		}) || slices.ContainsFunc(theme.Questions, func(question siq.Question) bool {
+			// Only base prices are reassigned, they are the prices compared by inversions
+			return question.Param(siq.ParamNamePrice) != nil
+		}) {
+			continue
+		}
+
+		prices := make([]int, len(theme.Questions))
+		order := make([]int, len(theme.Questions))
+		for i, score := range reported.Scores {
+			prices[i] = score.Ref.Price.Value
+			order[i] = i
+		}
+		slices.Sort(prices)
+		// Equally difficult questions keep their price order
+		slices.SortStableFunc(order, func(a, b int) int {
+			return cmp.Or(
+				cmp.Compare(reported.Scores[a].Difficulty, reported.Scores[b].Difficulty),
+				cmp.Compare(reported.Scores[a].Ref.Price.Value, reported.Scores[b].Ref.Price.Value),
+			)
+		})
+
+		questions := make([]siq.Question, len(order))
+		for slot, index := range order {
+			questions[slot] = theme.Questions[index]
+			questions[slot].BasePrice = prices[slot]
+			if slot != index {
+				moved++
+			}
+		}
+		theme.Questions = questions
+	}
+	return moved
+}
+
+// CalibrateFile scores the package at input. When output is set, the
+// package is written there with its media, reordered with Reorder when
+// options.Reorder is set. Output may be the input file.
+func CalibrateFile(ctx context.Context, provider llm.Provider, input, output string, options Options) (*Report, error) {
+	reader, err := siq.NewSIQReader(input)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	pkg, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	report, err := Estimate(ctx, provider, pkg, options)
+	if err != nil {
+		return nil, err
+	}
+	if output == "" {
+		return report, nil
+	}
+
+	if options.Reorder {
+		report.Moved = Reorder(pkg, report)
+	}
+	// The output may be the input, it is replaced once the package is complete
+	err = siq.ReplaceFile(output, func(writer *siq.SIQWriter) error {
+		if err := writer.WritePackage(pkg); err != nil {
+			return err
+		}
+		return writer.CopyFiles(reader)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,51 @@
+package siq
+
+import (
+	"slices"
+)
+
+// Clone returns a deep copy of the question, so the copy can be modified
+// without affecting the package it was taken from
+func (q *Question) Clone() Question {
+	clone := *q
+	clone.Params = cloneParams(q.Params)
+	clone.Right = slices.Clone(q.Right)
+	clone.Wrong = slices.Clone(q.Wrong)
+	if q.Script != nil {
+		script := *q.Script
+		clone.Script = &script
+	}
+	clone.Info = q.Info.Clone()
+	return clone
+}
+
+// Clone returns a deep copy of the info, nil for a nil info
+func (i *Info) Clone() *Info {
+	if i == nil {
+		return nil
+	}
+	return &Info{
+		Authors:         slices.Clone(i.Authors),
+		Sources:         slices.Clone(i.Sources),
+		Comments:        slices.Clone(i.Comments),
+		ShowmanComments: slices.Clone(i.ShowmanComments),
+	}
+}
+
+// cloneParams returns a deep copy of params and their nested params
+func cloneParams(params []Param) []Param {
+	if params == nil {
+		return nil
+	}
+	clone := make([]Param, len(params))
+	for i, param := range params {
+		clone[i] = param
+		clone[i].Items = slices.Clone(param.Items)
+		clone[i].Params = cloneParams(param.Params)
+		if param.NumberSet != nil {
+			set := *param.NumberSet
+			clone[i].NumberSet = &set
+		}
+	}
+	return clone
+}
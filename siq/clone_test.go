@@ -0,0 +1,18 @@
+package siq
+
+import (
+	"testing"
+)
+
+func TestQuestionClone(t *testing.T) {
+	question := decodeTestQuestion(t, secretQuestionXML)
+	clone := question.Clone()
+
+	clone.Param(ParamNameAnswer).Items[0].Value = "dog.png"
+	clone.Param("extra/first").Value = "changed"
+	clone.Right[0] = "Dog"
+
+	if question.AnswerContent()[0].Value != "cat.png" || question.Param("extra/first").Value != "one" || question.Right[0] != "Cat" {
+		t.Errorf("Expected the original question to be unchanged, got %+v", question)
+	}
+}
@@ -0,0 +1,255 @@
+// Package contactsheet renders the images used by the questions of a SIQ
+// package as a grid of labeled thumbnails
+package contactsheet
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"strings"
+	"sync"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	_ "golang.org/x/image/webp"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// Defaults of Options
+const (
+	DefaultThumbSize = 160
+	DefaultColumns   = 6
+)
+
+// Sheet layout in pixels
+const (
+	padding   = 8
+	labelGap  = 4
+	labelSize = 11
+	titleSize = 16
+)
+
+// placeholderLabel is shown instead of images that cannot be decoded
+const placeholderLabel = "unreadable"
+
+var (
+	backgroundColor = color.White
+	thumbColor      = color.RGBA{R: 0xEE, G: 0xEE, B: 0xEE, A: 0xFF}
+	textColor       = color.RGBA{R: 0x22, G: 0x22, B: 0x22, A: 0xFF}
+	answerColor     = color.RGBA{R: 0x1B, G: 0x5E, B: 0x20, A: 0xFF}
+	errorColor      = color.RGBA{R: 0xB7, G: 0x1C, B: 0x1C, A: 0xFF}
+)
+
+// Options configures the sheet layout
+type Options struct {
+	// ThumbSize is the width and height of the thumbnail area in pixels
+	ThumbSize int
+	// Columns is the maximal number of thumbnails per row
+	Columns int
+}
+
+// DefaultOptions returns the layout used by the contact-sheet command by default
+func DefaultOptions() Options {
+	return Options{ThumbSize: DefaultThumbSize, Columns: DefaultColumns}
+}
+
+// Thumbnail is a scaled down image used by a question
+type Thumbnail struct {
+	Ref  siq.QuestionRef
+	Path string
+	// Answer reports whether the image belongs to the answer
+	Answer bool
+	// Image is nil when the file could not be read or decoded
+	Image image.Image
+	Err   error
+}
+
+// Load reads and scales down every image referenced by the questions of
+// the package, in question order. An image used several times by the same
+// question is loaded once for it.
+func Load(reader *siq.SIQReader, pkg *siq.Package, size int) []Thumbnail {
+	var thumbs []Thumbnail
+	pkg.Walk(func(ref siq.QuestionRef, question *siq.Question) error {
+		seen := make(map[string]bool)
+		for param, item := range question.ParamItems() {
+			filePath := siq.ItemPath(*item)
+			if item.GetType() != siq.ContentTypeImage || filePath == "" || seen[filePath] {
+				continue
+			}
+			seen[filePath] = true
+			top, _, _ := strings.Cut(param, "/")
+			thumb := Thumbnail{Ref: ref, Path: filePath, Answer: top == siq.ParamNameAnswer}
+			thumb.Image, thumb.Err = loadImage(reader, filePath, size)
+			thumbs = append(thumbs, thumb)
+		}
+		return nil
+	})
+	return thumbs
+}
+
+// loadImage decodes an archive image and scales it to fit into a square of size
+func loadImage(reader *siq.SIQReader, filePath string, size int) (image.Image, error) {
+	file, err := reader.GetFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	src, _, err := image.Decode(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", filePath, err)
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(1, height*size/width)
+		} else {
+			width, height = max(1, width*size/height), size
+		}
+	}
+	thumb := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.ApproxBiLinear.Scale(thumb, thumb.Bounds(), src, bounds, draw.Over, nil)
+	return thumb, nil
+}
+
+// fonts holds the faces used for labels and titles
+type fonts struct {
+	label font.Face
+	title font.Face
+}
+
+// loadFonts parses the embedded Go fonts, which cover Latin and Cyrillic
+var loadFonts = sync.OnceValues(func() (fonts, error) {
+	label, err := newFace(goregular.TTF, labelSize)
+	if err != nil {
+		return fonts{}, err
+	}
+	title, err := newFace(gobold.TTF, titleSize)
+	if err != nil {
+		return fonts{}, err
+	}
+	return fonts{label: label, title: title}, nil
+})
+
+func newFace(data []byte, size float64) (font.Face, error) {
+	parsed, err := opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse font: %w", err)
+	}
+	return opentype.NewFace(parsed, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// Render draws a sheet with a title and a grid of labeled thumbnails. Each
+// label shows the round, the theme and the price of the question. Render
+// shares font faces between calls and must not be called concurrently.
+func Render(title string, thumbs []Thumbnail, options Options) (*image.RGBA, error) {
+	if options.ThumbSize < 16 || options.Columns < 1 {
+		return nil, fmt.Errorf("invalid layout: thumbnail size %d, %d column(s)", options.ThumbSize, options.Columns)
+	}
+	faces, err := loadFonts()
+	if err != nil {
+		return nil, err
+	}
+
+	lineHeight := faces.label.Metrics().Height.Ceil()
+	titleHeight := faces.title.Metrics().Height.Ceil()
+	cellWidth := options.ThumbSize
+	cellHeight := options.ThumbSize + labelGap + 2*lineHeight
+	columns := max(1, min(options.Columns, len(thumbs)))
+	rows := (len(thumbs) + columns - 1) / columns
+	top := padding + titleHeight + padding
+
+	sheet := image.NewRGBA(image.Rect(0, 0,
+		padding+columns*(cellWidth+padding),
+		top+rows*(cellHeight+padding)))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(backgroundColor), image.Point{}, draw.Src)
+	drawText(sheet, faces.title, textColor, title, padding, padding, sheet.Bounds().Dx()-2*padding)
+
+	for i, thumb := range thumbs {
+		x := padding + (i%columns)*(cellWidth+padding)
+		y := top + (i/columns)*(cellHeight+padding)
+
+		area := image.Rect(x, y, x+cellWidth, y+options.ThumbSize)
+		draw.Draw(sheet, area, image.NewUniform(thumbColor), image.Point{}, draw.Src)
+		if thumb.Image != nil {
+			bounds := thumb.Image.Bounds()
+			offset := image.Pt(x+(cellWidth-bounds.Dx())/2, y+(options.ThumbSize-bounds.Dy())/2)
+			draw.Draw(sheet, bounds.Sub(bounds.Min).Add(offset), thumb.Image, bounds.Min, draw.Over)
+		} else {
+			width := font.MeasureString(faces.label, placeholderLabel).Ceil()
+			drawText(sheet, faces.label, errorColor, placeholderLabel,
+				x+max(0, (cellWidth-width)/2), y+(options.ThumbSize-lineHeight)/2, cellWidth)
+		}
+
+		labelColor := textColor
+		price := thumb.Ref.Price.String()
+		if thumb.Answer {
+			labelColor = answerColor
+			price += " (answer)"
+		}
+		labelTop := y + options.ThumbSize + labelGap
+		drawText(sheet, faces.label, labelColor, thumb.Ref.RoundName, x, labelTop, cellWidth)
+		// Only the theme is shortened so the price always stays visible
+		price = " / " + price
+		theme := truncate(faces.label, thumb.Ref.ThemeName, cellWidth-font.MeasureString(faces.label, price).Ceil())
+		drawText(sheet, faces.label, labelColor, theme+price, x, labelTop+lineHeight, cellWidth)
+	}
+
+	return sheet, nil
+}
+
+// drawText draws a line of text with its top left corner at x, y,
+// shortening it with an ellipsis to fit into width
+func drawText(dst draw.Image, face font.Face, c color.Color, text string, x, y, width int) {
+	text = truncate(face, text, width)
+	drawer := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y+face.Metrics().Ascent.Ceil()),
+	}
+	drawer.DrawString(text)
+}
+
+// truncate shortens text with an ellipsis until it fits into width
+func truncate(face font.Face, text string, width int) string {
+	limit := fixed.I(width)
+	if font.MeasureString(face, text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if shortened := string(runes) + "…"; font.MeasureString(face, shortened) <= limit {
+			return shortened
+		}
+	}
+	return ""
+}
+
+// ByRound groups thumbnails by round, keeping the round order
+func ByRound(thumbs []Thumbnail) [][]Thumbnail {
+	var groups [][]Thumbnail
+	for i, thumb := range thumbs {
+		if i == 0 || thumb.Ref.RoundIndex != thumbs[i-1].Ref.RoundIndex {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], thumb)
+	}
+	return groups
+}
@@ -0,0 +1,150 @@
+package contactsheet
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/minmaxmean/sigma/internal/siqtest"
+	"github.com/minmaxmean/sigma/siq"
+)
+
+const sheetPackageXML = `<?xml version="1.0" encoding="utf-8"?>
+<package id="sheet" name="Sheet" version="5">
+	<round name="Раунд 1">
+		<theme name="Animals">
+			<question price="100">
+				<params>
+					<param name="question" type="content">
+						<item type="image" isRef="True">red.png</item>
+						<item type="audio" isRef="True">sound.mp3</item>
+						<item type="image" isRef="True">red.png</item>
+					</param>
+					<param name="answer" type="content">
+						<item type="image" isRef="True">wide.png</item>
+					</param>
+				</params>
+				<right><answer>Cat</answer></right>
+			</question>
+		</theme>
+	</round>
+	<round name="Round 2">
+		<theme name="Broken">
+			<question price="200">
+				<params>
+					<param name="question" type="content">
+						<item type="image" isRef="True">broken.png</item>
+					</param>
+				</params>
+				<right><answer>Dog</answer></right>
+			</question>
+		</theme>
+	</round>
+</package>`
+
+var red = color.RGBA{R: 0xFF, A: 0xFF}
+
+func encodePNG(t *testing.T, width, height int, c color.Color) string {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal("Failed to encode PNG:", err)
+	}
+	return buf.String()
+}
+
+func loadTestThumbnails(t *testing.T, size int) []Thumbnail {
+	path := siqtest.CreateFile(t, t.TempDir(), "sheet.siq", sheetPackageXML, map[string]string{
+		"Images/red.png":    encodePNG(t, 20, 20, red),
+		"Images/wide.png":   encodePNG(t, 400, 100, color.Black),
+		"Images/broken.png": "not an image",
+		"Audio/sound.mp3":   "sound",
+	})
+	reader, err := siq.NewSIQReader(path)
+	if err != nil {
+		t.Fatal("Failed to open SIQ file:", err)
+	}
+	defer reader.Close()
+	pkg, err := reader.Read()
+	if err != nil {
+		t.Fatal("Failed to read SIQ file:", err)
+	}
+	return Load(reader, pkg, size)
+}
+
+func TestLoad(t *testing.T) {
+	thumbs := loadTestThumbnails(t, 100)
+
+	if len(thumbs) != 3 {
+		t.Fatalf("Expected 3 thumbnails, got %d", len(thumbs))
+	}
+	if thumbs[0].Path != "Images/red.png" || thumbs[0].Answer || thumbs[0].Image.Bounds().Dx() != 20 {
+		t.Errorf("Expected small question image to keep its size, got %+v", thumbs[0])
+	}
+	if !thumbs[1].Answer || thumbs[1].Image.Bounds() != image.Rect(0, 0, 100, 25) {
+		t.Errorf("Expected answer image scaled to 100x25, got %+v", thumbs[1])
+	}
+	if thumbs[2].Image != nil || thumbs[2].Err == nil || thumbs[2].Ref.RoundName != "Round 2" {
+		t.Errorf("Expected broken image to have an error, got %+v", thumbs[2])
+	}
+
+	groups := ByRound(thumbs)
+	if len(groups) != 2 || len(groups[0]) != 2 || len(groups[1]) != 1 {
+		t.Errorf("Expected thumbnails grouped into 2 and 1, got %d groups", len(groups))
+	}
+}
+
+func TestRender(t *testing.T) {
+	options := Options{ThumbSize: 100, Columns: 2}
+	thumbs := loadTestThumbnails(t, options.ThumbSize)
+
+	sheet, err := Render("Sheet", thumbs, options)
+	if err != nil {
+		t.Fatal("Failed to render sheet:", err)
+	}
+
+	faces, _ := loadFonts()
+	lineHeight := faces.label.Metrics().Height.Ceil()
+	top := padding + faces.title.Metrics().Height.Ceil() + padding
+	cellHeight := options.ThumbSize + labelGap + 2*lineHeight
+
+	if width := padding + 2*(options.ThumbSize+padding); sheet.Bounds().Dx() != width {
+		t.Errorf("Expected sheet width %d, got %d", width, sheet.Bounds().Dx())
+	}
+	if height := top + 2*(cellHeight+padding); sheet.Bounds().Dy() != height {
+		t.Errorf("Expected sheet height %d, got %d", height, sheet.Bounds().Dy())
+	}
+
+	// The small red image is centered in the first cell
+	center := sheet.At(padding+options.ThumbSize/2, top+options.ThumbSize/2)
+	if center != red {
+		t.Errorf("Expected red pixel in the first thumbnail, got %v", center)
+	}
+	if corner := sheet.At(padding, top); corner != thumbColor {
+		t.Errorf("Expected thumbnail background around small image, got %v", corner)
+	}
+
+	if _, err := Render("Sheet", thumbs, Options{ThumbSize: 0, Columns: 2}); err == nil {
+		t.Error("Expected error for invalid layout")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	faces, err := loadFonts()
+	if err != nil {
+		t.Fatal("Failed to load fonts:", err)
+	}
+	if text := truncate(faces.label, "Short", 100); text != "Short" {
+		t.Errorf("Expected short text to stay unchanged, got %s", text)
+	}
+	if text := truncate(faces.label, "A very long theme name that does not fit", 60); text == "" || []rune(text)[len([]rune(text))-1] != '…' {
+		t.Errorf("Expected long text to end with an ellipsis, got %q", text)
+	}
+}
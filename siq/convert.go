@@ -0,0 +1,137 @@
+package siq
+
+import (
+	"strings"
+)
+
+// ConvertV4 converts version 4 rounds of the package to version 5 rounds.
+// Converted rounds are appended to Rounds and RoundsV4 is cleared.
+func (p *Package) ConvertV4() {
+	if len(p.RoundsV4) == 0 {
+		return
+	}
+	for _, roundV4 := range p.RoundsV4 {
+		p.Rounds = append(p.Rounds, ConvertV4RoundToV5(roundV4))
+	}
+	p.RoundsV4 = nil
+}
+
+// ConvertV4RoundToV5 converts a v4 round with its themes and questions to v5 format
+func ConvertV4RoundToV5(roundV4 RoundV4) Round {
+	themes := make([]Theme, len(roundV4.Themes))
+	for i, themeV4 := range roundV4.Themes {
+		themes[i] = ConvertV4ThemeToV5(themeV4)
+	}
+	return Round{
+		Name:   roundV4.Name,
+		Type:   roundV4.Type,
+		Info:   roundV4.Info,
+		Themes: themes,
+	}
+}
+
+// ConvertV4ThemeToV5 converts a v4 theme with its questions to v5 format
+func ConvertV4ThemeToV5(themeV4 ThemeV4) Theme {
+	questions := make([]Question, len(themeV4.Questions))
+	for i, questionV4 := range themeV4.Questions {
+		questions[i] = convertV4ToV5Question(questionV4)
+	}
+	return Theme{
+		Name:      themeV4.Name,
+		Info:      themeV4.Info,
+		Questions: questions,
+	}
+}
+
+// ConvertV4ToV5Question converts a v4 question to v5 format (exported version)
+func ConvertV4ToV5Question(qv4 QuestionV4) Question {
+	return convertV4ToV5Question(qv4)
+}
+
+// convertV4ToV5Question converts a v4 question to v5 format.
+// Scenario atoms before a marker become the question content and atoms after
+// it become the answer content. Atoms referencing package files with "@"
+// become isRef items.
+func convertV4ToV5Question(qv4 QuestionV4) Question {
+	question := Question{
+		Type:      "simple", // Default type for v4 questions
+		BasePrice: qv4.Price,
+		Right:     qv4.Right,
+		Wrong:     qv4.Wrong,
+		Info:      qv4.Info,
+	}
+
+	// Keep the v4 question type and its parameters as simple params
+	if qv4.Type != nil && qv4.Type.Name != "" {
+		question.Type = qv4.Type.Name
+		for _, paramV4 := range qv4.Type.Params {
+			question.Params = append(question.Params, Param{
+				Name:  paramV4.Name,
+				Type:  ParamTypeSimple,
+				Value: paramV4.Value,
+			})
+		}
+	}
+
+	// Convert scenario atoms to content items
+	if qv4.Scenario != nil {
+		var items, answerItems []ContentItem
+		inAnswer := false
+		for _, atom := range qv4.Scenario.Atoms {
+			if atom.Type == ContentTypeMarker {
+				inAnswer = true
+				continue
+			}
+
+			item := convertAtom(atom)
+			if inAnswer {
+				answerItems = append(answerItems, item)
+			} else {
+				items = append(items, item)
+			}
+		}
+
+		// Create a question parameter with the content items
+		question.Params = append(question.Params, Param{
+			Name:  ParamNameQuestion,
+			Type:  ParamTypeContent,
+			Items: items,
+		})
+		if len(answerItems) > 0 {
+			question.Params = append(question.Params, Param{
+				Name:  ParamNameAnswer,
+				Type:  ParamTypeContent,
+				Items: answerItems,
+			})
+		}
+	}
+
+	return question
+}
+
+// convertAtom converts a v4 scenario atom to a normalized v5 content item
+func convertAtom(atom Atom) ContentItem {
+	item := ContentItem{
+		Type:     atom.Type,
+		Value:    atom.Content,
+		Duration: atom.Duration,
+	}
+
+	switch atom.Type {
+	case "":
+		item.Type = ContentTypeText
+	case AtomTypeSay:
+		item.Type = ContentTypeText
+		item.Placement = PlacementReplic
+	case ContentTypeVoice:
+		item.Type = ContentTypeAudio
+	}
+
+	if item.Type != ContentTypeText && strings.HasPrefix(item.Value, "@") {
+		item.IsRef = true
+		item.Value = strings.TrimPrefix(item.Value, "@")
+	}
+
+	item.normalize()
+	return item
+}
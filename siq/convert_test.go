@@ -0,0 +1,135 @@
+package siq
+
+import (
+	"os"
+	"testing"
+)
+
+func TestConvertV4RoundToV5(t *testing.T) {
+	info := &Info{Authors: []string{"Author"}, Comments: []string{"Comment"}}
+	roundV4 := RoundV4{
+		Name: "Final",
+		Type: "final",
+		Info: info,
+		Themes: []ThemeV4{
+			{
+				Name: "Theme",
+				Info: &Info{Sources: []string{"Source"}},
+				Questions: []QuestionV4{
+					{
+						Price: 300,
+						Type: &QuestionTypeV4{
+							Name:   "cat",
+							Params: []ParamV4{{Name: "theme", Value: "Hidden theme"}},
+						},
+						Scenario: &Scenario{
+							Atoms: []Atom{
+								{Content: "Listen"},
+								{Type: ContentTypeVoice, Content: "@song.mp3", Duration: 10},
+								{Type: AtomTypeSay, Content: "Read aloud"},
+								{Type: ContentTypeImage, Content: "http://example.com/pic.png"},
+								{Type: ContentTypeMarker},
+								{Type: ContentTypeImage, Content: "@answer.png"},
+							},
+						},
+						Right: []string{"Right"},
+						Wrong: []string{"Wrong"},
+						Info:  &Info{Comments: []string{"Question comment"}},
+					},
+				},
+			},
+		},
+	}
+
+	round := ConvertV4RoundToV5(roundV4)
+	if round.Name != "Final" || round.Type != "final" || round.Info != info {
+		t.Errorf("Unexpected round attributes %+v", round)
+	}
+	if len(round.Themes) != 1 || round.Themes[0].Name != "Theme" || round.Themes[0].Info.Sources[0] != "Source" {
+		t.Fatalf("Unexpected themes %+v", round.Themes)
+	}
+
+	question := round.Themes[0].Questions[0]
+	if question.Type != "cat" || question.Theme() != "Hidden theme" {
+		t.Errorf("Expected v4 type and params to be kept, got type '%s' theme '%s'", question.Type, question.Theme())
+	}
+	if question.Price().Value != 300 {
+		t.Errorf("Expected price 300, got %s", question.Price())
+	}
+	if question.Right[0] != "Right" || question.Wrong[0] != "Wrong" || question.Info.Comments[0] != "Question comment" {
+		t.Errorf("Expected answers and info to be kept, got %+v", question)
+	}
+
+	content := question.GetQuestionContent()
+	if len(content) != 4 {
+		t.Fatalf("Expected 4 question items, got %d", len(content))
+	}
+	if content[0].Type != ContentTypeText || content[0].Placement != PlacementScreen || !content[0].GetWaitForFinish() {
+		t.Errorf("Unexpected text item %+v", content[0])
+	}
+	if content[1].Type != ContentTypeAudio || !content[1].IsRef || content[1].Value != "song.mp3" || content[1].Duration != 10 {
+		t.Errorf("Unexpected voice item %+v", content[1])
+	}
+	if content[2].Type != ContentTypeText || content[2].Placement != PlacementReplic {
+		t.Errorf("Unexpected say item %+v", content[2])
+	}
+	if content[3].IsRef || content[3].Value != "http://example.com/pic.png" {
+		t.Errorf("Unexpected external item %+v", content[3])
+	}
+
+	answer := question.AnswerContent()
+	if len(answer) != 1 || !answer[0].IsRef || answer[0].Value != "answer.png" {
+		t.Errorf("Unexpected answer content %+v", answer)
+	}
+}
+
+func TestReadConvertsV4Package(t *testing.T) {
+	contentXML := `<?xml version="1.0" encoding="utf-8"?>
+<package name="V4 Package" version="4" id="v4-package" xmlns="http://vladimirkhil.com/ygpackage3.0.xsd">
+	<rounds>
+		<round name="Round 1">
+			<themes>
+				<theme name="Theme 1">
+					<questions>
+						<question price="100">
+							<scenario>
+								<atom>Question text</atom>
+							</scenario>
+							<right>
+								<answer>Answer</answer>
+							</right>
+						</question>
+					</questions>
+				</theme>
+			</themes>
+		</round>
+	</rounds>
+</package>`
+
+	testFile := createTestSIQFileWithContent(t, contentXML)
+	defer os.Remove(testFile)
+
+	reader, err := NewSIQReader(testFile)
+	if err != nil {
+		t.Fatal("Failed to create SIQ reader:", err)
+	}
+	defer reader.Close()
+
+	pkg, err := reader.Read()
+	if err != nil {
+		t.Fatal("Failed to read SIQ file:", err)
+	}
+
+	if pkg.FormatVersion != 4 {
+		t.Errorf("Expected format version 4, got %d", pkg.FormatVersion)
+	}
+	if len(pkg.RoundsV4) != 0 {
+		t.Errorf("Expected v4 rounds to be converted, got %d left", len(pkg.RoundsV4))
+	}
+	if len(pkg.Rounds) != 1 || pkg.Rounds[0].Themes[0].Name != "Theme 1" {
+		t.Fatalf("Unexpected converted rounds %+v", pkg.Rounds)
+	}
+	if pkg.GetRoundCount() != 1 || pkg.GetThemeCount() != 1 || pkg.GetQuestionCount() != 1 {
+		t.Errorf("Unexpected statistics: %d rounds, %d themes, %d questions", pkg.GetRoundCount(), pkg.GetThemeCount(), pkg.GetQuestionCount())
+	}
+}
@@ -0,0 +1,339 @@
+// Package dedupe finds duplicate and near-duplicate questions and identical
+// media files within and across SIQ packages
+package dedupe
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// Defaults used when Options fields are zero
+const (
+	DefaultThreshold   = 0.8
+	DefaultShingleSize = 4
+	// signatureSize must be divisible by signatureBands
+	signatureSize  = 64
+	signatureBands = 16
+)
+
+// Options configures duplicate detection
+type Options struct {
+	// Threshold is the minimal Jaccard similarity of two questions to be
+	// reported as near-duplicates, between 0 and 1
+	Threshold float64
+	// ShingleSize is the length of character n-grams compared
+	ShingleSize int
+	// Media enables detection of identical media files
+	Media bool
+	// Workers is the number of packages read concurrently
+	Workers int
+}
+
+// Question is a question occurrence in a package
+type Question struct {
+	Path    string
+	Ref     siq.QuestionRef
+	Text    string
+	Answers []string
+}
+
+// Location returns a human-readable location of the question
+func (q Question) Location() string {
+	return fmt.Sprintf("%s: %s [%s]", q.Path, q.Ref, q.Ref.ID())
+}
+
+// Cluster is a group of duplicate questions
+type Cluster struct {
+	// Exact is true when all questions have the same normalized text and answers
+	Exact bool
+	// Similarity is the lowest similarity between linked questions of the cluster
+	Similarity float64
+	Questions  []Question
+}
+
+// MediaFile is a media file occurrence in a package
+type MediaFile struct {
+	Path string
+	Name string
+}
+
+// MediaCluster is a group of media files with identical content
+type MediaCluster struct {
+	Hash  string
+	Size  int64
+	Files []MediaFile
+}
+
+// Wasted returns the number of bytes taken by the redundant copies
+func (c MediaCluster) Wasted() int64 {
+	return c.Size * int64(len(c.Files)-1)
+}
+
+// Report holds the detected duplicates
+type Report struct {
+	Questions []Cluster
+	Media     []MediaCluster
+	Errors    []error
+}
+
+// packResult is what is collected from a single package
+type packResult struct {
+	questions []Question
+	media     []mediaEntry
+	err       error
+}
+
+// mediaEntry is a hashed media file
+type mediaEntry struct {
+	file MediaFile
+	hash string
+	size int64
+}
+
+// Find reads packages concurrently and reports duplicate questions and,
+// when enabled, identical media files. Question clusters are returned in the
+// order of their first occurrence in paths, media clusters are sorted by the
+// space wasted on redundant copies.
+func Find(paths []string, options Options) Report {
+	if options.Threshold <= 0 {
+		options.Threshold = DefaultThreshold
+	}
+	if options.ShingleSize <= 0 {
+		options.ShingleSize = DefaultShingleSize
+	}
+	if options.Workers < 1 {
+		options.Workers = 1
+	}
+
+	results := make([]packResult, len(paths))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(options.Workers, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				results[index] = readPack(paths[index], options.Media)
+			}
+		}()
+	}
+	for index := range paths {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	var report Report
+	var questions []Question
+	var media []mediaEntry
+	for index, result := range results {
+		if result.err != nil {
+			report.Errors = append(report.Errors, fmt.Errorf("%s: %w", paths[index], result.err))
+		}
+		questions = append(questions, result.questions...)
+		media = append(media, result.media...)
+	}
+
+	report.Questions = clusterQuestions(questions, options)
+	report.Media = clusterMedia(media)
+	return report
+}
+
+// readPack streams the questions of a package and hashes its media files
+func readPack(path string, withMedia bool) packResult {
+	reader, err := siq.NewSIQReader(path)
+	if err != nil {
+		return packResult{err: err}
+	}
+	defer reader.Close()
+
+	var result packResult
+	ref := siq.QuestionRef{RoundIndex: -1}
+
+	err = reader.Stream(siq.StreamHandler{
+		Round: func(round *siq.Round) error {
+			ref = siq.QuestionRef{RoundIndex: ref.RoundIndex + 1, RoundName: round.Name, ThemeIndex: -1}
+			return nil
+		},
+		Theme: func(round *siq.Round, theme *siq.Theme) error {
+			ref.ThemeIndex++
+			ref.ThemeName = theme.Name
+			ref.QuestionIndex = -1
+			return nil
+		},
+		Question: func(round *siq.Round, theme *siq.Theme, question *siq.Question) error {
+			ref.QuestionIndex++
+			ref.Price = question.Price()
+			result.questions = append(result.questions, Question{
+				Path:    path,
+				Ref:     ref,
+				Text:    questionText(question),
+				Answers: question.Right,
+			})
+			return nil
+		},
+	})
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	if !withMedia {
+		return result
+	}
+	for _, name := range reader.ListFiles() {
+		if siq.MediaType(name) == "" {
+			continue
+		}
+		hash, size, err := reader.HashFile(name)
+		if err != nil {
+			result.err = err
+			return result
+		}
+		result.media = append(result.media, mediaEntry{file: MediaFile{Path: path, Name: name}, hash: hash, size: size})
+	}
+	return result
+}
+
+// questionText joins the text items of the question content
+func questionText(question *siq.Question) string {
+	var parts []string
+	for _, item := range question.GetQuestionContent() {
+		if item.Type == siq.ContentTypeText {
+			parts = append(parts, item.Value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// textGroup holds the questions sharing the same normalized text and answers
+type textGroup struct {
+	questions []Question
+	shingles  map[string]bool
+}
+
+// clusterQuestions groups questions with identical normalized keys and links
+// groups whose shingle sets are similar enough. Candidate pairs are found
+// with MinHash locality-sensitive hashing so that not every pair of
+// questions has to be compared.
+func clusterQuestions(questions []Question, options Options) []Cluster {
+	var groups []*textGroup
+	byKey := make(map[string]*textGroup)
+	for _, question := range questions {
+		text := normalizeText(question.Text)
+		if text == "" {
+			// Media-only questions are covered by media detection
+			continue
+		}
+		answers := make([]string, len(question.Answers))
+		for i, answer := range question.Answers {
+			answers[i] = normalizeText(answer)
+		}
+		key := text + "\x00" + strings.Join(answers, "\x00")
+
+		group := byKey[key]
+		if group == nil {
+			group = &textGroup{shingles: shingles(text+" "+strings.Join(answers, " "), options.ShingleSize)}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.questions = append(group.questions, question)
+	}
+
+	// Link candidate groups that share at least one LSH band
+	parents := make([]int, len(groups))
+	similarity := make([]float64, len(groups))
+	for i := range parents {
+		parents[i] = i
+		similarity[i] = 1
+	}
+	find := func(i int) int {
+		for parents[i] != i {
+			parents[i] = parents[parents[i]]
+			i = parents[i]
+		}
+		return i
+	}
+
+	buckets := make(map[uint64][]int)
+	checked := make(map[[2]int]bool)
+	for index, group := range groups {
+		for _, key := range bandKeys(minHash(group.shingles, signatureSize), signatureBands) {
+			for _, other := range buckets[key] {
+				pair := [2]int{other, index}
+				if checked[pair] {
+					continue
+				}
+				checked[pair] = true
+
+				value := jaccard(groups[other].shingles, group.shingles)
+				if value < options.Threshold {
+					continue
+				}
+				a, b := find(other), find(index)
+				if a != b {
+					parents[b] = a
+					similarity[a] = min(similarity[a], similarity[b])
+				}
+				similarity[a] = min(similarity[a], value)
+			}
+			buckets[key] = append(buckets[key], index)
+		}
+	}
+
+	// Collect clusters in the order of their first group
+	members := make(map[int][]int)
+	var roots []int
+	for index := range groups {
+		root := find(index)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], index)
+	}
+
+	var clusters []Cluster
+	for _, root := range roots {
+		cluster := Cluster{Exact: len(members[root]) == 1, Similarity: similarity[root]}
+		for _, index := range members[root] {
+			cluster.Questions = append(cluster.Questions, groups[index].questions...)
+		}
+		if len(cluster.Questions) > 1 {
+			clusters = append(clusters, cluster)
+		}
+	}
+	return clusters
+}
+
+// clusterMedia groups media files by their content hash
+func clusterMedia(media []mediaEntry) []MediaCluster {
+	var clusters []*MediaCluster
+	byHash := make(map[string]*MediaCluster)
+	for _, entry := range media {
+		cluster := byHash[entry.hash]
+		if cluster == nil {
+			cluster = &MediaCluster{Hash: entry.hash, Size: entry.size}
+			byHash[entry.hash] = cluster
+			clusters = append(clusters, cluster)
+		}
+		cluster.Files = append(cluster.Files, entry.file)
+	}
+
+	var result []MediaCluster
+	for _, cluster := range clusters {
+		if len(cluster.Files) > 1 {
+			result = append(result, *cluster)
+		}
+	}
+	slices.SortStableFunc(result, func(a, b MediaCluster) int {
+		// Largest wasted space first
+		return cmp.Compare(b.Wasted(), a.Wasted())
+	})
+	return result
+}
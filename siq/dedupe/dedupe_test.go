@@ -0,0 +1,181 @@
+package dedupe
+
+import (
+	"testing"
+
+	"github.com/minmaxmean/sigma/internal/siqtest"
+)
+
+const packOneXML = `<?xml version="1.0" encoding="utf-8"?>
+<package id="one" name="One" version="5">
+	<round name="Round 1">
+		<theme name="Writers">
+			<question price="100">
+				<params>
+					<param name="question" type="content">
+						<item>Who wrote the novel "The Idiot"?</item>
+					</param>
+				</params>
+				<right><answer>Fyodor Dostoevsky</answer></right>
+			</question>
+			<question price="200">
+				<params>
+					<param name="question" type="content">
+						<item>Автор романа «Война и мир»</item>
+					</param>
+				</params>
+				<right><answer>Лев Толстой</answer></right>
+			</question>
+			<question price="300">
+				<params>
+					<param name="question" type="content">
+						<item>The largest planet of the Solar System</item>
+					</param>
+				</params>
+				<right><answer>Jupiter</answer></right>
+			</question>
+		</theme>
+	</round>
+</package>`
+
+const packTwoXML = `<?xml version="1.0" encoding="utf-8"?>
+<package id="two" name="Two" version="5">
+	<round name="Final">
+		<theme name="Books">
+			<question price="500">
+				<params>
+					<param name="question" type="content">
+						<item>WHO wrote the novel  'The Idiot'</item>
+					</param>
+				</params>
+				<right><answer>fyodor dostoevsky</answer></right>
+			</question>
+			<question price="600">
+				<params>
+					<param name="question" type="content">
+						<item>Кто автор романа «Война и мир»?</item>
+					</param>
+				</params>
+				<right><answer>Лев Толстой</answer></right>
+			</question>
+			<question price="700">
+				<params>
+					<param name="question" type="content">
+						<item>The smallest planet of the Solar System</item>
+					</param>
+				</params>
+				<right><answer>Mercury</answer></right>
+			</question>
+		</theme>
+	</round>
+</package>`
+
+func TestNormalizeText(t *testing.T) {
+	expected := map[string]string{
+		`Who wrote "The Idiot"?`:   "who wrote idiot",
+		"Ёлка  и   ПАЛКА!":         "елка палка",
+		"  ":                       "",
+		"Café, crème brûlée & co.": "cafe creme brulee co",
+	}
+	for input, output := range expected {
+		if got := normalizeText(input); got != output {
+			t.Errorf("Expected '%s' for '%s', got '%s'", output, input, got)
+		}
+	}
+}
+
+func TestMinHashEstimatesSimilarity(t *testing.T) {
+	a := shingles(normalizeText("The quick brown fox jumps over the lazy dog"), DefaultShingleSize)
+	b := shingles(normalizeText("The quick brown fox jumped over the lazy dog"), DefaultShingleSize)
+
+	sigA, sigB := minHash(a, signatureSize), minHash(b, signatureSize)
+	equal := 0
+	for i := range sigA {
+		if sigA[i] == sigB[i] {
+			equal++
+		}
+	}
+	estimate := float64(equal) / float64(signatureSize)
+	if diff := estimate - jaccard(a, b); diff > 0.25 || diff < -0.25 {
+		t.Errorf("MinHash estimate %.2f is too far from Jaccard %.2f", estimate, jaccard(a, b))
+	}
+
+	shared := false
+	keysB := bandKeys(sigB, signatureBands)
+	for i, key := range bandKeys(sigA, signatureBands) {
+		if key == keysB[i] {
+			shared = true
+		}
+	}
+	if !shared {
+		t.Error("Expected similar texts to share an LSH band")
+	}
+}
+
+func TestFindQuestionClusters(t *testing.T) {
+	dir := t.TempDir()
+	one := siqtest.CreateFile(t, dir, "one.siq", packOneXML, nil)
+	two := siqtest.CreateFile(t, dir, "two.siq", packTwoXML, nil)
+
+	report := Find([]string{one, two}, Options{Workers: 2})
+	if len(report.Errors) != 0 {
+		t.Fatalf("Unexpected errors: %v", report.Errors)
+	}
+	if len(report.Questions) != 2 {
+		t.Fatalf("Expected 2 clusters, got %d: %+v", len(report.Questions), report.Questions)
+	}
+
+	exact := report.Questions[0]
+	if !exact.Exact || exact.Similarity != 1 || len(exact.Questions) != 2 {
+		t.Errorf("Expected an exact cluster of 2 questions, got %+v", exact)
+	}
+	if exact.Questions[0].Path != one || exact.Questions[1].Ref.ID() != "r1/t1/q1" || exact.Questions[1].Ref.Price.Value != 500 {
+		t.Errorf("Unexpected exact cluster locations %+v", exact.Questions)
+	}
+
+	near := report.Questions[1]
+	if near.Exact || near.Similarity < DefaultThreshold || near.Similarity == 1 {
+		t.Errorf("Expected a near-duplicate cluster, got %+v", near)
+	}
+	if near.Questions[1].Ref.ID() != "r1/t1/q2" || near.Questions[1].Path != two {
+		t.Errorf("Unexpected near cluster locations %+v", near.Questions)
+	}
+
+	// A strict threshold only keeps exact duplicates
+	report = Find([]string{one, two}, Options{Threshold: 1})
+	if len(report.Questions) != 1 || !report.Questions[0].Exact {
+		t.Errorf("Expected only the exact cluster, got %+v", report.Questions)
+	}
+}
+
+func TestFindMediaClusters(t *testing.T) {
+	dir := t.TempDir()
+	one := siqtest.CreateFile(t, dir, "one.siq", packOneXML, map[string]string{
+		"Images/cat.png":  "cat image data",
+		"Images/copy.png": "cat image data",
+		"Audio/song.mp3":  "song data",
+	})
+	two := siqtest.CreateFile(t, dir, "two.siq", packTwoXML, map[string]string{
+		"Audio/track.mp3": "song data",
+		"Images/dog.png":  "dog image data",
+	})
+
+	report := Find([]string{one, two}, Options{Media: true})
+	if len(report.Media) != 2 {
+		t.Fatalf("Expected 2 media clusters, got %d: %+v", len(report.Media), report.Media)
+	}
+
+	// Larger wasted space goes first
+	if report.Media[0].Size != int64(len("cat image data")) || len(report.Media[0].Files) != 2 {
+		t.Errorf("Unexpected first media cluster %+v", report.Media[0])
+	}
+	songs := report.Media[1]
+	if len(songs.Files) != 2 || songs.Files[0].Path != one || songs.Files[1].Name != "Audio/track.mp3" {
+		t.Errorf("Expected the song shared across packages, got %+v", songs)
+	}
+
+	// Media is not hashed unless requested
+	if report := Find([]string{one, two}, Options{}); len(report.Media) != 0 {
+		t.Errorf("Expected no media clusters, got %+v", report.Media)
+	}
+}
@@ -0,0 +1,57 @@
+package dedupe
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// signature is a MinHash signature of a shingle set
+type signature []uint64
+
+// minHash computes a MinHash signature with the given number of hash functions
+func minHash(set map[string]bool, size int) signature {
+	sig := make(signature, size)
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+	for shingle := range set {
+		hasher := fnv.New64a()
+		hasher.Write([]byte(shingle))
+		base := hasher.Sum64()
+		for i := range sig {
+			if h := mix(base ^ uint64(i+1)*0x9e3779b97f4a7c15); h < sig[i] {
+				sig[i] = h
+			}
+		}
+	}
+	return sig
+}
+
+// mix is the splitmix64 finalizer used to derive independent hash functions
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// bandKeys splits the signature into bands and hashes each band.
+// Sets sharing a band key are candidate near-duplicates.
+func bandKeys(sig signature, bands int) []uint64 {
+	rows := len(sig) / bands
+	keys := make([]uint64, bands)
+	for band := range bands {
+		hasher := fnv.New64a()
+		var buf [8]byte
+		for _, value := range sig[band*rows : (band+1)*rows] {
+			for i := range buf {
+				buf[i] = byte(value >> (8 * i))
+			}
+			hasher.Write(buf[:])
+		}
+		keys[band] = hasher.Sum64() ^ uint64(band)
+	}
+	return keys
+}
@@ -0,0 +1,65 @@
+package dedupe
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/minmaxmean/sigma/textnorm"
+)
+
+// stopwords are frequent English and Russian words ignored when comparing questions
+var stopwords = map[string]bool{
+	"a": true, "an": true, "the": true, "of": true, "in": true, "on": true, "at": true,
+	"to": true, "and": true, "or": true, "is": true, "are": true, "was": true, "were": true,
+	"this": true, "that": true, "it": true, "for": true, "by": true, "with": true, "as": true,
+	"и": true, "в": true, "во": true, "на": true, "с": true, "со": true, "по": true, "к": true,
+	"о": true, "об": true, "а": true, "но": true, "или": true, "из": true, "у": true, "за": true,
+	"от": true, "до": true, "не": true, "что": true, "это": true, "как": true, "же": true, "ли": true,
+}
+
+// normalizeText folds case and diacritics, drops punctuation and stopwords
+// and returns the remaining words joined with single spaces
+func normalizeText(text string) string {
+	words := strings.FieldsFunc(textnorm.Fold(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	kept := words[:0]
+	for _, word := range words {
+		if !stopwords[word] {
+			kept = append(kept, word)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// shingles returns the set of character n-grams of the text.
+// Texts shorter than n produce a single shingle.
+func shingles(text string, n int) map[string]bool {
+	runes := []rune(text)
+	set := make(map[string]bool)
+	if len(runes) <= n {
+		if len(runes) > 0 {
+			set[text] = true
+		}
+		return set
+	}
+	for i := 0; i+n <= len(runes); i++ {
+		set[string(runes[i:i+n])] = true
+	}
+	return set
+}
+
+// jaccard returns the Jaccard similarity of two shingle sets
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	intersection := 0
+	for shingle := range a {
+		if b[shingle] {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
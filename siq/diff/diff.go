@@ -0,0 +1,344 @@
+package diff
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/minmaxmean/sigma/siq"
+	"github.com/minmaxmean/sigma/textnorm"
+)
+
+// Kind is the kind of a change
+type Kind string
+
+// Change kinds
+const (
+	Added   Kind = "added"
+	Removed Kind = "removed"
+	Changed Kind = "changed"
+	Renamed Kind = "renamed"
+)
+
+// FieldChange is a changed value of a named field
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// StructureChange is an added, removed or changed round or theme.
+// Theme is empty for round changes.
+type StructureChange struct {
+	Kind      Kind          `json:"kind"`
+	Round     string        `json:"round"`
+	Theme     string        `json:"theme,omitempty"`
+	Questions int           `json:"questions,omitempty"`
+	Fields    []FieldChange `json:"fields,omitempty"`
+}
+
+// QuestionChange is an added, removed or changed question.
+// OldRef is nil for added questions and NewRef is nil for removed ones.
+type QuestionChange struct {
+	Kind   Kind             `json:"kind"`
+	OldRef *siq.QuestionRef `json:"oldRef,omitempty"`
+	NewRef *siq.QuestionRef `json:"newRef,omitempty"`
+	Text   string           `json:"text"`
+	Fields []FieldChange    `json:"fields,omitempty"`
+}
+
+// Ref returns the location of the question in the newest package containing it
+func (c QuestionChange) Ref() siq.QuestionRef {
+	if c.NewRef != nil {
+		return *c.NewRef
+	}
+	return *c.OldRef
+}
+
+// MediaChange is an added, removed, modified or renamed archive file
+type MediaChange struct {
+	Kind    Kind   `json:"kind"`
+	Name    string `json:"name"`
+	OldName string `json:"oldName,omitempty"`
+	OldHash string `json:"oldHash,omitempty"`
+	NewHash string `json:"newHash,omitempty"`
+	OldSize int64  `json:"oldSize,omitempty"`
+	NewSize int64  `json:"newSize,omitempty"`
+}
+
+// Result holds all differences between two packages
+type Result struct {
+	Metadata  []FieldChange     `json:"metadata,omitempty"`
+	Rounds    []StructureChange `json:"rounds,omitempty"`
+	Themes    []StructureChange `json:"themes,omitempty"`
+	Questions []QuestionChange  `json:"questions,omitempty"`
+	Media     []MediaChange     `json:"media,omitempty"`
+}
+
+// IsEmpty reports whether the packages are equivalent
+func (r *Result) IsEmpty() bool {
+	return len(r.Metadata) == 0 && len(r.Rounds) == 0 && len(r.Themes) == 0 &&
+		len(r.Questions) == 0 && len(r.Media) == 0
+}
+
+// Compare reports the differences between two package snapshots.
+// Rounds and themes are matched by name first and by position among the
+// remaining ones, questions are matched by their normalized text first and by
+// position among the remaining ones, so reordering is not reported as change.
+func Compare(old, new *Snapshot) *Result {
+	result := &Result{}
+	result.Metadata = compareFields(infoFields(old.Package), infoFields(new.Package))
+	result.compareRounds(old.Package.Rounds, new.Package.Rounds)
+	result.Media = compareFiles(old.Files, new.Files)
+	return result
+}
+
+func (r *Result) compareRounds(oldRounds, newRounds []siq.Round) {
+	oldNames := make([]string, len(oldRounds))
+	for i, round := range oldRounds {
+		oldNames[i] = round.Name
+	}
+	newNames := make([]string, len(newRounds))
+	for i, round := range newRounds {
+		newNames[i] = round.Name
+	}
+
+	pairs, removed, added := match(oldNames, newNames)
+	for _, index := range removed {
+		r.Rounds = append(r.Rounds, StructureChange{Kind: Removed, Round: oldNames[index], Questions: roundQuestions(oldRounds[index])})
+	}
+	for _, index := range added {
+		r.Rounds = append(r.Rounds, StructureChange{Kind: Added, Round: newNames[index], Questions: roundQuestions(newRounds[index])})
+	}
+	for _, pair := range pairs {
+		oldRound, newRound := &oldRounds[pair[0]], &newRounds[pair[1]]
+		fields := compareFields(
+			[]field{{"name", oldRound.Name}, {"type", oldRound.Type}},
+			[]field{{"name", newRound.Name}, {"type", newRound.Type}},
+		)
+		if len(fields) > 0 {
+			r.Rounds = append(r.Rounds, StructureChange{Kind: Changed, Round: newRound.Name, Fields: fields})
+		}
+		r.compareThemes(pair, oldRound, newRound)
+	}
+}
+
+func (r *Result) compareThemes(roundPair [2]int, oldRound, newRound *siq.Round) {
+	oldNames := make([]string, len(oldRound.Themes))
+	for i, theme := range oldRound.Themes {
+		oldNames[i] = theme.Name
+	}
+	newNames := make([]string, len(newRound.Themes))
+	for i, theme := range newRound.Themes {
+		newNames[i] = theme.Name
+	}
+
+	pairs, removed, added := match(oldNames, newNames)
+	for _, index := range removed {
+		r.Themes = append(r.Themes, StructureChange{Kind: Removed, Round: oldRound.Name, Theme: oldNames[index], Questions: len(oldRound.Themes[index].Questions)})
+	}
+	for _, index := range added {
+		r.Themes = append(r.Themes, StructureChange{Kind: Added, Round: newRound.Name, Theme: newNames[index], Questions: len(newRound.Themes[index].Questions)})
+	}
+	for _, pair := range pairs {
+		oldTheme, newTheme := &oldRound.Themes[pair[0]], &newRound.Themes[pair[1]]
+		if oldTheme.Name != newTheme.Name {
+			r.Themes = append(r.Themes, StructureChange{
+				Kind:   Changed,
+				Round:  newRound.Name,
+				Theme:  newTheme.Name,
+				Fields: []FieldChange{{Field: "name", Old: oldTheme.Name, New: newTheme.Name}},
+			})
+		}
+
+		oldRef := siq.QuestionRef{RoundIndex: roundPair[0], RoundName: oldRound.Name, ThemeIndex: pair[0], ThemeName: oldTheme.Name}
+		newRef := siq.QuestionRef{RoundIndex: roundPair[1], RoundName: newRound.Name, ThemeIndex: pair[1], ThemeName: newTheme.Name}
+		r.compareQuestions(oldRef, newRef, oldTheme.Questions, newTheme.Questions)
+	}
+}
+
+func (r *Result) compareQuestions(oldTheme, newTheme siq.QuestionRef, oldQuestions, newQuestions []siq.Question) {
+	questionRef := func(theme siq.QuestionRef, questions []siq.Question, index int) *siq.QuestionRef {
+		ref := theme
+		ref.QuestionIndex = index
+		ref.Price = questions[index].Price()
+		return &ref
+	}
+	keys := func(questions []siq.Question) []string {
+		keys := make([]string, len(questions))
+		for i := range questions {
+			keys[i] = textnorm.Fold(itemsText(questions[i].GetQuestionContent()))
+		}
+		return keys
+	}
+
+	pairs, removed, added := match(keys(oldQuestions), keys(newQuestions))
+	for _, index := range removed {
+		r.Questions = append(r.Questions, QuestionChange{
+			Kind:   Removed,
+			OldRef: questionRef(oldTheme, oldQuestions, index),
+			Text:   itemsText(oldQuestions[index].GetQuestionContent()),
+		})
+	}
+	for _, index := range added {
+		r.Questions = append(r.Questions, QuestionChange{
+			Kind:   Added,
+			NewRef: questionRef(newTheme, newQuestions, index),
+			Text:   itemsText(newQuestions[index].GetQuestionContent()),
+		})
+	}
+	for _, pair := range pairs {
+		fields := compareFields(questionFields(&oldQuestions[pair[0]]), questionFields(&newQuestions[pair[1]]))
+		if len(fields) == 0 {
+			continue
+		}
+		r.Questions = append(r.Questions, QuestionChange{
+			Kind:   Changed,
+			OldRef: questionRef(oldTheme, oldQuestions, pair[0]),
+			NewRef: questionRef(newTheme, newQuestions, pair[1]),
+			Text:   itemsText(newQuestions[pair[1]].GetQuestionContent()),
+			Fields: fields,
+		})
+	}
+}
+
+// compareFiles reports added, removed and modified archive files.
+// A removed file whose content reappears under a new name is reported as renamed.
+func compareFiles(oldFiles, newFiles map[string]MediaInfo) []MediaChange {
+	var changes, added []MediaChange
+	removedByHash := make(map[string][]string)
+
+	for _, name := range sortedNames(oldFiles) {
+		oldInfo := oldFiles[name]
+		newInfo, ok := newFiles[name]
+		switch {
+		case !ok:
+			removedByHash[oldInfo.Hash] = append(removedByHash[oldInfo.Hash], name)
+		case newInfo.Hash != oldInfo.Hash:
+			changes = append(changes, MediaChange{
+				Kind: Changed, Name: name,
+				OldHash: oldInfo.Hash, NewHash: newInfo.Hash,
+				OldSize: oldInfo.Size, NewSize: newInfo.Size,
+			})
+		}
+	}
+
+	for _, name := range sortedNames(newFiles) {
+		if _, ok := oldFiles[name]; ok {
+			continue
+		}
+		info := newFiles[name]
+		if oldNames := removedByHash[info.Hash]; len(oldNames) > 0 {
+			removedByHash[info.Hash] = oldNames[1:]
+			changes = append(changes, MediaChange{
+				Kind: Renamed, Name: name, OldName: oldNames[0],
+				OldHash: info.Hash, NewHash: info.Hash,
+				OldSize: info.Size, NewSize: info.Size,
+			})
+			continue
+		}
+		added = append(added, MediaChange{Kind: Added, Name: name, NewHash: info.Hash, NewSize: info.Size})
+	}
+
+	for _, name := range sortedNames(oldFiles) {
+		info := oldFiles[name]
+		if slices.Contains(removedByHash[info.Hash], name) {
+			changes = append(changes, MediaChange{Kind: Removed, Name: name, OldHash: info.Hash, OldSize: info.Size})
+		}
+	}
+
+	changes = append(changes, added...)
+	slices.SortStableFunc(changes, func(a, b MediaChange) int { return strings.Compare(a.Name, b.Name) })
+	return changes
+}
+
+// compareFields returns the fields whose values differ, in the order of the
+// new fields followed by the fields that only exist in the old ones
+func compareFields(oldFields, newFields []field) []FieldChange {
+	oldValues := make(map[string]string, len(oldFields))
+	for _, f := range oldFields {
+		oldValues[f.name] = f.value
+	}
+	newValues := make(map[string]string, len(newFields))
+	for _, f := range newFields {
+		newValues[f.name] = f.value
+	}
+
+	var changes []FieldChange
+	for _, f := range newFields {
+		if oldValues[f.name] != f.value {
+			changes = append(changes, FieldChange{Field: f.name, Old: oldValues[f.name], New: f.value})
+		}
+	}
+	for _, f := range oldFields {
+		if _, ok := newValues[f.name]; !ok && f.value != "" {
+			changes = append(changes, FieldChange{Field: f.name, Old: f.value})
+		}
+	}
+	return changes
+}
+
+// match pairs equal keys first and the remaining elements by position.
+// It returns the matched index pairs ordered by the new index and the
+// indexes of unmatched old and new elements.
+func match(oldKeys, newKeys []string) (pairs [][2]int, removed, added []int) {
+	oldMatched := make([]bool, len(oldKeys))
+	newMatched := make([]bool, len(newKeys))
+
+	byKey := make(map[string][]int)
+	for i, key := range oldKeys {
+		byKey[key] = append(byKey[key], i)
+	}
+	for j, key := range newKeys {
+		if candidates := byKey[key]; len(candidates) > 0 {
+			byKey[key] = candidates[1:]
+			oldMatched[candidates[0]] = true
+			newMatched[j] = true
+			pairs = append(pairs, [2]int{candidates[0], j})
+		}
+	}
+
+	// Pair the remaining elements in order, they are most likely edited in place
+	i := 0
+	for j := range newKeys {
+		if newMatched[j] {
+			continue
+		}
+		for i < len(oldKeys) && oldMatched[i] {
+			i++
+		}
+		if i == len(oldKeys) {
+			added = append(added, j)
+			continue
+		}
+		oldMatched[i] = true
+		newMatched[j] = true
+		pairs = append(pairs, [2]int{i, j})
+	}
+	for i, matched := range oldMatched {
+		if !matched {
+			removed = append(removed, i)
+		}
+	}
+
+	slices.SortFunc(pairs, func(a, b [2]int) int { return a[1] - b[1] })
+	return pairs, removed, added
+}
+
+// roundQuestions returns the number of questions in a round
+func roundQuestions(round siq.Round) int {
+	count := 0
+	for _, theme := range round.Themes {
+		count += len(theme.Questions)
+	}
+	return count
+}
+
+// sortedNames returns the file names of a snapshot in sorted order
+func sortedNames(files map[string]MediaInfo) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
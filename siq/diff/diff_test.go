@@ -0,0 +1,190 @@
+package diff
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// textQuestion creates a simple question with text content
+func textQuestion(price int, text string, right ...string) siq.Question {
+	return siq.Question{
+		Type:      siq.QuestionTypeSimple,
+		BasePrice: price,
+		Params: []siq.Param{{
+			Name:  siq.ParamNameQuestion,
+			Type:  siq.ParamTypeContent,
+			Items: []siq.ContentItem{{Type: siq.ContentTypeText, Value: text}},
+		}},
+		Right: right,
+	}
+}
+
+func oldSnapshot() *Snapshot {
+	return &Snapshot{
+		Package: &siq.Package{
+			Name: "Pack",
+			Rounds: []siq.Round{
+				{Name: "Round 1", Themes: []siq.Theme{
+					{Name: "Planets", Questions: []siq.Question{
+						textQuestion(100, "Largest planet", "Jupiter"),
+						textQuestion(200, "Smallest planet", "Mercury"),
+						textQuestion(300, "Red planet", "Mars"),
+					}},
+					{Name: "Rivers", Questions: []siq.Question{textQuestion(100, "Longest river", "Nile")}},
+				}},
+				{Name: "Final", Themes: []siq.Theme{{Name: "Art"}}},
+			},
+		},
+		Files: map[string]MediaInfo{
+			"Images/a.png": {Hash: "aaa", Size: 10},
+			"Images/b.png": {Hash: "bbb", Size: 20},
+			"Audio/c.mp3":  {Hash: "ccc", Size: 30},
+		},
+	}
+}
+
+func newSnapshot() *Snapshot {
+	return &Snapshot{
+		Package: &siq.Package{
+			Name:     "Pack",
+			Language: "en",
+			Rounds: []siq.Round{
+				{Name: "Round 1", Themes: []siq.Theme{
+					{Name: "Rivers", Questions: []siq.Question{textQuestion(100, "Longest river", "Nile")}},
+					{Name: "Planets", Questions: []siq.Question{
+						textQuestion(100, "Smallest planet", "Mercury"),
+						textQuestion(200, "Largest planet", "Jupiter", "Zeus"),
+						textQuestion(400, "Ringed planet", "Saturn"),
+						textQuestion(500, "Hottest planet", "Venus"),
+					}},
+				}},
+				{Name: "Bonus", Themes: []siq.Theme{{Name: "Music", Questions: []siq.Question{textQuestion(100, "Composer", "Bach")}}}},
+			},
+		},
+		Files: map[string]MediaInfo{
+			"Images/a.png":  {Hash: "aaa", Size: 10},
+			"Images/b2.png": {Hash: "bbb", Size: 20},
+			"Audio/c.mp3":   {Hash: "ccc2", Size: 31},
+			"Video/new.mp4": {Hash: "ddd", Size: 40},
+		},
+	}
+}
+
+func TestCompareIdentical(t *testing.T) {
+	if result := Compare(oldSnapshot(), oldSnapshot()); !result.IsEmpty() {
+		t.Errorf("Expected no differences, got %+v", result)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	result := Compare(oldSnapshot(), newSnapshot())
+
+	if len(result.Metadata) != 1 || result.Metadata[0] != (FieldChange{Field: "language", Old: "", New: "en"}) {
+		t.Errorf("Unexpected metadata changes %+v", result.Metadata)
+	}
+
+	// Reordered themes are matched by name, the unmatched round is paired by position
+	if len(result.Rounds) != 1 || result.Rounds[0].Kind != Changed || result.Rounds[0].Fields[0].New != "Bonus" {
+		t.Errorf("Unexpected round changes %+v", result.Rounds)
+	}
+	if len(result.Themes) != 1 || result.Themes[0].Fields[0] != (FieldChange{Field: "name", Old: "Art", New: "Music"}) {
+		t.Errorf("Expected theme 'Art' renamed to 'Music', got %+v", result.Themes)
+	}
+
+	var summary []string
+	for _, change := range result.Questions {
+		summary = append(summary, string(change.Kind)+" "+change.Ref().ID()+" "+change.Text)
+	}
+	expected := []string{
+		"added r1/t2/q4 Hottest planet",
+		"changed r1/t2/q1 Smallest planet",
+		"changed r1/t2/q2 Largest planet",
+		"changed r1/t2/q3 Ringed planet",
+		"added r2/t1/q1 Composer",
+	}
+	if strings.Join(summary, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected question changes:\n%s", strings.Join(summary, "\n"))
+	}
+
+	largest := result.Questions[2]
+	if largest.OldRef.ID() != "r1/t1/q1" || largest.OldRef.Price.Value != 100 {
+		t.Errorf("Expected old location r1/t1/q1, got %+v", largest.OldRef)
+	}
+	if len(largest.Fields) != 2 || largest.Fields[0].Field != "price" || largest.Fields[1] != (FieldChange{Field: "right", Old: "Jupiter", New: "Jupiter; Zeus"}) {
+		t.Errorf("Unexpected field changes %+v", largest.Fields)
+	}
+
+	var media []string
+	for _, change := range result.Media {
+		media = append(media, string(change.Kind)+" "+change.Name+" "+change.OldName)
+	}
+	expectedMedia := []string{
+		"changed Audio/c.mp3 ",
+		"renamed Images/b2.png Images/b.png",
+		"added Video/new.mp4 ",
+	}
+	if strings.Join(media, "\n") != strings.Join(expectedMedia, "\n") {
+		t.Errorf("Unexpected media changes:\n%s", strings.Join(media, "\n"))
+	}
+}
+
+func TestMatch(t *testing.T) {
+	pairs, removed, added := match([]string{"a", "b", "c"}, []string{"c", "x", "a", "y"})
+	if len(pairs) != 3 || pairs[0] != [2]int{2, 0} || pairs[1] != [2]int{1, 1} || pairs[2] != [2]int{0, 2} {
+		t.Errorf("Unexpected pairs %v", pairs)
+	}
+	if len(removed) != 0 || len(added) != 1 || added[0] != 3 {
+		t.Errorf("Unexpected removed %v and added %v", removed, added)
+	}
+
+	pairs, removed, added = match([]string{"a", "b", "c"}, []string{"b"})
+	if len(pairs) != 1 || pairs[0] != [2]int{1, 0} || len(added) != 0 || len(removed) != 2 || removed[0] != 0 || removed[1] != 2 {
+		t.Errorf("Unexpected pairs %v, removed %v and added %v", pairs, removed, added)
+	}
+}
+
+func TestLoadAndDump(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.siq")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal("Failed to create test file:", err)
+	}
+	zipWriter := zip.NewWriter(file)
+	for name, data := range map[string]string{
+		"content.xml": `<package name="Dump" version="5"><round name="R"><theme name="T">
+			<question price="100"><params><param name="question" type="content"><item>Q?</item></param></params>
+			<right><answer>A</answer></right></question></theme></round></package>`,
+		"Images/cat.png": "cat",
+	} {
+		writer, err := zipWriter.Create(name)
+		if err != nil {
+			t.Fatal("Failed to create file in zip:", err)
+		}
+		writer.Write([]byte(data))
+	}
+	zipWriter.Close()
+	file.Close()
+
+	snapshot, err := Load(path)
+	if err != nil {
+		t.Fatal("Failed to load snapshot:", err)
+	}
+	if len(snapshot.Files) != 1 || snapshot.Files["Images/cat.png"].Size != 3 {
+		t.Errorf("Expected only the image to be hashed, got %+v", snapshot.Files)
+	}
+
+	var out strings.Builder
+	if err := Dump(&out, snapshot); err != nil {
+		t.Fatal("Failed to dump snapshot:", err)
+	}
+	for _, line := range []string{"name: Dump", "round 1: R", "  theme 1: T", "      price: 100", "      question: Q?", "      right: A", "  Images/cat.png 3 sha256:"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected dump to contain %q, got:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// Dump writes a canonical line-oriented text representation of the snapshot.
+// It is meant for line-based tools, for example as a git textconv filter,
+// so every value is on its own line and empty values are omitted.
+func Dump(w io.Writer, snapshot *Snapshot) error {
+	out := bufio.NewWriter(w)
+	pkg := snapshot.Package
+
+	for _, f := range infoFields(pkg) {
+		if f.value != "" {
+			fmt.Fprintf(out, "%s: %s\n", f.name, f.value)
+		}
+	}
+
+	for roundIndex, round := range pkg.Rounds {
+		fmt.Fprintf(out, "\nround %d: %s\n", roundIndex+1, round.Name)
+		if round.Type != "" {
+			fmt.Fprintf(out, "  type: %s\n", round.Type)
+		}
+		for themeIndex, theme := range round.Themes {
+			fmt.Fprintf(out, "  theme %d: %s\n", themeIndex+1, theme.Name)
+			for questionIndex := range theme.Questions {
+				fmt.Fprintf(out, "    question %d\n", questionIndex+1)
+				for _, f := range questionFields(&theme.Questions[questionIndex]) {
+					if f.value != "" {
+						fmt.Fprintf(out, "      %s: %s\n", f.name, f.value)
+					}
+				}
+			}
+		}
+	}
+
+	if len(snapshot.Files) > 0 {
+		fmt.Fprintf(out, "\nfiles:\n")
+		for _, name := range sortedNames(snapshot.Files) {
+			info := snapshot.Files[name]
+			fmt.Fprintf(out, "  %s %d sha256:%s\n", name, info.Size, info.Hash)
+		}
+	}
+
+	return out.Flush()
+}
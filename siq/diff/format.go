@@ -0,0 +1,105 @@
+package diff
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// itemsText renders content items as a single line.
+// Text items are shown as is, other items as "[type: value]".
+func itemsText(items []siq.ContentItem) string {
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.GetType() == siq.ContentTypeText {
+			parts = append(parts, item.Value)
+		} else {
+			parts = append(parts, fmt.Sprintf("[%s: %s]", item.GetType(), item.Value))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// paramText renders a parameter value as a single line
+func paramText(param siq.Param) string {
+	switch param.GetType() {
+	case siq.ParamTypeContent:
+		return itemsText(param.Items)
+	case siq.ParamTypeNumberSet:
+		if set := param.GetNumberSet(); set != nil {
+			return siq.QuestionPrice{Set: set}.String()
+		}
+		return ""
+	case siq.ParamTypeGroup:
+		parts := make([]string, 0, len(param.Params))
+		for _, child := range param.Params {
+			parts = append(parts, child.Name+"="+paramText(child))
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return strings.TrimSpace(param.Value)
+	}
+}
+
+// field is a named comparable value
+type field struct {
+	name  string
+	value string
+}
+
+// questionFields returns the comparable fields of a question in display order
+func questionFields(question *siq.Question) []field {
+	fields := []field{
+		{"type", question.Type},
+		{"price", question.Price().String()},
+		{"question", itemsText(question.GetQuestionContent())},
+		{"answer", itemsText(question.AnswerContent())},
+		{"right", strings.Join(question.Right, "; ")},
+		{"wrong", strings.Join(question.Wrong, "; ")},
+	}
+
+	var params []field
+	for _, param := range question.Params {
+		if param.Name == siq.ParamNameQuestion || param.Name == siq.ParamNameAnswer || param.Name == siq.ParamNamePrice {
+			continue
+		}
+		params = append(params, field{"param " + param.Name, paramText(param)})
+	}
+	slices.SortFunc(params, func(a, b field) int { return strings.Compare(a.name, b.name) })
+	return append(fields, params...)
+}
+
+// infoFields returns the comparable fields of package metadata in display order
+func infoFields(pkg *siq.Package) []field {
+	difficulty := ""
+	if pkg.Difficulty != 0 {
+		difficulty = strconv.Itoa(pkg.Difficulty)
+	}
+	fields := []field{
+		{"id", pkg.ID},
+		{"name", pkg.Name},
+		{"version", pkg.Version},
+		{"date", pkg.Date},
+		{"publisher", pkg.Publisher},
+		{"difficulty", difficulty},
+		{"language", pkg.Language},
+		{"restriction", pkg.Restriction},
+		{"logo", pkg.Logo},
+	}
+	var tags, authors, sources, comments []string
+	if pkg.Tags != nil {
+		tags = pkg.Tags.Tags
+	}
+	if pkg.Info != nil {
+		authors, sources, comments = pkg.Info.Authors, pkg.Info.Sources, pkg.Info.Comments
+	}
+	return append(fields,
+		field{"tags", strings.Join(tags, "; ")},
+		field{"authors", strings.Join(authors, "; ")},
+		field{"sources", strings.Join(sources, "; ")},
+		field{"comments", strings.Join(comments, "; ")},
+	)
+}
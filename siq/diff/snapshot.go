@@ -0,0 +1,52 @@
+// Package diff compares two versions of a SIQ package structurally
+package diff
+
+import (
+	"strings"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// contentFileName is the name of the package definition inside the archive
+const contentFileName = "content.xml"
+
+// MediaInfo identifies the content of an archive file
+type MediaInfo struct {
+	Hash string
+	Size int64
+}
+
+// Snapshot is a loaded package together with the hashes of its files
+type Snapshot struct {
+	Path    string
+	Package *siq.Package
+	// Files maps archive file names (except content.xml) to their content info
+	Files map[string]MediaInfo
+}
+
+// Load reads a package and hashes every file of its archive
+func Load(path string) (*Snapshot, error) {
+	reader, err := siq.NewSIQReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	pkg, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{Path: path, Package: pkg, Files: make(map[string]MediaInfo)}
+	for _, name := range reader.ListFiles() {
+		if strings.EqualFold(name, contentFileName) || strings.HasSuffix(name, "/") {
+			continue
+		}
+		hash, size, err := reader.HashFile(name)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Files[name] = MediaInfo{Hash: hash, Size: size}
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,314 @@
+// Package draft drafts themes of questions with a language model. Drafted
+// questions are marked in their comments so authors can find and review
+// them before the package is played.
+package draft
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/minmaxmean/sigma/llm"
+	"github.com/minmaxmean/sigma/siq"
+	"github.com/minmaxmean/sigma/textnorm"
+)
+
+// GeneratedComment starts the comment added to drafted themes and questions
+const GeneratedComment = "Generated draft, review before use"
+
+// systemPrompt describes the task to the model
+const systemPrompt = `You write questions for quiz game packages. A question is a short clue read by the host, players answer with a word or a short phrase.
+Questions must be factually correct, unambiguous and must not contain their answer. Respond with JSON only.`
+
+// Options configures drafting
+type Options struct {
+	Model string
+	Topic string
+	// Questions is the number of questions of the theme
+	Questions int
+	// Prices of the questions in order, by default 100, 200 and so on
+	Prices []int
+	// Language of the questions, by default the language of the topic
+	Language string
+	// Theme is the theme name, by default the name suggested by the model
+	Theme string
+	// Attempts is the number of requests made before giving up on invalid responses
+	Attempts int
+}
+
+// DefaultOptions returns the default drafting options
+func DefaultOptions() Options {
+	return Options{
+		Questions: 5,
+		Attempts:  3,
+	}
+}
+
+// Result is a drafted theme
+type Result struct {
+	Theme    siq.Theme
+	Attempts int
+	// PromptTokens and CompletionTokens are the totals reported by the provider
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// draftResponse is the JSON structure the model responds with
+type draftResponse struct {
+	Theme     string          `json:"theme"`
+	Questions []draftQuestion `json:"questions"`
+}
+
+type draftQuestion struct {
+	Text       string   `json:"text"`
+	Answer     string   `json:"answer"`
+	Alternates []string `json:"alternates"`
+	Sources    []string `json:"sources"`
+}
+
+// Draft asks the model for a theme of questions on the topic. Responses
+// failing validation are sent back to the model with the errors until a
+// valid theme is returned or the attempts are used up.
+func Draft(ctx context.Context, provider llm.Provider, options Options) (*Result, error) {
+	if strings.TrimSpace(options.Topic) == "" {
+		return nil, errors.New("topic is not set")
+	}
+	prices, err := questionPrices(options)
+	if err != nil {
+		return nil, err
+	}
+	attempts := max(options.Attempts, 1)
+
+	schema, err := responseSchema(len(prices))
+	if err != nil {
+		return nil, err
+	}
+	messages := []llm.Message{
+		{Role: llm.RoleSystem, Content: systemPrompt},
+		{Role: llm.RoleUser, Content: prompt(options, prices)},
+	}
+
+	result := &Result{}
+	var lastErr error
+	for result.Attempts < attempts {
+		result.Attempts++
+		resp, err := provider.Chat(ctx, llm.ChatRequest{Model: options.Model, Messages: messages, Format: schema})
+		if err != nil {
+			return nil, fmt.Errorf("failed to draft theme: %w", err)
+		}
+		result.PromptTokens += resp.PromptTokens
+		result.CompletionTokens += resp.CompletionTokens
+
+		theme, err := buildTheme(resp.Text, prices, options)
+		if err == nil {
+			result.Theme = *theme
+			return result, nil
+		}
+		lastErr = err
+		messages = append(messages,
+			llm.Message{Role: llm.RoleAssistant, Content: resp.Text},
+			llm.Message{Role: llm.RoleUser, Content: fmt.Sprintf("The response is invalid:\n%v\nReturn the whole corrected JSON.", err)},
+		)
+	}
+	return nil, fmt.Errorf("no valid theme after %d attempt(s): %w", attempts, lastErr)
+}
+
+// IsGenerated reports whether the info carries the drafted item comment
+func IsGenerated(info *siq.Info) bool {
+	if info == nil {
+		return false
+	}
+	for _, comment := range info.Comments {
+		if strings.HasPrefix(comment, GeneratedComment) {
+			return true
+		}
+	}
+	return false
+}
+
+// questionPrices returns the prices of the drafted questions
+func questionPrices(options Options) ([]int, error) {
+	if len(options.Prices) > 0 {
+		if options.Questions > 0 && options.Questions != len(options.Prices) {
+			return nil, fmt.Errorf("%d prices given for %d questions", len(options.Prices), options.Questions)
+		}
+		return options.Prices, nil
+	}
+	if options.Questions <= 0 {
+		return nil, errors.New("number of questions must be positive")
+	}
+	prices := make([]int, options.Questions)
+	for i := range prices {
+		prices[i] = (i + 1) * 100
+	}
+	return prices, nil
+}
+
+// prompt returns the request for the theme
+func prompt(options Options, prices []int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Write a theme of %d questions on the topic %q", len(prices), options.Topic)
+	if options.Language != "" {
+		fmt.Fprintf(&sb, " in %s", options.Language)
+	}
+	texts := make([]string, len(prices))
+	for i, price := range prices {
+		texts[i] = fmt.Sprint(price)
+	}
+	fmt.Fprintf(&sb, ".\nThe questions are worth %s in this order. Difficulty must grow with the price: the first question is easy, the last one is hard.\n", strings.Join(texts, ", "))
+	sb.WriteString(`For every question give:
+- text: the question as read by the host, without the answer
+- answer: the main right answer, short
+- alternates: other acceptable forms of the answer such as spelling variants or full names, may be empty
+- sources: where the facts can be checked, such as encyclopedia articles or URLs
+`)
+	if options.Theme == "" {
+		sb.WriteString("Also give a short theme name.\n")
+	}
+	return sb.String()
+}
+
+// responseSchema returns the JSON schema of a theme with count questions
+func responseSchema(count int) (json.RawMessage, error) {
+	stringList := map[string]any{"type": "array", "items": map[string]string{"type": "string"}}
+	return json.Marshal(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"theme": map[string]string{"type": "string"},
+			"questions": map[string]any{
+				"type":     "array",
+				"minItems": count,
+				"maxItems": count,
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"text":       map[string]string{"type": "string"},
+						"answer":     map[string]string{"type": "string"},
+						"alternates": stringList,
+						"sources":    stringList,
+					},
+					"required": []string{"text", "answer", "alternates", "sources"},
+				},
+			},
+		},
+		"required": []string{"theme", "questions"},
+	})
+}
+
+// buildTheme parses and validates a response and converts it to a theme
+// with marked questions
+func buildTheme(text string, prices []int, options Options) (*siq.Theme, error) {
+	var resp draftResponse
+	if err := json.Unmarshal([]byte(text), &resp); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	if len(resp.Questions) != len(prices) {
+		return nil, fmt.Errorf("expected %d questions, got %d", len(prices), len(resp.Questions))
+	}
+
+	name := strings.TrimSpace(options.Theme)
+	if name == "" {
+		name = strings.TrimSpace(resp.Theme)
+	}
+	if name == "" {
+		name = options.Topic
+	}
+	comment := fmt.Sprintf("%s (topic %q", GeneratedComment, options.Topic)
+	if options.Model != "" {
+		comment += ", model " + options.Model
+	}
+	comment += ")"
+
+	theme := &siq.Theme{Name: name, Info: &siq.Info{Comments: []string{comment}}}
+	var errs []error
+	seen := make(map[string]bool)
+	for i, drafted := range resp.Questions {
+		question, err := buildQuestion(drafted, prices[i], comment)
+		if err == nil {
+			key := textnorm.Fold(drafted.Text)
+			if seen[key] {
+				err = errors.New("repeats an earlier question")
+			}
+			seen[key] = true
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("question %d: %w", i+1, err))
+			continue
+		}
+		theme.Questions = append(theme.Questions, *question)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return theme, nil
+}
+
+// buildQuestion converts a drafted question to a simple question and
+// checks it against the package model
+func buildQuestion(drafted draftQuestion, price int, comment string) (*siq.Question, error) {
+	text := strings.TrimSpace(drafted.Text)
+	answer := strings.TrimSpace(drafted.Answer)
+	switch {
+	case text == "":
+		return nil, errors.New("text is empty")
+	case answer == "":
+		return nil, errors.New("answer is empty")
+	case containsWords(text, answer):
+		return nil, fmt.Errorf("text contains the answer %q", answer)
+	}
+
+	right := []string{answer}
+	for _, alternate := range drafted.Alternates {
+		alternate = strings.TrimSpace(alternate)
+		if alternate != "" && !containsFold(right, alternate) {
+			right = append(right, alternate)
+		}
+	}
+	var sources []string
+	for _, source := range drafted.Sources {
+		if source = strings.TrimSpace(source); source != "" {
+			sources = append(sources, source)
+		}
+	}
+
+	question := &siq.Question{
+		Type:      siq.QuestionTypeSimple,
+		BasePrice: price,
+		Params: []siq.Param{{
+			Name:  siq.ParamNameQuestion,
+			Type:  siq.ParamTypeContent,
+			Items: []siq.ContentItem{{Type: siq.ContentTypeText, Value: text}},
+		}},
+		Right: right,
+		Info:  &siq.Info{Sources: sources, Comments: []string{comment}},
+	}
+	if _, err := question.DecodeParams(); err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+// containsWords reports whether text contains phrase as whole words,
+// ignoring case and diacritics. Phrases shorter than 3 letters are ignored.
+func containsWords(text, phrase string) bool {
+	phrase = textnorm.Fold(phrase)
+	if len([]rune(phrase)) < 3 {
+		return false
+	}
+	pattern := `(^|[^\pL\pN])` + regexp.QuoteMeta(phrase) + `($|[^\pL\pN])`
+	matched, _ := regexp.MatchString(pattern, textnorm.Fold(text))
+	return matched
+}
+
+// containsFold reports whether values contain value ignoring case
+func containsFold(values []string, value string) bool {
+	for _, existing := range values {
+		if strings.EqualFold(existing, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package draft
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minmaxmean/sigma/internal/siqtest"
+	"github.com/minmaxmean/sigma/llm"
+	"github.com/minmaxmean/sigma/siq"
+)
+
+const planetsResponse = `{
+	"theme": "Planets",
+	"questions": [
+		{"text": "The largest planet of the Solar System", "answer": "Jupiter", "alternates": [], "sources": ["https://en.wikipedia.org/wiki/Jupiter"]},
+		{"text": "The planet with the most prominent rings", "answer": "Saturn", "alternates": ["saturn", " Planet Saturn "], "sources": []},
+		{"text": "The hottest planet despite not being the closest to the Sun", "answer": "Venus", "alternates": [], "sources": [" "]}
+	]
+}`
+
+func TestDraft(t *testing.T) {
+	provider := llm.NewScripted(planetsResponse)
+	options := DefaultOptions()
+	options.Model = "model"
+	options.Topic = "Planets of the Solar System"
+	options.Questions = 3
+	options.Language = "English"
+
+	result, err := Draft(context.Background(), provider, options)
+	if err != nil {
+		t.Fatalf("Failed to draft: %v", err)
+	}
+	theme := result.Theme
+	if theme.Name != "Planets" || len(theme.Questions) != 3 || result.Attempts != 1 {
+		t.Fatalf("Expected theme Planets with 3 questions, got %+v", result)
+	}
+	if !IsGenerated(theme.Info) {
+		t.Errorf("Expected theme to be marked as generated, got %+v", theme.Info)
+	}
+
+	for i, question := range theme.Questions {
+		if question.BasePrice != (i+1)*100 || !IsGenerated(question.Info) {
+			t.Errorf("Expected question %d marked with price %d, got %+v", i+1, (i+1)*100, question)
+		}
+		if _, err := question.DecodeParams(); err != nil {
+			t.Errorf("Expected valid question, got %v", err)
+		}
+	}
+	saturn := theme.Questions[1]
+	if strings.Join(saturn.Right, "|") != "Saturn|Planet Saturn" {
+		t.Errorf("Expected answer with distinct alternates, got %v", saturn.Right)
+	}
+	if sources := theme.Questions[0].Info.Sources; len(sources) != 1 || theme.Questions[2].Info.Sources != nil {
+		t.Errorf("Expected blank sources to be dropped, got %v and %v", sources, theme.Questions[2].Info.Sources)
+	}
+	if !strings.Contains(theme.Info.Comments[0], `topic "Planets of the Solar System", model model`) {
+		t.Errorf("Expected topic and model in the comment, got %q", theme.Info.Comments[0])
+	}
+
+	request := provider.Requests[0]
+	if !strings.Contains(request.Messages[1].Content, "100, 200, 300") || !strings.Contains(request.Messages[1].Content, "in English") {
+		t.Errorf("Expected prices and language in the prompt, got %q", request.Messages[1].Content)
+	}
+	if !strings.Contains(string(request.Format), `"minItems":3`) {
+		t.Errorf("Expected schema with the question count, got %s", request.Format)
+	}
+}
+
+func TestDraftRetriesInvalidResponses(t *testing.T) {
+	invalid := `{"theme": "Rivers", "questions": [
+		{"text": "The longest river of Africa", "answer": "Nile", "alternates": [], "sources": []},
+		{"text": "The Amazon flows into this ocean", "answer": "Amazon", "alternates": [], "sources": []}
+	]}`
+	valid := `{"theme": "Rivers", "questions": [
+		{"text": "The longest river of Africa", "answer": "Nile", "alternates": [], "sources": []},
+		{"text": "The river flowing through Manaus", "answer": "Amazon", "alternates": [], "sources": []}
+	]}`
+	provider := llm.NewScripted(`{"theme": "Rivers", "questions": []}`, invalid, valid)
+
+	options := DefaultOptions()
+	options.Topic = "Rivers"
+	options.Prices = []int{200, 400}
+	options.Questions = 0
+	options.Theme = "Great Rivers"
+
+	result, err := Draft(context.Background(), provider, options)
+	if err != nil {
+		t.Fatalf("Failed to draft: %v", err)
+	}
+	if result.Attempts != 3 || result.Theme.Name != "Great Rivers" || result.Theme.Questions[1].BasePrice != 400 {
+		t.Errorf("Expected valid theme on the third attempt, got %+v", result)
+	}
+
+	last := provider.Requests[2].Messages
+	if len(last) != 6 || last[2].Role != llm.RoleAssistant || last[4].Role != llm.RoleAssistant {
+		t.Fatalf("Expected invalid responses in the history, got %+v", last)
+	}
+	if !strings.Contains(last[3].Content, "expected 2 questions, got 0") {
+		t.Errorf("Expected count error to be sent back, got %q", last[3].Content)
+	}
+	if !strings.Contains(last[5].Content, `question 2: text contains the answer "Amazon"`) {
+		t.Errorf("Expected answer error to be sent back, got %q", last[5].Content)
+	}
+
+	provider = llm.NewScripted(invalid, invalid)
+	options.Attempts = 2
+	if _, err := Draft(context.Background(), provider, options); err == nil || !strings.Contains(err.Error(), "after 2 attempt(s)") {
+		t.Errorf("Expected error after using up attempts, got %v", err)
+	}
+
+	provider = llm.NewScripted()
+	provider.Err = errors.New("offline")
+	if _, err := Draft(context.Background(), provider, options); err == nil {
+		t.Errorf("Expected provider error")
+	}
+	options.Questions = 3
+	if _, err := Draft(context.Background(), llm.NewScripted(), options); err == nil {
+		t.Errorf("Expected error for price count mismatch")
+	}
+}
+
+func TestWriteTheme(t *testing.T) {
+	dir := t.TempDir()
+	result, err := Draft(context.Background(), llm.NewScripted(planetsResponse), Options{Topic: "Planets", Questions: 3})
+	if err != nil {
+		t.Fatalf("Failed to draft: %v", err)
+	}
+
+	// A new package is created
+	newPath := filepath.Join(dir, "new.siq")
+	if err := WriteTheme(result.Theme, Target{Path: newPath, Language: "en"}); err != nil {
+		t.Fatalf("Failed to write new package: %v", err)
+	}
+	pkg := readPackage(t, newPath)
+	if pkg.Name != "Planets" || pkg.Language != "en" || pkg.ID == "" || len(pkg.Rounds) != 1 || pkg.Rounds[0].Name != "Round 1" {
+		t.Errorf("Unexpected new package %+v", pkg)
+	}
+	if pkg.GetQuestionCount() != 3 || !IsGenerated(pkg.Rounds[0].Themes[0].Questions[0].Info) {
+		t.Errorf("Expected marked questions in the new package")
+	}
+
+	// An existing package is rewritten with its media
+	existing := siqtest.CreateFile(t, dir, "existing.siq", `<?xml version="1.0" encoding="utf-8"?>
+<package id="p" name="Pack" version="5">
+	<round name="Round 1"><theme name="Cats"><question price="100">
+		<params><param name="question" type="content"><item type="image" isRef="True">cat.png</item></param></params>
+		<right><answer>Cat</answer></right>
+	</question></theme></round>
+	<round name="Final"><theme name="Dogs"/></round>
+</package>`, map[string]string{"Images/cat.png": "png"})
+
+	if err := WriteTheme(result.Theme, Target{Path: existing, Round: "Round 1"}); err != nil {
+		t.Fatalf("Failed to write existing package: %v", err)
+	}
+	if err := WriteTheme(result.Theme, Target{Path: existing, Round: "Bonus"}); err != nil {
+		t.Fatalf("Failed to write new round: %v", err)
+	}
+	pkg = readPackage(t, existing)
+	if len(pkg.Rounds) != 3 || len(pkg.Rounds[0].Themes) != 2 || pkg.Rounds[0].Themes[1].Name != "Planets" || pkg.Rounds[2].Name != "Bonus" {
+		t.Errorf("Expected theme added to Round 1 and a new Bonus round, got %+v", pkg.Rounds)
+	}
+
+	reader, err := siq.NewSIQReader(existing)
+	if err != nil {
+		t.Fatalf("Failed to open package: %v", err)
+	}
+	defer reader.Close()
+	if _, err := reader.GetFile("Images/cat.png"); err != nil {
+		t.Errorf("Expected media to be kept: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("Expected no temporary files left, got %v", entries)
+	}
+}
+
+func readPackage(t *testing.T, path string) *siq.Package {
+	t.Helper()
+	reader, err := siq.NewSIQReader(path)
+	if err != nil {
+		t.Fatalf("Failed to open package: %v", err)
+	}
+	defer reader.Close()
+	pkg, err := reader.Read()
+	if err != nil {
+		t.Fatalf("Failed to read package: %v", err)
+	}
+	return pkg
+}
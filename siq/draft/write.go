@@ -0,0 +1,87 @@
+package draft
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// Target selects where a drafted theme is written
+type Target struct {
+	// Path of the package, a new package is created when it does not exist
+	Path string
+	// Round is the name of the round receiving the theme. It is created when
+	// missing, empty selects the last round.
+	Round string
+	// Language is set on new packages
+	Language string
+}
+
+// WriteTheme adds the theme to the target package. Existing packages are
+// rewritten in place with their media.
+func WriteTheme(theme siq.Theme, target Target) error {
+	reader, err := siq.NewSIQReader(target.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		pkg := newPackage(theme.Name, target.Language)
+		addTheme(pkg, theme, target.Round)
+		return writePackage(target.Path, pkg, nil)
+	}
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	pkg, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	addTheme(pkg, theme, target.Round)
+	return writePackage(target.Path, pkg, reader)
+}
+
+// addTheme appends the theme to the named round, or to the last round when
+// round is empty, creating the round when needed
+func addTheme(pkg *siq.Package, theme siq.Theme, round string) {
+	index := -1
+	for i := range pkg.Rounds {
+		if round == "" || pkg.Rounds[i].Name == round {
+			index = i
+		}
+	}
+	if index < 0 {
+		if round == "" {
+			round = fmt.Sprintf("Round %d", len(pkg.Rounds)+1)
+		}
+		pkg.Rounds = append(pkg.Rounds, siq.Round{Name: round})
+		index = len(pkg.Rounds) - 1
+	}
+	pkg.Rounds[index].Themes = append(pkg.Rounds[index].Themes, theme)
+}
+
+// newPackage creates an empty version 5 package
+func newPackage(name, language string) *siq.Package {
+	return &siq.Package{
+		ID:       siq.NewID(),
+		Name:     name,
+		Version:  "5",
+		Date:     time.Now().Format("02.01.2006"),
+		Language: language,
+	}
+}
+
+// writePackage writes the package and the files of reader unless it is nil.
+// An existing package at path is replaced once the new one is complete.
+func writePackage(path string, pkg *siq.Package, reader *siq.SIQReader) error {
+	return siq.ReplaceFile(path, func(writer *siq.SIQWriter) error {
+		if err := writer.WritePackage(pkg); err != nil {
+			return err
+		}
+		if reader == nil {
+			return nil
+		}
+		return writer.CopyFiles(reader)
+	})
+}
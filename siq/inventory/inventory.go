@@ -0,0 +1,198 @@
+// Package inventory cross-references the media files of a SIQ archive with
+// the content items and logo that refer to them
+package inventory
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// Use is a place in the package that refers to a media file
+type Use struct {
+	// Ref is the question location, nil for the package logo
+	Ref *siq.QuestionRef
+	// Param is the name of the question param holding the item, nested
+	// params are joined with "/", for example "answerOptions/A"
+	Param string
+	// Item is the content item referring to the file, the package logo is
+	// described by an image item
+	Item siq.ContentItem
+}
+
+// String returns a human-readable description of the use
+func (u Use) String() string {
+	if u.Ref == nil {
+		return "package logo"
+	}
+	return u.Ref.ID() + " " + u.Ref.String() + " [" + u.Param + "]"
+}
+
+// Asset is a media file of the package or a reference to one
+type Asset struct {
+	// Path is the decoded archive path, or the URL of an external file
+	Path string
+	// Entry is the raw archive entry name, empty for missing and external files
+	Entry string
+	Type  string
+	Size  int64
+	Uses  []Use
+	// Hint names an archive entry with the same file name in another
+	// folder for missing files, for example when an image is typed as audio
+	Hint string
+}
+
+// TypeStats summarizes the archive files of a content type
+type TypeStats struct {
+	Type         string
+	Files        int
+	Size         int64
+	Unreferenced int
+}
+
+// Inventory lists the media of a package
+type Inventory struct {
+	// Files are the archive files in media folders, sorted by path
+	Files []Asset
+	// Missing are referenced files that are not in the archive
+	Missing []Asset
+	// External are media items and logos pointing outside of the archive
+	External []Asset
+	// Types are the file statistics per content type
+	Types []TypeStats
+}
+
+// Unreferenced returns the archive files nothing refers to
+func (inv *Inventory) Unreferenced() []Asset {
+	var assets []Asset
+	for _, asset := range inv.Files {
+		if len(asset.Uses) == 0 {
+			assets = append(assets, asset)
+		}
+	}
+	return assets
+}
+
+// Build reads the package and the archive file list of reader and
+// cross-references them. Archive entries and references are compared by
+// their decoded paths, media folder names are matched case-insensitively.
+func Build(reader *siq.SIQReader) (*Inventory, error) {
+	pkg, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*Asset)
+	byName := make(map[string]string) // decoded file name -> archive path
+	for _, name := range reader.ListFiles() {
+		filePath := siq.EntryPath(name)
+		if filePath == "" {
+			continue
+		}
+		file, err := reader.GetFile(name)
+		if err != nil {
+			return nil, err
+		}
+		files[filePath] = &Asset{
+			Path:  filePath,
+			Entry: name,
+			Type:  siq.MediaType(name),
+			Size:  int64(file.UncompressedSize64),
+		}
+		byName[path.Base(filePath)] = filePath
+	}
+
+	missing := make(map[string]*Asset)
+	external := make(map[string]*Asset)
+	var missingOrder, externalOrder []string
+
+	add := func(filePath, contentType string, use Use) {
+		if asset, ok := files[filePath]; ok {
+			asset.Uses = append(asset.Uses, use)
+			return
+		}
+		asset, ok := missing[filePath]
+		if !ok {
+			asset = &Asset{Path: filePath, Type: contentType}
+			if other, found := byName[path.Base(filePath)]; found {
+				asset.Hint = other
+			}
+			missing[filePath] = asset
+			missingOrder = append(missingOrder, filePath)
+		}
+		asset.Uses = append(asset.Uses, use)
+	}
+	addExternal := func(url, contentType string, use Use) {
+		asset, ok := external[url]
+		if !ok {
+			asset = &Asset{Path: url, Type: contentType}
+			external[url] = asset
+			externalOrder = append(externalOrder, url)
+		}
+		asset.Uses = append(asset.Uses, use)
+	}
+
+	logo := Use{Item: siq.ContentItem{Type: siq.ContentTypeImage}}
+	if logoPath := pkg.LogoPath(); logoPath != "" {
+		logo.Item.IsRef = true
+		logo.Item.Value = strings.TrimPrefix(pkg.Logo, "@")
+		add(logoPath, siq.ContentTypeImage, logo)
+	} else if pkg.Logo != "" {
+		logo.Item.Value = pkg.Logo
+		addExternal(pkg.Logo, siq.ContentTypeImage, logo)
+	}
+
+	pkg.Walk(func(ref siq.QuestionRef, question *siq.Question) error {
+		for param, item := range question.ParamItems() {
+			use := Use{Ref: &ref, Param: param, Item: *item}
+			contentType := item.GetType()
+			switch {
+			case siq.MediaFolder(contentType) == "" || item.Value == "":
+			case item.IsRef:
+				add(siq.ItemPath(*item), contentType, use)
+			default:
+				addExternal(item.Value, contentType, use)
+			}
+		}
+		return nil
+	})
+
+	inv := &Inventory{}
+	for _, asset := range files {
+		inv.Files = append(inv.Files, *asset)
+	}
+	slices.SortFunc(inv.Files, func(a, b Asset) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	for _, filePath := range missingOrder {
+		inv.Missing = append(inv.Missing, *missing[filePath])
+	}
+	for _, url := range externalOrder {
+		inv.External = append(inv.External, *external[url])
+	}
+	inv.Types = typeStats(inv.Files)
+
+	return inv, nil
+}
+
+// typeStats sums up files per content type in the order of media folders
+func typeStats(files []Asset) []TypeStats {
+	order := []string{siq.ContentTypeImage, siq.ContentTypeAudio, siq.ContentTypeVideo, siq.ContentTypeHtml}
+	stats := make([]TypeStats, len(order))
+	for i, contentType := range order {
+		stats[i].Type = contentType
+	}
+	for _, file := range files {
+		index := slices.Index(order, file.Type)
+		stats[index].Files++
+		stats[index].Size += file.Size
+		if len(file.Uses) == 0 {
+			stats[index].Unreferenced++
+		}
+	}
+	return slices.DeleteFunc(stats, func(s TypeStats) bool {
+		return s.Files == 0
+	})
+}
@@ -0,0 +1,163 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/minmaxmean/sigma/internal/siqtest"
+	"github.com/minmaxmean/sigma/siq"
+)
+
+func buildInventory(t *testing.T, contentXML string, media map[string]string) *Inventory {
+	path := siqtest.CreateFile(t, t.TempDir(), "pack.siq", contentXML, media)
+	reader, err := siq.NewSIQReader(path)
+	if err != nil {
+		t.Fatal("Failed to open SIQ file:", err)
+	}
+	defer reader.Close()
+
+	inv, err := Build(reader)
+	if err != nil {
+		t.Fatal("Failed to build inventory:", err)
+	}
+	return inv
+}
+
+func findAsset(assets []Asset, path string) *Asset {
+	for i := range assets {
+		if assets[i].Path == path {
+			return &assets[i]
+		}
+	}
+	return nil
+}
+
+func TestBuild(t *testing.T) {
+	contentXML := `<?xml version="1.0" encoding="utf-8"?>
+<package id="media" name="Media" version="5" logo="@logo.png">
+	<round name="Round 1">
+		<theme name="Animals">
+			<question price="100">
+				<params>
+					<param name="question" type="content">
+						<item type="image" isRef="True">%D0%BA%D0%BE%D1%82.png</item>
+						<item type="audio" isRef="True">meow.mp3</item>
+					</param>
+					<param name="answer" type="content">
+						<item type="image" isRef="True">кот.png</item>
+						<item type="video" isRef="True">dog.mp4</item>
+					</param>
+				</params>
+				<right><answer>Cat</answer></right>
+			</question>
+			<question price="200">
+				<params>
+					<param name="question" type="content">
+						<item type="image">https://example.com/bird.jpg</item>
+					</param>
+					<param name="answerOptions" type="group">
+						<param name="A" type="content">
+							<item type="image" isRef="True">logo.png</item>
+						</param>
+					</param>
+				</params>
+				<right><answer>A</answer></right>
+			</question>
+		</theme>
+	</round>
+</package>`
+
+	inv := buildInventory(t, contentXML, map[string]string{
+		"Images/logo.png":    "logo",
+		"images/кот.png":     "cat image",
+		"Audio/meow.mp3":     "meow",
+		"Audio/unused.mp3":   "unused audio",
+		"Images/dog.mp4":     "dog video",
+		"Texts/authors.json": "not media",
+	})
+
+	if len(inv.Files) != 5 {
+		t.Fatalf("Expected 5 media files, got %d: %+v", len(inv.Files), inv.Files)
+	}
+
+	cat := findAsset(inv.Files, "Images/кот.png")
+	if cat == nil {
+		t.Fatal("Expected lowercase folder to be matched as Images")
+	}
+	if cat.Entry != "images/кот.png" || len(cat.Uses) != 2 {
+		t.Errorf("Expected encoded and plain references to the same entry, got %+v", cat)
+	}
+	if cat.Uses[0].Param != "question" || cat.Uses[1].Param != "answer" || cat.Uses[0].Ref.ID() != "r1/t1/q1" {
+		t.Errorf("Expected uses in question and answer of r1/t1/q1, got %v", cat.Uses)
+	}
+
+	logo := findAsset(inv.Files, "Images/logo.png")
+	if logo == nil || len(logo.Uses) != 2 || logo.Uses[0].Ref != nil || logo.Uses[1].Param != "answerOptions/A" {
+		t.Errorf("Expected logo to be used by the package and an answer option, got %+v", logo)
+	}
+
+	unreferenced := inv.Unreferenced()
+	if len(unreferenced) != 2 || unreferenced[0].Path != "Audio/unused.mp3" || unreferenced[1].Path != "Images/dog.mp4" {
+		t.Errorf("Expected unused.mp3 and dog.mp4 to be unreferenced, got %+v", unreferenced)
+	}
+
+	if len(inv.Missing) != 1 || inv.Missing[0].Path != "Video/dog.mp4" || inv.Missing[0].Hint != "Images/dog.mp4" {
+		t.Errorf("Expected Video/dog.mp4 to be missing with a hint, got %+v", inv.Missing)
+	}
+
+	if len(inv.External) != 1 || inv.External[0].Path != "https://example.com/bird.jpg" || inv.External[0].Uses[0].Ref.ID() != "r1/t1/q2" {
+		t.Errorf("Expected one external image, got %+v", inv.External)
+	}
+
+	expected := []TypeStats{
+		{Type: siq.ContentTypeImage, Files: 3, Size: int64(len("logo") + len("cat image") + len("dog video")), Unreferenced: 1},
+		{Type: siq.ContentTypeAudio, Files: 2, Size: int64(len("meow") + len("unused audio")), Unreferenced: 1},
+	}
+	if len(inv.Types) != len(expected) {
+		t.Fatalf("Expected %d types, got %+v", len(expected), inv.Types)
+	}
+	for i := range expected {
+		if inv.Types[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], inv.Types[i])
+		}
+	}
+}
+
+func TestBuildV4(t *testing.T) {
+	contentXML := `<?xml version="1.0" encoding="utf-8"?>
+<package name="V4 Package" version="4" id="v4-package" xmlns="http://vladimirkhil.com/ygpackage3.0.xsd">
+	<rounds>
+		<round name="Round 1">
+			<themes>
+				<theme name="Theme">
+					<questions>
+						<question price="100">
+							<scenario>
+								<atom>Listen</atom>
+								<atom type="voice">@song%201.mp3</atom>
+								<atom type="image">http://example.com/a.png</atom>
+							</scenario>
+							<right>
+								<answer>Answer</answer>
+							</right>
+						</question>
+					</questions>
+				</theme>
+			</themes>
+		</round>
+	</rounds>
+</package>`
+
+	inv := buildInventory(t, contentXML, map[string]string{
+		"Audio/song 1.mp3": "song",
+	})
+
+	if len(inv.Files) != 1 || len(inv.Files[0].Uses) != 1 {
+		t.Errorf("Expected the voice atom to reference Audio/song 1.mp3, got %+v", inv.Files)
+	}
+	if len(inv.Missing) != 0 {
+		t.Errorf("Expected no missing files, got %+v", inv.Missing)
+	}
+	if len(inv.External) != 1 || inv.External[0].Path != "http://example.com/a.png" {
+		t.Errorf("Expected one external image, got %+v", inv.External)
+	}
+}
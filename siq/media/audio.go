@@ -0,0 +1,286 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// mp3Frame is a parsed MPEG audio frame header
+type mp3Frame struct {
+	version    int // 1, 2 or 25 for MPEG 2.5
+	layer      int
+	bitrate    int // bits per second
+	sampleRate int
+	padding    int
+	mono       bool
+}
+
+// MPEG audio bitrates in kbps by version and layer, index 0 is "free"
+var (
+	bitratesV1 = [3][15]int{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	}
+	bitratesV2 = [3][15]int{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	}
+	sampleRates = map[int][3]int{
+		1:  {44100, 48000, 32000},
+		2:  {22050, 24000, 16000},
+		25: {11025, 12000, 8000},
+	}
+)
+
+// parseFrameHeader parses the MPEG audio frame header at the start of data
+func parseFrameHeader(data []byte) (mp3Frame, bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+
+	var frame mp3Frame
+	switch (data[1] >> 3) & 3 {
+	case 0:
+		frame.version = 25
+	case 2:
+		frame.version = 2
+	case 3:
+		frame.version = 1
+	default:
+		return mp3Frame{}, false
+	}
+	frame.layer = 4 - int((data[1]>>1)&3)
+	bitrateIndex := int(data[2] >> 4)
+	rateIndex := int((data[2] >> 2) & 3)
+	if frame.layer == 4 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return mp3Frame{}, false
+	}
+
+	if frame.version == 1 {
+		frame.bitrate = bitratesV1[frame.layer-1][bitrateIndex] * 1000
+	} else {
+		frame.bitrate = bitratesV2[frame.layer-1][bitrateIndex] * 1000
+	}
+	frame.sampleRate = sampleRates[frame.version][rateIndex]
+	frame.padding = int((data[2] >> 1) & 1)
+	frame.mono = data[3]>>6 == 3
+	return frame, true
+}
+
+// samples returns the number of samples per frame
+func (f mp3Frame) samples() int {
+	switch {
+	case f.layer == 1:
+		return 384
+	case f.layer == 3 && f.version != 1:
+		return 576
+	}
+	return 1152
+}
+
+// size returns the frame length in bytes, including the header
+func (f mp3Frame) size() int {
+	if f.layer == 1 {
+		return (12*f.bitrate/f.sampleRate + f.padding) * 4
+	}
+	return f.samples()/8*f.bitrate/f.sampleRate + f.padding
+}
+
+// sideInfoSize returns the length of the layer 3 side information
+func (f mp3Frame) sideInfoSize() int {
+	switch {
+	case f.version == 1 && !f.mono:
+		return 32
+	case f.version == 1, !f.mono:
+		return 17
+	}
+	return 9
+}
+
+// mp3Window is how far after the ID3v2 tag the first MPEG audio frame is searched
+const mp3Window = 64 << 10
+
+// probeMP3 skips the ID3v2 tag, finds the first frame and computes the
+// duration from the Xing or VBRI header of VBR files or from the bitrate
+// of the first frame for CBR files
+func probeMP3(src *source, info *Info) error {
+	head, err := src.read(0, 10)
+	if err != nil {
+		return err
+	}
+	offset := int64(0)
+	if bytes.HasPrefix(head, []byte("ID3")) && len(head) >= 10 {
+		size := int64(head[6]&0x7F)<<21 | int64(head[7]&0x7F)<<14 | int64(head[8]&0x7F)<<7 | int64(head[9]&0x7F)
+		offset = 10 + size
+		if head[5]&0x10 != 0 {
+			offset += 10
+		}
+	}
+
+	data, err := src.read(offset, mp3Window)
+	if err != nil {
+		return err
+	}
+
+	// Require two consecutive frames to avoid false syncs in garbage data
+	var frame mp3Frame
+	start := 0
+	for ; start+4 <= len(data); start++ {
+		var ok bool
+		if frame, ok = parseFrameHeader(data[start:]); !ok {
+			continue
+		}
+		next := start + frame.size()
+		if next+4 > len(data) {
+			break
+		}
+		if _, ok := parseFrameHeader(data[next:]); ok {
+			break
+		}
+	}
+	if start+4 > len(data) {
+		return errors.New("no MPEG audio frame found")
+	}
+
+	audioSize := src.size - offset - int64(start)
+	tag, err := src.read(src.size-128, 3)
+	if err != nil {
+		return err
+	}
+	if string(tag) == "TAG" {
+		audioSize -= 128
+	}
+	info.Bitrate = frame.bitrate
+
+	if frames := vbrFrames(data[start:], frame); frames > 0 {
+		info.Duration = seconds(int64(frames)*int64(frame.samples()), int64(frame.sampleRate))
+		info.Bitrate = int(float64(audioSize) * 8 / info.Duration.Seconds())
+		return nil
+	}
+	info.Duration = seconds(audioSize*8, int64(frame.bitrate))
+	return nil
+}
+
+// vbrFrames returns the frame count stored in the Xing, Info or VBRI
+// header of the first frame, or 0 when there is none
+func vbrFrames(data []byte, frame mp3Frame) int {
+	xing := 4 + frame.sideInfoSize()
+	if len(data) >= xing+12 {
+		tag := string(data[xing : xing+4])
+		flags := binary.BigEndian.Uint32(data[xing+4:])
+		if (tag == "Xing" || tag == "Info") && flags&1 != 0 {
+			return int(binary.BigEndian.Uint32(data[xing+8:]))
+		}
+	}
+	const vbri = 4 + 32
+	if len(data) >= vbri+18 && string(data[vbri:vbri+4]) == "VBRI" {
+		return int(binary.BigEndian.Uint32(data[vbri+14:]))
+	}
+	return 0
+}
+
+// oggMaxPage is the largest possible Ogg page: a header with 255 segments of 255 bytes
+const oggMaxPage = 27 + 255 + 255*255
+
+// probeOGG reads the sample rate from the Vorbis or Opus identification
+// header of the first page and the duration from the granule position of
+// the last page
+func probeOGG(src *source, info *Info) error {
+	data, err := src.read(0, oggMaxPage)
+	if err != nil {
+		return err
+	}
+	if len(data) < 27 {
+		return errors.New("truncated page header")
+	}
+	segments := int(data[26])
+	payloadStart := 27 + segments
+	if len(data) < payloadStart {
+		return errors.New("truncated segment table")
+	}
+	serial := binary.LittleEndian.Uint32(data[14:])
+	payload := data[payloadStart:]
+
+	var rate, preSkip int64
+	switch {
+	case bytes.HasPrefix(payload, []byte("\x01vorbis")) && len(payload) >= 24:
+		rate = int64(binary.LittleEndian.Uint32(payload[12:]))
+		info.Bitrate = int(int32(binary.LittleEndian.Uint32(payload[20:])))
+	case bytes.HasPrefix(payload, []byte("OpusHead")) && len(payload) >= 12:
+		// Opus granule positions always count 48 kHz samples
+		rate = 48000
+		preSkip = int64(binary.LittleEndian.Uint16(payload[10:]))
+	default:
+		return errors.New("unsupported codec")
+	}
+
+	// Find the last page of the same stream with a granule position, the
+	// last two pages are enough in case the very last one has none
+	tail, err := src.read(max(0, src.size-2*oggMaxPage), 2*oggMaxPage)
+	if err != nil {
+		return err
+	}
+	for end := len(tail); end > 0; {
+		index := bytes.LastIndex(tail[:end], []byte("OggS"))
+		if index < 0 {
+			break
+		}
+		end = index
+		page := tail[index:]
+		if len(page) < 27 || binary.LittleEndian.Uint32(page[14:]) != serial {
+			continue
+		}
+		granule := int64(binary.LittleEndian.Uint64(page[6:]))
+		if granule <= 0 {
+			continue
+		}
+		info.Duration = seconds(granule-preSkip, rate)
+		break
+	}
+	if info.Bitrate < 0 {
+		info.Bitrate = 0
+	}
+	return nil
+}
+
+// probeWAV reads the format and data chunks of a RIFF WAVE file
+func probeWAV(src *source, info *Info) error {
+	var byteRate, dataSize int64
+	for offset := int64(12); offset+8 <= src.size; {
+		header, err := src.read(offset, 8)
+		if err != nil {
+			return err
+		}
+		id := string(header[:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:]))
+		remaining := src.size - offset - 8
+		switch id {
+		case "fmt ":
+			body, err := src.read(offset+8, 16)
+			if err != nil {
+				return err
+			}
+			if len(body) < 16 {
+				return errors.New("truncated fmt chunk")
+			}
+			byteRate = int64(binary.LittleEndian.Uint32(body[8:]))
+		case "data":
+			// Streamed files may declare a larger size than written
+			dataSize = min(size, remaining)
+		}
+		if id == "data" || size >= remaining {
+			break
+		}
+		offset += 8 + size + size&1
+	}
+	if byteRate == 0 {
+		return errors.New("missing fmt chunk")
+	}
+
+	info.Duration = seconds(dataSize, byteRate)
+	info.Bitrate = int(byteRate * 8)
+	return nil
+}
@@ -0,0 +1,112 @@
+package media
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// Kinds of problems reported by CheckFile and CheckItem
+const (
+	IssueUnknown   = "unknown"
+	IssueExtension = "extension"
+	IssueType      = "type"
+	IssueDuration  = "duration"
+)
+
+// DurationTolerance is the difference between a declared and an actual
+// duration that is not reported. Longer files may differ by up to 5%.
+const DurationTolerance = time.Second
+
+// Issue is a mismatch between a media file and its name or use
+type Issue struct {
+	Kind    string
+	Message string
+}
+
+func (i Issue) String() string {
+	return i.Kind + ": " + i.Message
+}
+
+// extensionFormats maps file extensions to the formats they stand for
+var extensionFormats = map[string]string{
+	".png":  FormatPNG,
+	".jpg":  FormatJPEG,
+	".jpeg": FormatJPEG,
+	".jpe":  FormatJPEG,
+	".gif":  FormatGIF,
+	".webp": FormatWebP,
+	".mp3":  FormatMP3,
+	".ogg":  FormatOGG,
+	".oga":  FormatOGG,
+	".opus": FormatOGG,
+	".wav":  FormatWAV,
+	".mp4":  FormatMP4,
+	".m4a":  FormatMP4,
+	".m4v":  FormatMP4,
+	".mov":  FormatMP4,
+	".webm": FormatWebM,
+	".mkv":  FormatWebM,
+}
+
+// CheckFile reports files of an unknown format and files whose extension
+// does not match their content, for example a ".png" that is really JPEG.
+// Files with unfamiliar extensions are only checked for a known format.
+func CheckFile(filePath string, info Info) []Issue {
+	if info.Format == FormatUnknown {
+		if siq.MediaType(filePath) == siq.ContentTypeHtml {
+			return nil
+		}
+		return []Issue{{Kind: IssueUnknown, Message: "content is not a known media format"}}
+	}
+
+	ext := strings.ToLower(path.Ext(filePath))
+	if expected, ok := extensionFormats[ext]; ok && expected != info.Format {
+		return []Issue{{
+			Kind:    IssueExtension,
+			Message: fmt.Sprintf("%s file has %s content", ext, info.Format),
+		}}
+	}
+	return nil
+}
+
+// CheckItem reports mismatches between a content item and the file it
+// refers to: an item type that does not match the file content, such as an
+// audio file used as an image, and a declared duration that differs from
+// the actual one
+func CheckItem(item siq.ContentItem, info Info) []Issue {
+	var issues []Issue
+
+	itemType := item.GetType()
+	if itemType == siq.ContentTypeVoice {
+		itemType = siq.ContentTypeAudio
+	}
+	// Video containers are also used for audio and the other way around
+	compatible := itemType == info.ContentType ||
+		(info.Format == FormatMP4 || info.Format == FormatWebM) && itemType == siq.ContentTypeAudio
+	if info.ContentType != "" && itemType != siq.ContentTypeHtml && !compatible {
+		issues = append(issues, Issue{
+			Kind:    IssueType,
+			Message: fmt.Sprintf("%s item refers to %s content (%s)", item.GetType(), info.ContentType, info.Format),
+		})
+	}
+
+	if item.Duration > 0 && info.Duration > 0 {
+		declared := time.Duration(item.Duration) * time.Second
+		diff := declared - info.Duration
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > max(DurationTolerance, info.Duration/20) {
+			issues = append(issues, Issue{
+				Kind: IssueDuration,
+				Message: fmt.Sprintf("declared duration %s, actual %s",
+					declared, info.Duration.Round(100*time.Millisecond)),
+			})
+		}
+	}
+	return issues
+}
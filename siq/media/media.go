@@ -0,0 +1,240 @@
+// Package media detects the actual format of media files by their content
+// and reads image dimensions, durations and bitrates from container headers
+package media
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"time"
+
+	_ "golang.org/x/image/webp"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// Formats detected by Probe
+const (
+	FormatPNG     = "png"
+	FormatJPEG    = "jpeg"
+	FormatGIF     = "gif"
+	FormatWebP    = "webp"
+	FormatMP3     = "mp3"
+	FormatOGG     = "ogg"
+	FormatWAV     = "wav"
+	FormatMP4     = "mp4"
+	FormatWebM    = "webm"
+	FormatUnknown = ""
+)
+
+// Info describes the content of a media file
+type Info struct {
+	Format string
+	// ContentType is the content type matching the format, for example
+	// siq.ContentTypeImage, or empty for unknown formats
+	ContentType string
+	// Width and Height are the dimensions of images and video tracks
+	Width  int
+	Height int
+	// Duration is the play time of audio and video, 0 when unknown
+	Duration time.Duration
+	// Bitrate is the average bitrate in bits per second, 0 when unknown
+	Bitrate int
+}
+
+// String returns a short human-readable description of the file
+func (i Info) String() string {
+	if i.Format == FormatUnknown {
+		return "unknown format"
+	}
+	text := i.Format
+	if i.Width > 0 && i.Height > 0 {
+		text += fmt.Sprintf(" %dx%d", i.Width, i.Height)
+	}
+	if i.Duration > 0 {
+		text += " " + i.Duration.Round(100*time.Millisecond).String()
+	}
+	if i.Bitrate > 0 {
+		text += fmt.Sprintf(" %d kbps", i.Bitrate/1000)
+	}
+	return text
+}
+
+// Probe detects the format of data and reads its metadata. Unknown formats
+// are not an error, they are returned with an empty Format. An error is
+// returned when data looks like a known format but its headers are broken.
+func Probe(data []byte) (Info, error) {
+	return ProbeReader(bytes.NewReader(data), int64(len(data)))
+}
+
+// ProbeReader is like Probe but reads only the parts of the size bytes of r
+// it needs: the headers at the start and, depending on the format, the last
+// page or tag at the end or the movie box of MP4 files
+func ProbeReader(r io.ReaderAt, size int64) (Info, error) {
+	src := &source{r: r, size: size}
+	head, err := src.read(0, sniffSize)
+	if err != nil {
+		return Info{}, err
+	}
+	info := Info{Format: Sniff(head)}
+	info.ContentType = contentType(info.Format)
+
+	switch info.Format {
+	case FormatPNG, FormatJPEG, FormatGIF, FormatWebP:
+		var config image.Config
+		config, _, err = image.DecodeConfig(io.NewSectionReader(r, 0, size))
+		info.Width, info.Height = config.Width, config.Height
+	case FormatMP3:
+		err = probeMP3(src, &info)
+	case FormatOGG:
+		err = probeOGG(src, &info)
+	case FormatWAV:
+		err = probeWAV(src, &info)
+	case FormatMP4:
+		err = probeMP4(src, &info)
+	}
+	if err != nil {
+		return info, fmt.Errorf("invalid %s file: %w", info.Format, err)
+	}
+
+	if info.Bitrate == 0 && info.Duration > 0 {
+		info.Bitrate = int(float64(size) * 8 / info.Duration.Seconds())
+	}
+	return info, nil
+}
+
+// ProbeFile probes an archive file without reading it whole. Stored files
+// are read in place, compressed files are decompressed only up to the
+// parts the probe needs.
+func ProbeFile(file *zip.File) (Info, error) {
+	size := int64(file.UncompressedSize64)
+	if file.Method == zip.Store {
+		if raw, err := file.OpenRaw(); err == nil {
+			if r, ok := raw.(io.ReaderAt); ok {
+				return ProbeReader(r, size)
+			}
+		}
+	}
+
+	r := &entryReader{file: file}
+	defer r.Close()
+	return ProbeReader(r, size)
+}
+
+// sniffSize is the length of the prefix Sniff needs to detect all formats
+const sniffSize = 64
+
+// source is a file being probed
+type source struct {
+	r    io.ReaderAt
+	size int64
+}
+
+// read returns up to n bytes at offset, fewer at the end of the file
+func (s *source) read(offset, n int64) ([]byte, error) {
+	if offset < 0 || offset >= s.size {
+		return nil, nil
+	}
+	buf := make([]byte, min(n, s.size-offset))
+	read, err := s.r.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf[:read], nil
+}
+
+// entryReader reads a compressed archive file at arbitrary offsets by
+// decompressing it forward, reopening it to go back
+type entryReader struct {
+	file *zip.File
+	rc   io.ReadCloser
+	pos  int64
+}
+
+func (e *entryReader) ReadAt(p []byte, offset int64) (int, error) {
+	if e.rc == nil || offset < e.pos {
+		e.Close()
+		rc, err := e.file.Open()
+		if err != nil {
+			return 0, err
+		}
+		e.rc, e.pos = rc, 0
+	}
+	if _, err := io.CopyN(io.Discard, e.rc, offset-e.pos); err != nil {
+		e.Close()
+		return 0, err
+	}
+	e.pos = offset
+
+	read, err := io.ReadFull(e.rc, p)
+	e.pos += int64(read)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return read, err
+}
+
+// Close closes the decompressing reader
+func (e *entryReader) Close() error {
+	if e.rc == nil {
+		return nil
+	}
+	err := e.rc.Close()
+	e.rc = nil
+	return err
+}
+
+// Sniff returns the format of data by its signature, or FormatUnknown
+func Sniff(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return FormatPNG
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return FormatJPEG
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return FormatGIF
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return FormatWebP
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+		return FormatWAV
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return FormatOGG
+	case len(data) >= 8 && string(data[4:8]) == "ftyp":
+		return FormatMP4
+	case bytes.HasPrefix(data, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return FormatWebM
+	case bytes.HasPrefix(data, []byte("ID3")):
+		return FormatMP3
+	}
+	if _, ok := parseFrameHeader(data); ok {
+		return FormatMP3
+	}
+	return FormatUnknown
+}
+
+// contentType returns the content type of files in a format. MP4 files
+// are reported as video until their tracks are read.
+func contentType(format string) string {
+	switch format {
+	case FormatPNG, FormatJPEG, FormatGIF, FormatWebP:
+		return siq.ContentTypeImage
+	case FormatMP3, FormatOGG, FormatWAV:
+		return siq.ContentTypeAudio
+	case FormatMP4, FormatWebM:
+		return siq.ContentTypeVideo
+	}
+	return ""
+}
+
+// seconds converts a number of samples at a sample rate to a duration
+func seconds(samples int64, rate int64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(samples) / float64(rate) * float64(time.Second))
+}
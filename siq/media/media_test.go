@@ -0,0 +1,279 @@
+package media
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+func encodeImage(t *testing.T, format string, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case FormatPNG:
+		err = png.Encode(&buf, img)
+	case FormatJPEG:
+		err = jpeg.Encode(&buf, img, nil)
+	case FormatGIF:
+		err = gif.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatal("Failed to encode image:", err)
+	}
+	return buf.Bytes()
+}
+
+// createWAV returns a mono 16-bit WAV file of the given length
+func createWAV(rate int, seconds int) []byte {
+	dataSize := rate * 2 * seconds
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Size             uint32
+		Format, Channels uint16
+		Rate, ByteRate   uint32
+		BlockAlign, Bits uint16
+	}{16, 1, 1, uint32(rate), uint32(rate * 2), 2, 16})
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
+	buf.Write(make([]byte, dataSize))
+	return buf.Bytes()
+}
+
+// createMP3 returns an ID3 tag followed by MPEG 1 layer 3 frames at
+// 128 kbps and 44.1 kHz. The first frame holds a Xing header when
+// xingFrames is positive.
+func createMP3(frames int, xingFrames int) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 0, 10})
+	buf.Write(make([]byte, 10))
+	for i := range frames {
+		frame := make([]byte, 417)
+		copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+		if i == 0 && xingFrames > 0 {
+			copy(frame[36:], "Xing")
+			binary.BigEndian.PutUint32(frame[40:], 1)
+			binary.BigEndian.PutUint32(frame[44:], uint32(xingFrames))
+		}
+		buf.Write(frame)
+	}
+	return buf.Bytes()
+}
+
+// oggPage returns an Ogg page with a single segment
+func oggPage(granule int64, payload []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("OggS")
+	buf.Write([]byte{0, 0})
+	binary.Write(&buf, binary.LittleEndian, granule)
+	binary.Write(&buf, binary.LittleEndian, []uint32{7, 0, 0})
+	buf.Write([]byte{1, byte(len(payload))})
+	buf.Write(payload)
+	return buf.Bytes()
+}
+
+func createOGG(header []byte, lastGranule int64) []byte {
+	data := oggPage(0, header)
+	data = append(data, oggPage(lastGranule/2, make([]byte, 200))...)
+	return append(data, oggPage(lastGranule, make([]byte, 100))...)
+}
+
+// box returns an ISO base media box
+func box(boxType string, parts ...[]byte) []byte {
+	body := bytes.Join(parts, nil)
+	header := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(header, boxType...), body...)
+}
+
+func createMP4(handler string, width, height int, timescale, duration uint32) []byte {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], timescale)
+	binary.BigEndian.PutUint32(mvhd[16:], duration)
+
+	tkhd := make([]byte, 84)
+	binary.BigEndian.PutUint32(tkhd[76:], uint32(width)<<16)
+	binary.BigEndian.PutUint32(tkhd[80:], uint32(height)<<16)
+
+	hdlr := make([]byte, 24)
+	copy(hdlr[8:], handler)
+
+	return bytes.Join([][]byte{
+		box("ftyp", []byte("isom\x00\x00\x02\x00isom")),
+		box("moov", box("mvhd", mvhd), box("trak", box("tkhd", tkhd), box("mdia", box("hdlr", hdlr)))),
+		box("mdat", make([]byte, 1000)),
+	}, nil)
+}
+
+func TestProbe(t *testing.T) {
+	vorbis := append([]byte("\x01vorbis\x00\x00\x00\x00\x01"), binary.LittleEndian.AppendUint32(nil, 44100)...)
+	vorbis = append(vorbis, make([]byte, 16)...)
+	opus := []byte("OpusHead\x01\x02\x38\x01\x80\xbb\x00\x00\x00\x00\x00")
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected Info
+	}{
+		{"png", encodeImage(t, FormatPNG, 30, 20), Info{Format: FormatPNG, ContentType: siq.ContentTypeImage, Width: 30, Height: 20}},
+		{"jpeg", encodeImage(t, FormatJPEG, 16, 8), Info{Format: FormatJPEG, ContentType: siq.ContentTypeImage, Width: 16, Height: 8}},
+		{"gif", encodeImage(t, FormatGIF, 5, 7), Info{Format: FormatGIF, ContentType: siq.ContentTypeImage, Width: 5, Height: 7}},
+		{"wav", createWAV(8000, 2), Info{Format: FormatWAV, ContentType: siq.ContentTypeAudio, Duration: 2 * time.Second, Bitrate: 128000}},
+		{"mp3 cbr", createMP3(100, 0), Info{Format: FormatMP3, ContentType: siq.ContentTypeAudio, Duration: 2606250 * time.Microsecond, Bitrate: 128000}},
+		{"vorbis", createOGG(vorbis, 88200), Info{Format: FormatOGG, ContentType: siq.ContentTypeAudio, Duration: 2 * time.Second}},
+		{"opus", createOGG(opus, 96312), Info{Format: FormatOGG, ContentType: siq.ContentTypeAudio, Duration: 2 * time.Second}},
+		{"mp4 video", createMP4("vide", 640, 360, 1000, 5000), Info{Format: FormatMP4, ContentType: siq.ContentTypeVideo, Width: 640, Height: 360, Duration: 5 * time.Second}},
+		{"mp4 audio", createMP4("soun", 0, 0, 44100, 441000), Info{Format: FormatMP4, ContentType: siq.ContentTypeAudio, Duration: 10 * time.Second}},
+		{"unknown", []byte("<html></html>"), Info{}},
+	}
+
+	for _, test := range tests {
+		info, err := Probe(test.data)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		// Bitrates derived from the file size are not checked
+		if test.expected.Bitrate == 0 {
+			info.Bitrate = 0
+		}
+		if info != test.expected {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, info)
+		}
+	}
+}
+
+func TestProbeMP3Xing(t *testing.T) {
+	info, err := Probe(createMP3(10, 1000))
+	if err != nil {
+		t.Fatal("Failed to probe MP3:", err)
+	}
+	expected := seconds(1000*1152, 44100)
+	if info.Duration != expected {
+		t.Errorf("Expected duration %s from Xing header, got %s", expected, info.Duration)
+	}
+}
+
+func TestProbeBrokenHeaders(t *testing.T) {
+	if _, err := Probe([]byte("RIFF\x00\x00\x00\x00WAVEdata")); err == nil {
+		t.Error("Expected error for WAV without fmt chunk")
+	}
+	if _, err := Probe(box("ftyp", []byte("isom"))); err == nil {
+		t.Error("Expected error for MP4 without moov box")
+	}
+}
+
+// countingReader counts the bytes read from the underlying reader
+type countingReader struct {
+	r    io.ReaderAt
+	read int
+}
+
+func (c *countingReader) ReadAt(p []byte, offset int64) (int, error) {
+	n, err := c.r.ReadAt(p, offset)
+	c.read += n
+	return n, err
+}
+
+func TestProbeReaderReadsHeadersOnly(t *testing.T) {
+	// The movie box follows a large media data box, as in files not optimized for streaming
+	mp4 := createMP4("vide", 640, 360, 1000, 5000)
+	ftyp := box("ftyp", []byte("isom\x00\x00\x02\x00isom"))
+	moov := mp4[len(ftyp) : len(mp4)-len(box("mdat", make([]byte, 1000)))]
+	data := bytes.Join([][]byte{ftyp, box("mdat", make([]byte, 4<<20)), moov}, nil)
+
+	r := &countingReader{r: bytes.NewReader(data)}
+	info, err := ProbeReader(r, int64(len(data)))
+	if err != nil {
+		t.Fatal("Failed to probe MP4:", err)
+	}
+	if info.Width != 640 || info.Duration != 5*time.Second {
+		t.Errorf("Unexpected MP4 info %+v", info)
+	}
+	if r.read > 1024 {
+		t.Errorf("Expected only box headers and the movie box to be read, got %d bytes", r.read)
+	}
+}
+
+func TestProbeFile(t *testing.T) {
+	wav := createWAV(8000, 2)
+
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	for _, method := range []uint16{zip.Store, zip.Deflate} {
+		writer, err := zipWriter.CreateHeader(&zip.FileHeader{Name: fmt.Sprintf("Audio/%d.wav", method), Method: method})
+		if err != nil {
+			t.Fatal("Failed to create file in zip:", err)
+		}
+		writer.Write(wav)
+	}
+	zipWriter.Close()
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal("Failed to open zip:", err)
+	}
+	for _, file := range archive.File {
+		info, err := ProbeFile(file)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", file.Name, err)
+			continue
+		}
+		if info.Format != FormatWAV || info.Duration != 2*time.Second {
+			t.Errorf("%s: unexpected info %+v", file.Name, info)
+		}
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	jpegInfo := Info{Format: FormatJPEG, ContentType: siq.ContentTypeImage}
+
+	if issues := CheckFile("Images/cat.png", jpegInfo); len(issues) != 1 || issues[0].Kind != IssueExtension {
+		t.Errorf("Expected extension issue for JPEG named .png, got %v", issues)
+	}
+	if issues := CheckFile("Images/cat.JPG", jpegInfo); len(issues) != 0 {
+		t.Errorf("Expected no issues for JPEG named .JPG, got %v", issues)
+	}
+	if issues := CheckFile("Audio/song.mp3", Info{}); len(issues) != 1 || issues[0].Kind != IssueUnknown {
+		t.Errorf("Expected unknown format issue, got %v", issues)
+	}
+	if issues := CheckFile("Html/page.html", Info{}); len(issues) != 0 {
+		t.Errorf("Expected no issues for HTML files, got %v", issues)
+	}
+}
+
+func TestCheckItem(t *testing.T) {
+	audio := Info{Format: FormatMP3, ContentType: siq.ContentTypeAudio, Duration: 30 * time.Second}
+
+	issues := CheckItem(siq.ContentItem{Type: siq.ContentTypeImage, IsRef: true, Value: "song.mp3"}, audio)
+	if len(issues) != 1 || issues[0].Kind != IssueType {
+		t.Errorf("Expected type issue for audio used as image, got %v", issues)
+	}
+
+	issues = CheckItem(siq.ContentItem{Type: siq.ContentTypeVoice, IsRef: true, Value: "song.mp3", Duration: 31}, audio)
+	if len(issues) != 0 {
+		t.Errorf("Expected no issues for voice item within tolerance, got %v", issues)
+	}
+
+	issues = CheckItem(siq.ContentItem{Type: siq.ContentTypeAudio, IsRef: true, Value: "song.mp3", Duration: 10}, audio)
+	if len(issues) != 1 || issues[0].Kind != IssueDuration {
+		t.Errorf("Expected duration issue, got %v", issues)
+	}
+
+	video := Info{Format: FormatMP4, ContentType: siq.ContentTypeVideo}
+	if issues := CheckItem(siq.ContentItem{Type: siq.ContentTypeAudio, IsRef: true, Value: "clip.mp4"}, video); len(issues) != 0 {
+		t.Errorf("Expected MP4 to be accepted as audio, got %v", issues)
+	}
+}
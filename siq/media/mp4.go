@@ -0,0 +1,155 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// mp4Track is what probeMP4 needs to know about a track
+type mp4Track struct {
+	handler       string
+	width, height int
+}
+
+// boxHeader parses the header of an ISO base media box at the start of data
+// and returns the box type, the header length and the box size, where
+// remaining is the number of bytes from the box start to the end of the file
+func boxHeader(data []byte, remaining uint64) (string, uint64, uint64, error) {
+	if len(data) < 8 {
+		return "", 0, 0, errors.New("truncated box header")
+	}
+	size := uint64(binary.BigEndian.Uint32(data))
+	boxType := string(data[4:8])
+	header := uint64(8)
+	switch size {
+	case 0:
+		size = remaining
+	case 1:
+		if len(data) < 16 {
+			return "", 0, 0, errors.New("truncated box header")
+		}
+		size = binary.BigEndian.Uint64(data[8:])
+		header = 16
+	}
+	if size < header {
+		return "", 0, 0, errors.New("invalid box size")
+	}
+	// Truncated files are probed as far as they go
+	return boxType, header, min(size, remaining), nil
+}
+
+// eachBox calls fn for every ISO base media box in data with its type and body
+func eachBox(data []byte, fn func(boxType string, body []byte) error) error {
+	for len(data) > 0 {
+		boxType, header, size, err := boxHeader(data, uint64(len(data)))
+		if err != nil {
+			return err
+		}
+		if err := fn(boxType, data[header:size]); err != nil {
+			return err
+		}
+		data = data[size:]
+	}
+	return nil
+}
+
+// probeMP4 reads the duration from the movie header and the dimensions of
+// the first video track. Files without video tracks are reported as audio.
+// Top-level boxes are skipped by their headers, so only the movie box is read.
+func probeMP4(src *source, info *Info) error {
+	var moov []byte
+	for offset := int64(0); offset < src.size; {
+		data, err := src.read(offset, 16)
+		if err != nil {
+			return err
+		}
+		boxType, header, size, err := boxHeader(data, uint64(src.size-offset))
+		if err != nil {
+			return err
+		}
+		if boxType == "moov" {
+			if moov, err = src.read(offset+int64(header), int64(size-header)); err != nil {
+				return err
+			}
+			break
+		}
+		offset += int64(size)
+	}
+	if moov == nil {
+		return errors.New("missing moov box")
+	}
+
+	var tracks []mp4Track
+	err := eachBox(moov, func(boxType string, body []byte) error {
+		switch boxType {
+		case "mvhd":
+			return readMovieHeader(body, info)
+		case "trak":
+			track, err := readTrack(body)
+			tracks = append(tracks, track)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	info.ContentType = siq.ContentTypeAudio
+	for _, track := range tracks {
+		if track.handler == "vide" {
+			info.ContentType = siq.ContentTypeVideo
+			info.Width, info.Height = track.width, track.height
+			break
+		}
+	}
+	return nil
+}
+
+// readMovieHeader reads the time scale and duration of an mvhd box
+func readMovieHeader(body []byte, info *Info) error {
+	var timescale, duration uint64
+	switch {
+	case len(body) >= 20 && body[0] == 0:
+		timescale = uint64(binary.BigEndian.Uint32(body[12:]))
+		duration = uint64(binary.BigEndian.Uint32(body[16:]))
+	case len(body) >= 32 && body[0] == 1:
+		timescale = uint64(binary.BigEndian.Uint32(body[20:]))
+		duration = binary.BigEndian.Uint64(body[24:])
+	default:
+		return errors.New("invalid mvhd box")
+	}
+	info.Duration = seconds(int64(duration), int64(timescale))
+	return nil
+}
+
+// readTrack reads the handler type and the dimensions of a trak box
+func readTrack(body []byte) (mp4Track, error) {
+	var track mp4Track
+	err := eachBox(body, func(boxType string, body []byte) error {
+		switch boxType {
+		case "tkhd":
+			// Width and height are 16.16 fixed point numbers at the end of the box
+			offset := 76
+			if len(body) > 0 && body[0] == 1 {
+				offset = 88
+			}
+			if len(body) < offset+8 {
+				return errors.New("invalid tkhd box")
+			}
+			track.width = int(binary.BigEndian.Uint32(body[offset:]) >> 16)
+			track.height = int(binary.BigEndian.Uint32(body[offset+4:]) >> 16)
+		case "mdia":
+			return eachBox(body, func(boxType string, body []byte) error {
+				if boxType == "hdlr" && len(body) >= 12 {
+					track.handler = string(body[8:12])
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	return track, err
+}
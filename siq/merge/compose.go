@@ -0,0 +1,398 @@
+package merge
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minmaxmean/sigma/siq"
+	"github.com/minmaxmean/sigma/siq/search"
+	"github.com/minmaxmean/sigma/textnorm"
+)
+
+// idSelector matches question selectors in the "r1/t2/q3" form
+var idSelector = regexp.MustCompile(`^r(\d+)/t(\d+)/q(\d+)$`)
+
+// candidate is a question of a source package
+type candidate struct {
+	src      *source
+	ref      siq.QuestionRef
+	question *siq.Question
+}
+
+// key identifies the question across all sources
+func (c candidate) key() string {
+	return c.src.path + "#" + c.ref.ID()
+}
+
+// composer holds the state of a compose run
+type composer struct {
+	*merger
+	spec    *Spec
+	sources map[string]*source
+	used    map[string]bool
+	added   map[string]string // source path and file path to output path
+	rng     *rand.Rand
+}
+
+// Compose builds a package from questions of other packages as described by
+// the spec and writes it to output. Questions keep their content, answers and
+// attribution: authors and sources inherited from their theme, round or
+// package are copied to the question and global references are resolved.
+// Referenced media files are copied, colliding names are renamed.
+func Compose(spec *Spec, output string) (*Result, error) {
+	name := spec.Name
+	if name == "" {
+		name = "Composed package"
+	}
+
+	c := &composer{
+		merger: &merger{
+			pkg: &siq.Package{
+				ID:         siq.NewID(),
+				Name:       name,
+				Version:    "5",
+				Date:       time.Now().Format("02.01.2006"),
+				Publisher:  spec.Publisher,
+				Difficulty: spec.Difficulty,
+				Language:   spec.Language,
+			},
+			result: &Result{},
+			files:  make(map[string]string),
+		},
+		spec:    spec,
+		sources: make(map[string]*source),
+		used:    make(map[string]bool),
+		added:   make(map[string]string),
+	}
+	defer func() {
+		for _, src := range c.sources {
+			src.reader.Close()
+		}
+	}()
+	if spec.Seed != 0 {
+		c.rng = rand.New(rand.NewPCG(spec.Seed, 0))
+	}
+	if len(spec.Authors) > 0 {
+		c.pkg.Info = &siq.Info{Authors: spec.Authors}
+	}
+	if len(spec.Tags) > 0 {
+		c.pkg.Tags = &siq.Tags{Tags: spec.Tags}
+	}
+
+	for i, roundSpec := range spec.Rounds {
+		round := siq.Round{Name: roundSpec.Name, Type: roundSpec.Type}
+		for j, themeSpec := range roundSpec.Themes {
+			prices := themeSpec.Prices
+			if len(prices) == 0 {
+				prices = roundSpec.Prices
+			}
+
+			theme := siq.Theme{Name: themeSpec.Name}
+			for k, questionSpec := range themeSpec.Questions {
+				selected, err := c.selectQuestions(questionSpec)
+				if err != nil {
+					return nil, fmt.Errorf("round %d, theme %d, question %d: %w", i+1, j+1, k+1, err)
+				}
+				for _, cand := range selected {
+					price := questionSpec.Price
+					if price == 0 && len(theme.Questions) < len(prices) {
+						price = prices[len(theme.Questions)]
+					}
+					question, err := c.copyQuestion(cand, price)
+					if err != nil {
+						return nil, err
+					}
+					theme.Questions = append(theme.Questions, question)
+				}
+			}
+			round.Themes = append(round.Themes, theme)
+		}
+		c.pkg.Rounds = append(c.pkg.Rounds, round)
+	}
+
+	c.result.Rounds = c.pkg.GetRoundCount()
+	c.result.Questions = c.pkg.GetQuestionCount()
+	c.result.Files = len(c.copies)
+	return c.result, write(output, c.pkg, c.copies)
+}
+
+// open returns the source package at path, opening it on first use
+func (c *composer) open(path string) (*source, error) {
+	path = filepath.Clean(path)
+	if src, ok := c.sources[path]; ok {
+		return src, nil
+	}
+	src, err := openSource(path)
+	if err != nil {
+		return nil, err
+	}
+	c.sources[path] = src
+	return src, nil
+}
+
+// selectQuestions resolves a question spec to the questions it selects
+// and marks them as used
+func (c *composer) selectQuestions(spec QuestionSpec) ([]candidate, error) {
+	var selected []candidate
+	if spec.Ref != "" {
+		cand, err := c.resolveRef(spec.Ref)
+		if err != nil {
+			return nil, err
+		}
+		if c.used[cand.key()] {
+			return nil, fmt.Errorf("question %s is used twice", spec.Ref)
+		}
+		selected = []candidate{cand}
+	} else {
+		var err error
+		if selected, err = c.runQuery(spec.Query); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, cand := range selected {
+		c.used[cand.key()] = true
+	}
+	return selected, nil
+}
+
+// resolveRef finds a question by a "pack.siq#round/theme/price" or "pack.siq#r1/t2/q3" reference
+func (c *composer) resolveRef(ref string) (candidate, error) {
+	packPath, selector, found := strings.Cut(ref, "#")
+	if !found || selector == "" {
+		return candidate{}, fmt.Errorf("invalid reference %q, expected pack.siq#round/theme/price", ref)
+	}
+	src, err := c.open(c.spec.resolve(packPath))
+	if err != nil {
+		return candidate{}, err
+	}
+
+	if match := idSelector.FindStringSubmatch(selector); match != nil {
+		r, _ := strconv.Atoi(match[1])
+		t, _ := strconv.Atoi(match[2])
+		q, _ := strconv.Atoi(match[3])
+		if cand, ok := questionAt(src, r-1, t-1, q-1); ok {
+			return cand, nil
+		}
+		return candidate{}, fmt.Errorf("question %s not found", ref)
+	}
+
+	segments := strings.Split(selector, "/")
+	if len(segments) < 3 {
+		return candidate{}, fmt.Errorf("invalid reference %q, expected pack.siq#round/theme/price", ref)
+	}
+	price, err := strconv.Atoi(strings.TrimSpace(segments[len(segments)-1]))
+	if err != nil {
+		return candidate{}, fmt.Errorf("invalid price in reference %q", ref)
+	}
+	themeName := strings.TrimSpace(segments[len(segments)-2])
+	roundName := strings.TrimSpace(strings.Join(segments[:len(segments)-2], "/"))
+
+	var result candidate
+	found = false
+	src.pkg.Walk(func(questionRef siq.QuestionRef, question *siq.Question) error {
+		if !found && strings.EqualFold(questionRef.RoundName, roundName) &&
+			strings.EqualFold(questionRef.ThemeName, themeName) && questionRef.Price.Value == price {
+			result = candidate{src: src, ref: questionRef, question: question}
+			found = true
+		}
+		return nil
+	})
+	if !found {
+		return candidate{}, fmt.Errorf("question %s not found", ref)
+	}
+	return result, nil
+}
+
+// questionAt returns the question at zero-based indexes of a source package
+func questionAt(src *source, roundIndex, themeIndex, questionIndex int) (candidate, bool) {
+	rounds := src.pkg.Rounds
+	if roundIndex < 0 || roundIndex >= len(rounds) {
+		return candidate{}, false
+	}
+	themes := rounds[roundIndex].Themes
+	if themeIndex < 0 || themeIndex >= len(themes) {
+		return candidate{}, false
+	}
+	questions := themes[themeIndex].Questions
+	if questionIndex < 0 || questionIndex >= len(questions) {
+		return candidate{}, false
+	}
+
+	question := &questions[questionIndex]
+	return candidate{
+		src: src,
+		ref: siq.QuestionRef{
+			RoundIndex:    roundIndex,
+			RoundName:     rounds[roundIndex].Name,
+			ThemeIndex:    themeIndex,
+			ThemeName:     themes[themeIndex].Name,
+			QuestionIndex: questionIndex,
+			Price:         question.Price(),
+		},
+		question: question,
+	}, true
+}
+
+// runQuery selects unused questions of the pool matching the query
+func (c *composer) runQuery(query *Query) ([]candidate, error) {
+	pool := query.Pool
+	if len(pool) == 0 {
+		pool = c.spec.Pool
+	}
+	if len(pool) == 0 {
+		return nil, fmt.Errorf("query without a pool")
+	}
+	poolPaths := make([]string, len(pool))
+	for i, poolPath := range pool {
+		poolPaths[i] = c.spec.resolve(poolPath)
+	}
+	files, err := search.CollectFiles(poolPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	text := textnorm.Fold(query.Text)
+	var candidates []candidate
+	for _, file := range files {
+		src, err := c.open(file)
+		if err != nil {
+			return nil, err
+		}
+		if !matchesPackage(src.pkg, query) {
+			continue
+		}
+		src.pkg.Walk(func(ref siq.QuestionRef, question *siq.Question) error {
+			cand := candidate{src: src, ref: ref, question: question}
+			if !c.used[cand.key()] && matchesQuestion(question, query, text) {
+				candidates = append(candidates, cand)
+			}
+			return nil
+		})
+	}
+
+	if c.rng != nil {
+		c.rng.Shuffle(len(candidates), func(i, j int) {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		})
+	}
+
+	count := max(query.Count, 1)
+	if len(candidates) < count {
+		return nil, fmt.Errorf("query matched %d of %d required question(s)", len(candidates), count)
+	}
+	return candidates[:count], nil
+}
+
+// matchesPackage checks the package-level query filters
+func matchesPackage(pkg *siq.Package, query *Query) bool {
+	if query.MinDifficulty != 0 && pkg.Difficulty < query.MinDifficulty {
+		return false
+	}
+	if query.MaxDifficulty != 0 && pkg.Difficulty > query.MaxDifficulty {
+		return false
+	}
+	if len(query.Tags) == 0 {
+		return true
+	}
+	if pkg.Tags != nil {
+		for _, tag := range pkg.Tags.Tags {
+			for _, wanted := range query.Tags {
+				if strings.EqualFold(tag, wanted) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchesQuestion checks the question-level query filters, text is the folded query text
+func matchesQuestion(question *siq.Question, query *Query, text string) bool {
+	if query.Type != "" && question.Type != query.Type {
+		return false
+	}
+
+	hasMedia := false
+	var parts []string
+	for item := range question.ContentItems() {
+		if siq.MediaFolder(item.GetType()) != "" {
+			hasMedia = true
+		} else {
+			parts = append(parts, item.Value)
+		}
+	}
+	if query.HasMedia != nil && *query.HasMedia != hasMedia {
+		return false
+	}
+	return text == "" || strings.Contains(textnorm.Fold(strings.Join(parts, " ")), text)
+}
+
+// copyQuestion copies a question with its attribution and media into the composed package
+func (c *composer) copyQuestion(cand candidate, price int) (siq.Question, error) {
+	question := cand.question.Clone()
+	if price > 0 {
+		question.BasePrice = price
+	}
+
+	// Copy inherited attribution to the question and resolve global references
+	pkg := cand.src.pkg
+	round := &pkg.Rounds[cand.ref.RoundIndex]
+	theme := &round.Themes[cand.ref.ThemeIndex]
+	if question.Info == nil {
+		question.Info = &siq.Info{}
+	}
+	for _, info := range []*siq.Info{theme.Info, round.Info, pkg.Info} {
+		if info == nil {
+			continue
+		}
+		if len(question.Info.Authors) == 0 {
+			question.Info.Authors = append(question.Info.Authors, info.Authors...)
+		}
+		if len(question.Info.Sources) == 0 {
+			question.Info.Sources = append(question.Info.Sources, info.Sources...)
+		}
+	}
+	for _, values := range [][]string{question.Info.Authors, question.Info.Sources} {
+		for i, value := range values {
+			if resolved, err := pkg.ResolveReference(value); err == nil {
+				values[i] = resolved
+			}
+		}
+	}
+	if len(question.Info.Authors) == 0 && len(question.Info.Sources) == 0 &&
+		len(question.Info.Comments) == 0 && len(question.Info.ShowmanComments) == 0 {
+		question.Info = nil
+	}
+
+	for item := range question.ContentItems() {
+		itemPath := siq.ItemPath(*item)
+		if itemPath == "" {
+			continue
+		}
+		if cand.src.files[itemPath] == nil {
+			c.result.Missing = append(c.result.Missing, fmt.Sprintf("%s: %s (%s)", cand.src.path, itemPath, cand.ref.ID()))
+			continue
+		}
+
+		key := cand.src.path + "\x00" + itemPath
+		newPath, ok := c.added[key]
+		if !ok {
+			var err error
+			if newPath, err = c.addFile(cand.src, itemPath); err != nil {
+				return question, err
+			}
+			c.added[key] = newPath
+		}
+		if newPath != itemPath {
+			item.Value = path.Base(newPath)
+		}
+	}
+	return question, nil
+}
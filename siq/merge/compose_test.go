@@ -0,0 +1,143 @@
+package merge
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minmaxmean/sigma/internal/siqtest"
+)
+
+const composeSpecYAML = `name: Game Night
+language: en
+authors: [Host]
+pool: [packs]
+rounds:
+  - name: Warm-up
+    prices: [100, 200, 300]
+    themes:
+      - name: Animals
+        questions:
+          - ref: packs/a.siq#A1/Cats/100
+          - ref: packs/b.siq#r1/t1/q1
+          - query:
+              text: final
+  - name: Bonus
+    themes:
+      - name: Mixed
+        prices: [500]
+        questions:
+          - query:
+              hasMedia: true
+              tags: [music]
+            price: 1000
+`
+
+func writeComposeSpec(t *testing.T, dir, spec string) string {
+	specPath := filepath.Join(dir, "spec.yaml")
+	if err := os.WriteFile(specPath, []byte(spec), 0644); err != nil {
+		t.Fatal("Failed to write spec:", err)
+	}
+	return specPath
+}
+
+func createComposePool(t *testing.T) string {
+	dir := t.TempDir()
+	packs := filepath.Join(dir, "packs")
+	if err := os.Mkdir(packs, 0755); err != nil {
+		t.Fatal("Failed to create pool directory:", err)
+	}
+	siqtest.CreateFile(t, packs, "a.siq", packAXML, map[string]string{
+		"Images/cat.png":  "cat A",
+		"Images/logo.png": "logo",
+	})
+	siqtest.CreateFile(t, packs, "b.siq", packBXML, map[string]string{
+		"Images/cat.png":  "cat B",
+		"Images/logo.png": "logo",
+		"Audio/bark.mp3":  "bark",
+	})
+	return dir
+}
+
+func TestCompose(t *testing.T) {
+	dir := createComposePool(t)
+	spec, err := LoadSpec(writeComposeSpec(t, dir, composeSpecYAML))
+	if err != nil {
+		t.Fatal("Failed to load spec:", err)
+	}
+
+	output := filepath.Join(dir, "out.siq")
+	result, err := Compose(spec, output)
+	if err != nil {
+		t.Fatal("Failed to compose package:", err)
+	}
+	if result.Rounds != 2 || result.Questions != 4 {
+		t.Errorf("Unexpected compose result %+v", result)
+	}
+
+	pkg, files := readTestPackage(t, output)
+	if pkg.Name != "Game Night" || pkg.Language != "en" || pkg.Info.Authors[0] != "Host" {
+		t.Errorf("Unexpected package metadata %+v", pkg)
+	}
+
+	questions := pkg.Rounds[0].Themes[0].Questions
+	if len(questions) != 3 {
+		t.Fatalf("Expected 3 questions in the first theme, got %d", len(questions))
+	}
+	var prices []int
+	for _, question := range questions {
+		prices = append(prices, question.BasePrice)
+	}
+	if prices[0] != 100 || prices[1] != 200 || prices[2] != 300 {
+		t.Errorf("Expected assigned prices 100, 200, 300, got %v", prices)
+	}
+	if questions[2].Right[0] != "Yes" {
+		t.Errorf("Expected the query to select the final question, got %+v", questions[2])
+	}
+
+	// Attribution is inherited from the source package and resolved
+	if info := questions[0].Info; info == nil || info.Authors[0] != "Alice" || info.Sources[0] != "Encyclopedia p. 5" {
+		t.Errorf("Unexpected attribution of the first question %+v", questions[0].Info)
+	}
+	if info := questions[1].Info; info == nil || info.Authors[0] != "Alice" {
+		t.Errorf("Unexpected attribution of the second question %+v", questions[1].Info)
+	}
+
+	// Both cat.png files are copied, the second one renamed
+	if items := questions[1].GetQuestionContent(); items[0].Value != "cat_2.png" {
+		t.Errorf("Expected the reference to be rewritten, got %+v", items)
+	}
+	if files["Images/cat.png"] != "cat A" || files["Images/cat_2.png"] != "cat B" || files["Audio/bark.mp3"] != "bark" {
+		t.Errorf("Unexpected files %v", files)
+	}
+
+	// The media query skips the already used question of pack B
+	bonus := pkg.Rounds[1].Themes[0].Questions
+	if len(bonus) != 1 || bonus[0].BasePrice != 1000 || bonus[0].Right[0] != "Logo" {
+		t.Errorf("Unexpected bonus questions %+v", bonus)
+	}
+	if len(result.Missing) != 1 || !strings.Contains(result.Missing[0], "missing.png") {
+		t.Errorf("Expected missing.png to be reported, got %v", result.Missing)
+	}
+}
+
+func TestComposeErrors(t *testing.T) {
+	dir := createComposePool(t)
+
+	specs := map[string]string{
+		"both":      "rounds:\n  - themes:\n      - questions:\n          - ref: packs/a.siq#r1/t1/q1\n            query: {text: cat}\n",
+		"not found": "rounds:\n  - themes:\n      - questions:\n          - ref: packs/a.siq#A1/Cats/500\n",
+		"twice":     "rounds:\n  - themes:\n      - questions:\n          - ref: packs/a.siq#r1/t1/q1\n          - ref: packs/a.siq#A1/Cats/100\n",
+		"too few":   "pool: [packs]\nrounds:\n  - themes:\n      - questions:\n          - query: {type: stake}\n",
+	}
+	for name, specYAML := range specs {
+		spec, err := LoadSpec(writeComposeSpec(t, dir, specYAML))
+		if err == nil {
+			_, err = Compose(spec, filepath.Join(dir, "out.siq"))
+		}
+		if err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
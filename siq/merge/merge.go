@@ -0,0 +1,372 @@
+package merge
+
+import (
+	"archive/zip"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// RoundTypeFinal is the type of final rounds, which are always placed last
+const RoundTypeFinal = "final"
+
+// Options configures merging
+type Options struct {
+	// Interleave takes rounds from the packages in turn instead of concatenating them
+	Interleave bool
+	// Name of the merged package, the names of the inputs are joined when empty
+	Name string
+}
+
+// Rename is a file or global id renamed to avoid a collision
+type Rename struct {
+	Source string
+	Old    string
+	New    string
+}
+
+// Result summarizes a merge
+type Result struct {
+	Rounds    int
+	Questions int
+	// Files is the number of media files written
+	Files int
+	// SharedFiles is the number of identical files stored only once
+	SharedFiles  int
+	RenamedFiles []Rename
+	RenamedIDs   []Rename
+	// Missing lists referenced media files that do not exist in their package
+	Missing []string
+}
+
+// fileCopy is a media file to copy into the output archive
+type fileCopy struct {
+	file *zip.File
+	name string
+}
+
+// merger holds the state of a merge
+type merger struct {
+	pkg    *siq.Package
+	result *Result
+	files  map[string]string // output file path to content hash
+	copies []fileCopy
+}
+
+// Merge combines packages into a new package written to output.
+// Rounds are concatenated or interleaved, final rounds are moved to the end.
+// Global authors and sources are merged: entries with equal names are stored
+// once and colliding ids are renamed. Media files with equal names but
+// different content are renamed and isRef items are rewritten accordingly,
+// identical files are stored once.
+func Merge(paths []string, output string, options Options) (*Result, error) {
+	if len(paths) < 2 {
+		return nil, fmt.Errorf("at least two packages are required")
+	}
+
+	var sources []*source
+	defer func() {
+		for _, src := range sources {
+			src.reader.Close()
+		}
+	}()
+	for _, path := range paths {
+		src, err := openSource(path)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+
+	first := sources[0].pkg
+	m := &merger{
+		pkg: &siq.Package{
+			ID:          siq.NewID(),
+			Name:        options.Name,
+			Version:     "5",
+			Restriction: first.Restriction,
+			Publisher:   first.Publisher,
+			Difficulty:  first.Difficulty,
+			Language:    first.Language,
+		},
+		result: &Result{},
+		files:  make(map[string]string),
+	}
+
+	var names []string
+	roundLists := make([][]siq.Round, len(sources))
+	for i, src := range sources {
+		names = append(names, src.pkg.Name)
+		if err := m.addSource(src, i == 0); err != nil {
+			return nil, err
+		}
+		roundLists[i] = src.pkg.Rounds
+	}
+	if m.pkg.Name == "" {
+		m.pkg.Name = strings.Join(names, " + ")
+	}
+	m.pkg.Rounds = orderRounds(roundLists, options.Interleave)
+
+	m.result.Rounds = m.pkg.GetRoundCount()
+	m.result.Questions = m.pkg.GetQuestionCount()
+	m.result.Files = len(m.copies)
+	return m.result, write(output, m.pkg, m.copies)
+}
+
+// addSource merges the metadata, global definitions and media of a source
+// and rewrites its references. The logo is only taken from the first package.
+func (m *merger) addSource(src *source, first bool) error {
+	pkg := src.pkg
+
+	// Package-level authors and sources are inherited by rounds, keep them
+	// with the rounds of this package
+	if pkg.Info != nil && (len(pkg.Info.Authors) > 0 || len(pkg.Info.Sources) > 0) {
+		for i := range pkg.Rounds {
+			round := &pkg.Rounds[i]
+			if round.Info == nil {
+				round.Info = &siq.Info{}
+			}
+			if len(round.Info.Authors) == 0 {
+				round.Info.Authors = slices.Clone(pkg.Info.Authors)
+			}
+			if len(round.Info.Sources) == 0 {
+				round.Info.Sources = slices.Clone(pkg.Info.Sources)
+			}
+		}
+	}
+
+	rewriteReferences(pkg, m.mergeGlobal(src))
+	if pkg.Info != nil {
+		m.mergeInfo(pkg.Info)
+	}
+	if pkg.Tags != nil {
+		if m.pkg.Tags == nil {
+			m.pkg.Tags = &siq.Tags{}
+		}
+		for _, tag := range pkg.Tags.Tags {
+			if !slices.Contains(m.pkg.Tags.Tags, tag) {
+				m.pkg.Tags.Tags = append(m.pkg.Tags.Tags, tag)
+			}
+		}
+	}
+
+	renamed, err := m.mergeFiles(src)
+	if err != nil {
+		return err
+	}
+
+	if logo := pkg.LogoPath(); first && logo != "" {
+		if newPath, ok := renamed[logo]; ok {
+			m.pkg.Logo = "@" + path.Base(newPath)
+		} else {
+			m.result.Missing = append(m.result.Missing, fmt.Sprintf("%s: %s", src.path, logo))
+		}
+	}
+
+	return pkg.Walk(func(ref siq.QuestionRef, question *siq.Question) error {
+		for item := range question.ContentItems() {
+			itemPath := siq.ItemPath(*item)
+			if itemPath == "" {
+				continue
+			}
+			newPath, ok := renamed[itemPath]
+			if !ok {
+				m.result.Missing = append(m.result.Missing, fmt.Sprintf("%s: %s (%s)", src.path, itemPath, ref.ID()))
+				continue
+			}
+			if newPath != itemPath {
+				item.Value = path.Base(newPath)
+			}
+		}
+		return nil
+	})
+}
+
+// globalEntry is a global author or source
+type globalEntry struct {
+	ID   string
+	Name string
+}
+
+// mergeGlobal adds the global authors and sources of a source and returns
+// the mapping of its ids to the ids used in the merged package
+func (m *merger) mergeGlobal(src *source) map[string]string {
+	ids := make(map[string]string)
+	if src.pkg.Global == nil {
+		return ids
+	}
+	if m.pkg.Global == nil {
+		m.pkg.Global = &siq.Global{}
+	}
+	global := m.pkg.Global
+
+	// Authors and sources are referenced the same way, so their ids must not collide
+	used := make(map[string]bool)
+	for _, author := range global.Authors {
+		used[author.ID] = true
+	}
+	for _, source := range global.Sources {
+		used[source.ID] = true
+	}
+
+	var authors, sources []globalEntry
+	for _, author := range global.Authors {
+		authors = append(authors, globalEntry(author))
+	}
+	for _, source := range global.Sources {
+		sources = append(sources, globalEntry(source))
+	}
+	for _, author := range src.pkg.Global.Authors {
+		authors = m.mergeEntry(src.path, authors, globalEntry(author), used, ids)
+	}
+	for _, source := range src.pkg.Global.Sources {
+		sources = m.mergeEntry(src.path, sources, globalEntry(source), used, ids)
+	}
+
+	global.Authors = global.Authors[:0]
+	for _, author := range authors {
+		global.Authors = append(global.Authors, siq.GlobalAuthor(author))
+	}
+	global.Sources = global.Sources[:0]
+	for _, source := range sources {
+		global.Sources = append(global.Sources, siq.GlobalSource(source))
+	}
+	return ids
+}
+
+// mergeEntry adds a global entry unless an entry with the same name exists.
+// An entry whose id is taken by a different entry gets a numeric suffix.
+func (m *merger) mergeEntry(srcPath string, entries []globalEntry, entry globalEntry, used map[string]bool, ids map[string]string) []globalEntry {
+	for _, existing := range entries {
+		if existing.Name == entry.Name {
+			ids[entry.ID] = existing.ID
+			return entries
+		}
+	}
+
+	id := entry.ID
+	for n := 2; used[id]; n++ {
+		id = entry.ID + "-" + strconv.Itoa(n)
+	}
+	used[id] = true
+	if id != entry.ID {
+		m.result.RenamedIDs = append(m.result.RenamedIDs, Rename{Source: srcPath, Old: entry.ID, New: id})
+	}
+	ids[entry.ID] = id
+	return append(entries, globalEntry{ID: id, Name: entry.Name})
+}
+
+// mergeInfo adds package-level authors, sources and comments without duplicates
+func (m *merger) mergeInfo(info *siq.Info) {
+	if m.pkg.Info == nil {
+		m.pkg.Info = &siq.Info{}
+	}
+	appendNew := func(values []string, added []string) []string {
+		for _, value := range added {
+			if !slices.Contains(values, value) {
+				values = append(values, value)
+			}
+		}
+		return values
+	}
+	m.pkg.Info.Authors = appendNew(m.pkg.Info.Authors, info.Authors)
+	m.pkg.Info.Sources = appendNew(m.pkg.Info.Sources, info.Sources)
+	m.pkg.Info.Comments = appendNew(m.pkg.Info.Comments, info.Comments)
+}
+
+// mergeFiles assigns output names to the media files of a source and
+// returns the mapping of its file paths to output paths
+func (m *merger) mergeFiles(src *source) (map[string]string, error) {
+	renamed := make(map[string]string)
+	for _, filePath := range src.ordered {
+		newPath, err := m.addFile(src, filePath)
+		if err != nil {
+			return nil, err
+		}
+		renamed[filePath] = newPath
+	}
+	return renamed, nil
+}
+
+// addFile assigns an output name to a media file of a source and returns it.
+// The file keeps its name unless a different file already uses it, a file
+// identical to an already added one is stored once.
+func (m *merger) addFile(src *source, filePath string) (string, error) {
+	hash, _, err := src.reader.HashFile(src.files[filePath].Name)
+	if err != nil {
+		return "", err
+	}
+
+	newPath := filePath
+	for n := 2; ; n++ {
+		existing, used := m.files[newPath]
+		if !used {
+			m.files[newPath] = hash
+			m.copies = append(m.copies, fileCopy{file: src.files[filePath], name: newPath})
+			break
+		}
+		if existing == hash {
+			m.result.SharedFiles++
+			break
+		}
+		newPath = siq.RenameFile(filePath, n)
+	}
+
+	if newPath != filePath {
+		m.result.RenamedFiles = append(m.result.RenamedFiles, Rename{Source: src.path, Old: filePath, New: newPath})
+	}
+	return newPath, nil
+}
+
+// orderRounds concatenates or interleaves the rounds of the packages and moves final rounds to the end
+func orderRounds(lists [][]siq.Round, interleave bool) []siq.Round {
+	var ordered []siq.Round
+	if interleave {
+		for i := 0; ; i++ {
+			added := false
+			for _, rounds := range lists {
+				if i < len(rounds) {
+					ordered = append(ordered, rounds[i])
+					added = true
+				}
+			}
+			if !added {
+				break
+			}
+		}
+	} else {
+		for _, rounds := range lists {
+			ordered = append(ordered, rounds...)
+		}
+	}
+
+	var regular, final []siq.Round
+	for _, round := range ordered {
+		if round.Type == RoundTypeFinal {
+			final = append(final, round)
+		} else {
+			regular = append(regular, round)
+		}
+	}
+	return append(regular, final...)
+}
+
+// write creates the output archive with the package and its media files.
+// Nothing is left behind when writing fails.
+func write(output string, pkg *siq.Package, copies []fileCopy) error {
+	return siq.ReplaceFile(output, func(writer *siq.SIQWriter) error {
+		if err := writer.WritePackage(pkg); err != nil {
+			return err
+		}
+		for _, copy := range copies {
+			if err := writer.CopyFile(copy.file, copy.name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,258 @@
+package merge
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minmaxmean/sigma/internal/siqtest"
+	"github.com/minmaxmean/sigma/siq"
+)
+
+const packAXML = `<?xml version="1.0" encoding="utf-8"?>
+<package id="a" name="Pack A" version="5" logo="@logo.png" language="en">
+	<info><authors><author>@1</author></authors></info>
+	<tags><tag>Science</tag></tags>
+	<global>
+		<authors><author id="1">Alice</author></authors>
+		<sources><source id="s1">Encyclopedia</source></sources>
+	</global>
+	<round name="A1">
+		<theme name="Cats">
+			<question price="100">
+				<params>
+					<param name="question" type="content">
+						<item type="image" isRef="True">cat.png</item>
+					</param>
+				</params>
+				<right><answer>Cat</answer></right>
+				<info><sources><source>@s1#p. 5</source></sources></info>
+			</question>
+		</theme>
+	</round>
+	<round name="A final" type="final">
+		<theme name="Final">
+			<question price="0">
+				<params>
+					<param name="question" type="content">
+						<item>Final question</item>
+					</param>
+				</params>
+				<right><answer>Yes</answer></right>
+			</question>
+		</theme>
+	</round>
+</package>`
+
+const packBXML = `<?xml version="1.0" encoding="utf-8"?>
+<package id="b" name="Pack B" version="5">
+	<info><authors><author>@1</author></authors></info>
+	<tags><tag>Science</tag><tag>Music</tag></tags>
+	<global>
+		<authors><author id="1">Bob</author><author id="2">Alice</author></authors>
+	</global>
+	<round name="B1">
+		<theme name="Dogs">
+			<question price="100">
+				<params>
+					<param name="question" type="content">
+						<item type="image" isRef="True">cat.png</item>
+						<item type="audio" isRef="True">bark.mp3</item>
+					</param>
+				</params>
+				<right><answer>Dog</answer></right>
+				<info><authors><author>@2</author></authors></info>
+			</question>
+		</theme>
+	</round>
+	<round name="B2">
+		<theme name="Logos">
+			<question price="200">
+				<params>
+					<param name="question" type="content">
+						<item type="image" isRef="True">logo.png</item>
+						<item type="image" isRef="True">missing.png</item>
+					</param>
+				</params>
+				<right><answer>Logo</answer></right>
+			</question>
+		</theme>
+	</round>
+</package>`
+
+// readTestPackage reads a package and the content of its files
+func readTestPackage(t *testing.T, path string) (*siq.Package, map[string]string) {
+	reader, err := siq.NewSIQReader(path)
+	if err != nil {
+		t.Fatal("Failed to open package:", err)
+	}
+	defer reader.Close()
+
+	pkg, err := reader.Read()
+	if err != nil {
+		t.Fatal("Failed to read package:", err)
+	}
+
+	files := make(map[string]string)
+	for _, name := range reader.ListFiles() {
+		if name == "content.xml" {
+			continue
+		}
+		file, _ := reader.GetFile(name)
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal("Failed to open file:", err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal("Failed to read file:", err)
+		}
+		files[name] = string(data)
+	}
+	return pkg, files
+}
+
+func createTestPacks(t *testing.T) (string, string, string) {
+	dir := t.TempDir()
+	a := siqtest.CreateFile(t, dir, "a.siq", packAXML, map[string]string{
+		"Images/cat.png":  "cat A",
+		"Images/logo.png": "logo",
+	})
+	b := siqtest.CreateFile(t, dir, "b.siq", packBXML, map[string]string{
+		"Images/cat.png":  "cat B",
+		"Images/logo.png": "logo",
+		"Audio/bark.mp3":  "bark",
+	})
+	return dir, a, b
+}
+
+func TestMerge(t *testing.T) {
+	dir, a, b := createTestPacks(t)
+	output := filepath.Join(dir, "merged.siq")
+
+	result, err := Merge([]string{a, b}, output, Options{})
+	if err != nil {
+		t.Fatal("Failed to merge packages:", err)
+	}
+	if result.Rounds != 4 || result.Questions != 4 || result.Files != 4 || result.SharedFiles != 1 {
+		t.Errorf("Unexpected merge result %+v", result)
+	}
+	if len(result.RenamedFiles) != 1 || result.RenamedFiles[0].New != "Images/cat_2.png" {
+		t.Errorf("Expected cat.png of pack B to be renamed, got %+v", result.RenamedFiles)
+	}
+	if len(result.Missing) != 1 || !strings.Contains(result.Missing[0], "Images/missing.png") {
+		t.Errorf("Expected missing.png to be reported, got %v", result.Missing)
+	}
+
+	pkg, files := readTestPackage(t, output)
+	if pkg.Name != "Pack A + Pack B" || pkg.Logo != "@logo.png" || pkg.Language != "en" {
+		t.Errorf("Unexpected merged metadata %+v", pkg)
+	}
+	if strings.Join(pkg.Tags.Tags, ",") != "Science,Music" {
+		t.Errorf("Unexpected merged tags %v", pkg.Tags.Tags)
+	}
+
+	var rounds []string
+	for _, round := range pkg.Rounds {
+		rounds = append(rounds, round.Name)
+	}
+	if strings.Join(rounds, ",") != "A1,B1,B2,A final" {
+		t.Errorf("Expected the final round last, got %v", rounds)
+	}
+
+	// Media references are rewritten to the renamed files
+	items := pkg.Rounds[1].Themes[0].Questions[0].GetQuestionContent()
+	if items[0].Value != "cat_2.png" || files["Images/cat_2.png"] != "cat B" || files["Images/cat.png"] != "cat A" {
+		t.Errorf("Unexpected media after merge: items %+v, files %v", items, files)
+	}
+
+	// Bob collides with Alice's id, Alice is stored once
+	global := pkg.Global
+	if len(global.Authors) != 2 || global.Authors[1] != (siq.GlobalAuthor{ID: "1-2", Name: "Bob"}) {
+		t.Errorf("Unexpected merged authors %+v", global.Authors)
+	}
+	if authors := pkg.Rounds[1].Info.Authors; len(authors) != 1 || authors[0] != "@1-2" {
+		t.Errorf("Expected inherited author of pack B to be rewritten, got %v", authors)
+	}
+	if authors := pkg.Rounds[1].Themes[0].Questions[0].Info.Authors; authors[0] != "@1" {
+		t.Errorf("Expected Alice reference to point to the existing id, got %v", authors)
+	}
+	if sources := pkg.Rounds[0].Themes[0].Questions[0].Info.Sources; sources[0] != "@s1#p. 5" {
+		t.Errorf("Expected source reference to be kept, got %v", sources)
+	}
+}
+
+func TestMergeInterleave(t *testing.T) {
+	dir, a, b := createTestPacks(t)
+	output := filepath.Join(dir, "merged.siq")
+
+	if _, err := Merge([]string{b, a}, output, Options{Interleave: true, Name: "Mix"}); err != nil {
+		t.Fatal("Failed to merge packages:", err)
+	}
+
+	pkg, _ := readTestPackage(t, output)
+	var rounds []string
+	for _, round := range pkg.Rounds {
+		rounds = append(rounds, round.Name)
+	}
+	if pkg.Name != "Mix" || strings.Join(rounds, ",") != "B1,A1,B2,A final" {
+		t.Errorf("Unexpected interleaved package %s: %v", pkg.Name, rounds)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	dir, _, b := createTestPacks(t)
+
+	results, err := Split(b, dir)
+	if err != nil {
+		t.Fatal("Failed to split package:", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 packages, got %d", len(results))
+	}
+	if filepath.Base(results[0].Path) != "b-round1.siq" || results[0].Files != 2 || results[0].Questions != 1 {
+		t.Errorf("Unexpected first split result %+v", results[0])
+	}
+	if len(results[1].Missing) != 1 || results[1].Missing[0] != "Images/missing.png" {
+		t.Errorf("Expected missing file in second package, got %+v", results[1])
+	}
+
+	pkg, files := readTestPackage(t, results[0].Path)
+	if pkg.Name != "Pack B: B1" || len(pkg.Rounds) != 1 {
+		t.Errorf("Unexpected split package %+v", pkg)
+	}
+	if len(files) != 2 || files["Images/cat.png"] != "cat B" || files["Audio/bark.mp3"] != "bark" {
+		t.Errorf("Expected only the media of the round, got %v", files)
+	}
+	if len(pkg.Global.Authors) != 2 {
+		t.Errorf("Expected referenced authors to be kept, got %+v", pkg.Global.Authors)
+	}
+
+	_, files = readTestPackage(t, results[1].Path)
+	if len(files) != 1 || files["Images/logo.png"] != "logo" {
+		t.Errorf("Expected only logo.png in second package, got %v", files)
+	}
+}
+
+func TestSplitNonCanonicalFolders(t *testing.T) {
+	dir := t.TempDir()
+	b := siqtest.CreateFile(t, dir, "b.siq", packBXML, map[string]string{
+		"images/cat.png": "cat B",
+		"audio/bark.mp3": "bark",
+	})
+
+	results, err := Split(b, dir)
+	if err != nil {
+		t.Fatal("Failed to split package:", err)
+	}
+	if len(results[0].Missing) != 0 || results[0].Files != 2 {
+		t.Errorf("Expected media in lower-case folders to be found, got %+v", results[0])
+	}
+
+	_, files := readTestPackage(t, results[0].Path)
+	if files["Images/cat.png"] != "cat B" || files["Audio/bark.mp3"] != "bark" {
+		t.Errorf("Expected media to be written to canonical folders, got %v", files)
+	}
+}
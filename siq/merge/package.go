@@ -0,0 +1,108 @@
+// Package merge combines several SIQ packages into one, composes packages
+// from questions of other packages and splits a package into one package
+// per round
+package merge
+
+import (
+	"archive/zip"
+	"fmt"
+	"strings"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// source is an opened input package
+type source struct {
+	path    string
+	reader  *siq.SIQReader
+	pkg     *siq.Package
+	files   map[string]*zip.File // media files by canonical archive path
+	ordered []string             // media file paths in archive order
+}
+
+// openSource reads a package and indexes its media files
+func openSource(path string) (*source, error) {
+	reader, err := siq.NewSIQReader(path)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := reader.Read()
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	src := &source{path: path, reader: reader, pkg: pkg, files: make(map[string]*zip.File)}
+	for _, name := range reader.ListFiles() {
+		filePath := siq.EntryPath(name)
+		if filePath == "" {
+			continue
+		}
+		file, err := reader.GetFile(name)
+		if err != nil {
+			reader.Close()
+			return nil, err
+		}
+		if _, ok := src.files[filePath]; !ok {
+			src.files[filePath] = file
+			src.ordered = append(src.ordered, filePath)
+		}
+	}
+	return src, nil
+}
+
+// eachInfo calls fn for the package info and the info of every round, theme and question
+func eachInfo(pkg *siq.Package, fn func(info *siq.Info)) {
+	if pkg.Info != nil {
+		fn(pkg.Info)
+	}
+	for i := range pkg.Rounds {
+		round := &pkg.Rounds[i]
+		if round.Info != nil {
+			fn(round.Info)
+		}
+		for j := range round.Themes {
+			theme := &round.Themes[j]
+			if theme.Info != nil {
+				fn(theme.Info)
+			}
+			for k := range theme.Questions {
+				if info := theme.Questions[k].Info; info != nil {
+					fn(info)
+				}
+			}
+		}
+	}
+}
+
+// splitReference splits an author or source reference like "@id#spec" into
+// its id and specification. ok is false for plain text values.
+func splitReference(value string) (id, spec string, ok bool) {
+	if !strings.HasPrefix(value, "@") {
+		return "", "", false
+	}
+	id, spec, _ = strings.Cut(value[1:], "#")
+	return id, spec, true
+}
+
+// rewriteReferences replaces global author and source ids in all infos of the package
+func rewriteReferences(pkg *siq.Package, ids map[string]string) {
+	rewrite := func(values []string) {
+		for i, value := range values {
+			id, spec, ok := splitReference(value)
+			if !ok {
+				continue
+			}
+			if newID, found := ids[id]; found && newID != id {
+				values[i] = "@" + newID
+				if spec != "" {
+					values[i] += "#" + spec
+				}
+			}
+		}
+	}
+	eachInfo(pkg, func(info *siq.Info) {
+		rewrite(info.Authors)
+		rewrite(info.Sources)
+	})
+}
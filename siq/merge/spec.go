@@ -0,0 +1,120 @@
+package merge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec describes a package composed from questions of other packages
+type Spec struct {
+	Name       string   `yaml:"name"`
+	Language   string   `yaml:"language"`
+	Publisher  string   `yaml:"publisher"`
+	Difficulty int      `yaml:"difficulty"`
+	Authors    []string `yaml:"authors"`
+	Tags       []string `yaml:"tags"`
+	// Pool lists packages and directories searched by queries
+	Pool []string `yaml:"pool"`
+	// Seed shuffles query candidates when not zero, otherwise they are taken in pool order
+	Seed   uint64      `yaml:"seed"`
+	Rounds []RoundSpec `yaml:"rounds"`
+
+	// dir is the directory relative paths are resolved against
+	dir string
+}
+
+// RoundSpec describes a round of a composed package
+type RoundSpec struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+	// Prices are assigned to the questions of every theme in order, unless the theme has its own
+	Prices []int       `yaml:"prices"`
+	Themes []ThemeSpec `yaml:"themes"`
+}
+
+// ThemeSpec describes a theme of a composed package
+type ThemeSpec struct {
+	Name      string         `yaml:"name"`
+	Prices    []int          `yaml:"prices"`
+	Questions []QuestionSpec `yaml:"questions"`
+}
+
+// QuestionSpec selects questions either by reference or by query
+type QuestionSpec struct {
+	// Ref is "pack.siq#round/theme/price" or "pack.siq#r1/t2/q3"
+	Ref   string `yaml:"ref"`
+	Query *Query `yaml:"query"`
+	// Price overrides the assigned price of the selected questions
+	Price int `yaml:"price"`
+}
+
+// Query selects questions from the pool by their properties
+type Query struct {
+	// Pool overrides the pool of the spec
+	Pool []string `yaml:"pool"`
+	// Tags matches packages having at least one of the tags
+	Tags []string `yaml:"tags"`
+	// Type is the question type
+	Type string `yaml:"type"`
+	// HasMedia requires questions with or without media content when set
+	HasMedia *bool `yaml:"hasMedia"`
+	// MinDifficulty and MaxDifficulty limit the package difficulty when not zero
+	MinDifficulty int `yaml:"minDifficulty"`
+	MaxDifficulty int `yaml:"maxDifficulty"`
+	// Text must occur in the question content (case- and diacritic-insensitive)
+	Text string `yaml:"text"`
+	// Count is the number of questions to select, 1 when not set
+	Count int `yaml:"count"`
+}
+
+// LoadSpec reads a compose spec from a YAML file.
+// Relative package paths in the spec are resolved against its directory.
+func LoadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec: %w", err)
+	}
+
+	var spec Spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse spec %s: %w", path, err)
+	}
+	spec.dir = filepath.Dir(path)
+
+	if err := spec.validate(); err != nil {
+		return nil, fmt.Errorf("invalid spec %s: %w", path, err)
+	}
+	return &spec, nil
+}
+
+// validate checks that every question entry selects questions in exactly one way
+func (s *Spec) validate() error {
+	if len(s.Rounds) == 0 {
+		return fmt.Errorf("no rounds defined")
+	}
+	for i, round := range s.Rounds {
+		for j, theme := range round.Themes {
+			for k, question := range theme.Questions {
+				location := fmt.Sprintf("rounds[%d].themes[%d].questions[%d]", i, j, k)
+				if (question.Ref == "") == (question.Query == nil) {
+					return fmt.Errorf("%s: exactly one of ref and query is required", location)
+				}
+				if question.Query != nil && question.Query.Count < 0 {
+					return fmt.Errorf("%s: negative count", location)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resolve returns a path relative to the spec directory
+func (s *Spec) resolve(path string) string {
+	if filepath.IsAbs(path) || s.dir == "" {
+		return path
+	}
+	return filepath.Join(s.dir, path)
+}
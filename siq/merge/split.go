@@ -0,0 +1,99 @@
+package merge
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// SplitResult describes a package written by Split
+type SplitResult struct {
+	Path      string
+	Round     string
+	Questions int
+	Files     int
+	// Missing lists referenced media files that do not exist in the package
+	Missing []string
+}
+
+// Split writes one package per round of the package at path into outputDir.
+// Each package keeps the metadata of the original, the global authors and
+// sources it references and only the media files its round uses.
+func Split(path, outputDir string) ([]SplitResult, error) {
+	src, err := openSource(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.reader.Close()
+
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var results []SplitResult
+	for index, round := range src.pkg.Rounds {
+		pkg := *src.pkg
+		pkg.ID = siq.NewID()
+		pkg.Name = fmt.Sprintf("%s: %s", src.pkg.Name, round.Name)
+		pkg.Rounds = []siq.Round{round}
+		pkg.Global = usedGlobal(&pkg)
+
+		result := SplitResult{
+			Path:      filepath.Join(outputDir, fmt.Sprintf("%s-round%d.siq", base, index+1)),
+			Round:     round.Name,
+			Questions: pkg.GetQuestionCount(),
+		}
+
+		var copies []fileCopy
+		for _, filePath := range pkg.MediaPaths() {
+			file, ok := src.files[filePath]
+			if !ok {
+				result.Missing = append(result.Missing, filePath)
+				continue
+			}
+			copies = append(copies, fileCopy{file: file, name: filePath})
+		}
+		if logo := pkg.LogoPath(); logo != "" && src.files[logo] == nil {
+			pkg.Logo = ""
+		}
+		result.Files = len(copies)
+
+		if err := write(result.Path, &pkg, copies); err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// usedGlobal returns the global authors and sources referenced by the package
+func usedGlobal(pkg *siq.Package) *siq.Global {
+	if pkg.Global == nil {
+		return nil
+	}
+
+	used := make(map[string]bool)
+	eachInfo(pkg, func(info *siq.Info) {
+		for _, value := range slices.Concat(info.Authors, info.Sources) {
+			if id, _, ok := splitReference(value); ok {
+				used[id] = true
+			}
+		}
+	})
+
+	global := &siq.Global{}
+	for _, author := range pkg.Global.Authors {
+		if used[author.ID] {
+			global.Authors = append(global.Authors, author)
+		}
+	}
+	for _, source := range pkg.Global.Sources {
+		if used[source.ID] {
+			global.Sources = append(global.Sources, source)
+		}
+	}
+	if len(global.Authors) == 0 && len(global.Sources) == 0 {
+		return nil
+	}
+	return global
+}
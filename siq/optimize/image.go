@@ -0,0 +1,95 @@
+package optimize
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	// Registered so GIF images are recognized and left unchanged
+	_ "image/gif"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Image formats as reported by image.Decode
+const (
+	formatJPEG = "jpeg"
+	formatPNG  = "png"
+)
+
+// imageResult is a re-encoded image
+type imageResult struct {
+	data    []byte
+	format  string
+	resized bool
+}
+
+// optimizeImage resizes and re-encodes a JPEG or PNG image.
+// ok is false when the image is kept as is: unsupported formats, animated
+// GIFs and images whose re-encoding does not make them smaller.
+func optimizeImage(data []byte, options Options) (imageResult, bool) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil || (format != formatJPEG && format != formatPNG) {
+		return imageResult{}, false
+	}
+
+	result := imageResult{format: format}
+	if options.MaxDimension > 0 {
+		if resized, ok := resize(img, options.MaxDimension); ok {
+			img = resized
+			result.resized = true
+		}
+	}
+	if format == formatPNG && options.PNGToJPEG && isOpaque(img) {
+		result.format = formatJPEG
+	}
+
+	var buf bytes.Buffer
+	switch result.format {
+	case formatJPEG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: options.JPEGQuality})
+	default:
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		err = encoder.Encode(&buf, img)
+	}
+	if err != nil {
+		return imageResult{}, false
+	}
+
+	// Re-encoding alone is only worth it when it saves space
+	if !result.resized && result.format == format && buf.Len() >= len(data) {
+		return imageResult{}, false
+	}
+	result.data = buf.Bytes()
+	return result, true
+}
+
+// resize scales the image down so that its larger side equals maxDimension
+func resize(img image.Image, maxDimension int) (image.Image, bool) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxDimension && height <= maxDimension {
+		return nil, false
+	}
+
+	if width >= height {
+		height = max(1, height*maxDimension/width)
+		width = maxDimension
+	} else {
+		width = max(1, width*maxDimension/height)
+		height = maxDimension
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, xdraw.Src, nil)
+	return dst, true
+}
+
+// isOpaque reports whether the image has no transparent pixels
+func isOpaque(img image.Image) bool {
+	if opaque, ok := img.(interface{ Opaque() bool }); ok {
+		return opaque.Opaque()
+	}
+	return false
+}
@@ -0,0 +1,300 @@
+// Package optimize reduces the size of SIQ packages by re-encoding images,
+// removing unreferenced media and storing identical files once
+package optimize
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// Defaults used by DefaultOptions
+const (
+	DefaultMaxDimension = 1920
+	DefaultJPEGQuality  = 85
+)
+
+// Options configures optimization
+type Options struct {
+	// MaxDimension is the maximal width and height of images, 0 disables resizing
+	MaxDimension int
+	// JPEGQuality is the quality of re-encoded JPEG images, from 1 to 100
+	JPEGQuality int
+	// PNGToJPEG converts opaque PNG images to JPEG, renaming them
+	PNGToJPEG bool
+	// KeepUnreferenced keeps archive files no question refers to
+	KeepUnreferenced bool
+	// Store lists content types stored without compression
+	Store []string
+	// Level is the deflate level of compressed files
+	Level int
+}
+
+// DefaultOptions returns the options used by the optimize command by default.
+// Images, audio and video are already compressed, so they are stored as is.
+func DefaultOptions() Options {
+	return Options{
+		MaxDimension: DefaultMaxDimension,
+		JPEGQuality:  DefaultJPEGQuality,
+		Store:        []string{siq.ContentTypeImage, siq.ContentTypeAudio, siq.ContentTypeVideo},
+		Level:        flate.BestCompression,
+	}
+}
+
+// Actions applied to files
+const (
+	ActionKept         = "kept"
+	ActionReencoded    = "re-encoded"
+	ActionResized      = "resized"
+	ActionConverted    = "converted"
+	ActionDuplicate    = "duplicate"
+	ActionUnreferenced = "unreferenced"
+)
+
+// FileReport describes what happened to an archive file
+type FileReport struct {
+	Name string
+	// NewName is the name in the optimized package, the kept file for
+	// duplicates and empty for removed files
+	NewName string
+	Action  string
+	OldSize int64
+	NewSize int64
+}
+
+// Saved returns the number of bytes saved on the file content
+func (f FileReport) Saved() int64 {
+	return f.OldSize - f.NewSize
+}
+
+// Report summarizes an optimization
+type Report struct {
+	Files []FileReport
+	// OldSize and NewSize are the sizes of the package files
+	OldSize int64
+	NewSize int64
+}
+
+// output is a file to write to the optimized package
+type output struct {
+	name   string
+	data   []byte    // re-encoded content, nil when copied from the source
+	source *zip.File // used when data is nil
+}
+
+// Optimize writes an optimized copy of the package at input to outputPath,
+// which may be input itself
+func Optimize(input, outputPath string, options Options) (*Report, error) {
+	if options.JPEGQuality < 1 || options.JPEGQuality > 100 {
+		return nil, fmt.Errorf("invalid JPEG quality %d", options.JPEGQuality)
+	}
+
+	reader, err := siq.NewSIQReader(input)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	pkg, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]bool)
+	for _, filePath := range pkg.MediaPaths() {
+		referenced[filePath] = true
+	}
+
+	report := &Report{}
+	renames := make(map[string]string)
+	byHash := make(map[string]string) // folder and content hash -> kept name
+	used := make(map[string]bool)
+	var outputs []output
+
+	// Converted images must not take the name of another file
+	for _, name := range reader.ListFiles() {
+		used[archivePath(name)] = true
+	}
+
+	for _, name := range reader.ListFiles() {
+		if name == "content.xml" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		file, err := reader.GetFile(name)
+		if err != nil {
+			return nil, err
+		}
+		filePath := archivePath(name)
+		fileReport := FileReport{Name: filePath, Action: ActionKept, OldSize: int64(file.UncompressedSize64)}
+
+		if !referenced[filePath] && !options.KeepUnreferenced {
+			fileReport.Action = ActionUnreferenced
+			report.Files = append(report.Files, fileReport)
+			continue
+		}
+
+		out := output{name: filePath, source: file}
+		var hash string
+		if siq.MediaType(filePath) == siq.ContentTypeImage {
+			data, err := readFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if result, ok := optimizeImage(data, options); ok {
+				data = result.data
+				out.data = data
+				fileReport.Action = ActionReencoded
+				if result.resized {
+					fileReport.Action = ActionResized
+				}
+				if result.format == formatJPEG && !isJPEGName(filePath) {
+					out.name = uniqueName(strings.TrimSuffix(filePath, path.Ext(filePath))+".jpg", used)
+					fileReport.Action = ActionConverted
+				}
+			}
+			sum := sha256.Sum256(data)
+			hash = hex.EncodeToString(sum[:])
+			fileReport.NewSize = int64(len(data))
+		} else {
+			if hash, fileReport.NewSize, err = reader.HashFile(name); err != nil {
+				return nil, err
+			}
+		}
+
+		// Items are renamed by file name only, so duplicates are looked up
+		// within the same media folder
+		key := path.Dir(filePath) + "/" + hash
+		if kept, ok := byHash[key]; ok {
+			fileReport.Action = ActionDuplicate
+			fileReport.NewName = kept
+			fileReport.NewSize = 0
+			renames[filePath] = kept
+			report.Files = append(report.Files, fileReport)
+			continue
+		}
+
+		byHash[key] = out.name
+		fileReport.NewName = out.name
+		if out.name != filePath {
+			renames[filePath] = out.name
+		}
+		outputs = append(outputs, out)
+		report.Files = append(report.Files, fileReport)
+	}
+
+	pkg.RenameFiles(renames)
+	if report.OldSize, err = fileSize(input); err != nil {
+		return nil, err
+	}
+	if err := write(outputPath, pkg, outputs, options); err != nil {
+		return nil, err
+	}
+	if report.NewSize, err = fileSize(outputPath); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// archivePath returns the path of an archive entry as content items refer to
+// it: media files get their canonical folder and decoded name
+func archivePath(name string) string {
+	if filePath := siq.EntryPath(name); filePath != "" {
+		return filePath
+	}
+	return siq.DecodeFileName(name)
+}
+
+// write creates the optimized package, replacing the input when it is the
+// output
+func write(outputPath string, pkg *siq.Package, outputs []output, options Options) error {
+	return siq.ReplaceFile(outputPath, func(writer *siq.SIQWriter) error {
+		return writeFiles(writer, pkg, outputs, options)
+	})
+}
+
+func writeFiles(writer *siq.SIQWriter, pkg *siq.Package, outputs []output, options Options) error {
+	if err := writer.SetCompressionLevel(options.Level); err != nil {
+		return err
+	}
+	if err := writer.WritePackage(pkg); err != nil {
+		return err
+	}
+
+	for _, out := range outputs {
+		var w io.Writer
+		var err error
+		if slices.Contains(options.Store, siq.MediaType(out.name)) {
+			w, err = writer.CreateStoredFile(out.name)
+		} else {
+			w, err = writer.CreateFile(out.name)
+		}
+		if err != nil {
+			return err
+		}
+
+		if out.data != nil {
+			_, err = w.Write(out.data)
+		} else {
+			err = copyFile(w, out.source)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", out.name, err)
+		}
+	}
+	return nil
+}
+
+// readFile returns the content of an archive file
+func readFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// copyFile copies the content of an archive file to w
+func copyFile(w io.Writer, file *zip.File) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = io.Copy(w, rc)
+	return err
+}
+
+// isJPEGName reports whether the file name has a JPEG extension
+func isJPEGName(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".jpg" || ext == ".jpeg"
+}
+
+// uniqueName returns name or a numbered variant of it that is not used yet and marks it as used
+func uniqueName(name string, used map[string]bool) string {
+	unique := name
+	for n := 2; used[unique]; n++ {
+		unique = siq.RenameFile(name, n)
+	}
+	used[unique] = true
+	return unique
+}
+
+// fileSize returns the size of a file on disk
+func fileSize(name string) (int64, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
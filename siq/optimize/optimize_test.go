@@ -0,0 +1,261 @@
+package optimize
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minmaxmean/sigma/internal/siqtest"
+	"github.com/minmaxmean/sigma/siq"
+)
+
+const testContentXML = `<?xml version="1.0" encoding="utf-8"?>
+<package id="opt" name="Optimize" version="5" logo="@logo.png">
+	<round name="Round 1">
+		<theme name="Theme 1">
+			<question price="100">
+				<params>
+					<param name="question" type="content">
+						<item type="image" isRef="True">wide.png</item>
+						<item type="audio" isRef="True">sound.mp3</item>
+					</param>
+					<param name="answer" type="content">
+						<item type="image" isRef="True">wide_copy.png</item>
+						<item type="image" isRef="True">alpha.png</item>
+					</param>
+				</params>
+				<right><answer>Answer</answer></right>
+			</question>
+		</theme>
+	</round>
+</package>`
+
+// encodeTestPNG creates a PNG image with a gradient, transparent when alpha is below 255
+func encodeTestPNG(t *testing.T, width, height int, alpha uint8) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: uint8(x + y), A: alpha})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal("Failed to encode PNG:", err)
+	}
+	return buf.Bytes()
+}
+
+func createTestPackage(t *testing.T, dir string) string {
+	// Files are stored in name order, so wide_copy.png is the duplicate of wide.png
+	wide := encodeTestPNG(t, 400, 100, 255)
+	files := map[string][]byte{
+		"content.xml":          []byte(testContentXML),
+		"Images/logo.png":      encodeTestPNG(t, 300, 300, 255),
+		"Images/wide.png":      wide,
+		"Images/wide_copy.png": wide,
+		"Images/wide.jpg":      []byte("not a real jpeg"),
+		"Images/alpha.png":     encodeTestPNG(t, 50, 50, 128),
+		"Images/unused.png":    encodeTestPNG(t, 10, 10, 255),
+		"Audio/sound.mp3":      bytes.Repeat([]byte("sound"), 100),
+	}
+
+	path := filepath.Join(dir, "in.siq")
+	siqtest.WriteArchive(t, path, files)
+	return path
+}
+
+func TestOptimize(t *testing.T) {
+	dir := t.TempDir()
+	input := createTestPackage(t, dir)
+	output := filepath.Join(dir, "out.siq")
+
+	options := DefaultOptions()
+	options.MaxDimension = 200
+	options.PNGToJPEG = true
+
+	report, err := Optimize(input, output, options)
+	if err != nil {
+		t.Fatal("Failed to optimize package:", err)
+	}
+
+	actions := make(map[string]FileReport)
+	for _, file := range report.Files {
+		actions[file.Name] = file
+	}
+	if file := actions["Images/logo.png"]; file.Action != ActionConverted || file.NewName != "Images/logo.jpg" {
+		t.Errorf("Expected logo to be converted to JPEG, got %+v", file)
+	}
+	// The converted wide.png must not replace the existing wide.jpg
+	if file := actions["Images/wide.png"]; file.Action != ActionConverted || file.NewName != "Images/wide_2.jpg" {
+		t.Errorf("Expected wide.png to be converted under a free name, got %+v", file)
+	}
+	if file := actions["Images/wide_copy.png"]; file.Action != ActionDuplicate {
+		t.Errorf("Expected wide_copy.png to be a duplicate, got %+v", file)
+	}
+	if file := actions["Images/unused.png"]; file.Action != ActionUnreferenced {
+		t.Errorf("Expected unused.png to be removed, got %+v", file)
+	}
+	if file := actions["Images/wide.jpg"]; file.Action != ActionUnreferenced {
+		t.Errorf("Expected wide.jpg to be removed, got %+v", file)
+	}
+	if file := actions["Images/alpha.png"]; file.NewName != "Images/alpha.png" {
+		t.Errorf("Expected transparent PNG to stay PNG, got %+v", file)
+	}
+
+	reader, err := siq.NewSIQReader(output)
+	if err != nil {
+		t.Fatal("Failed to open optimized package:", err)
+	}
+	defer reader.Close()
+	pkg, err := reader.Read()
+	if err != nil {
+		t.Fatal("Failed to read optimized package:", err)
+	}
+
+	question := pkg.Rounds[0].Themes[0].Questions[0]
+	if value := question.GetQuestionContent()[0].Value; value != "wide_2.jpg" {
+		t.Errorf("Expected reference to the converted image, got %s", value)
+	}
+	if value := question.AnswerContent()[0].Value; value != "wide_2.jpg" {
+		t.Errorf("Expected duplicate to reference the kept file, got %s", value)
+	}
+	if pkg.Logo != "@logo.jpg" {
+		t.Errorf("Expected logo reference to be rewritten, got %s", pkg.Logo)
+	}
+
+	file, err := reader.GetFile("Images/wide_2.jpg")
+	if err != nil {
+		t.Fatal("Expected converted image in package:", err)
+	}
+	rc, _ := file.Open()
+	config, format, err := image.DecodeConfig(rc)
+	rc.Close()
+	if err != nil || format != "jpeg" || config.Width != 200 || config.Height != 50 {
+		t.Errorf("Expected a 200x50 JPEG, got %s %dx%d (%v)", format, config.Width, config.Height, err)
+	}
+
+	audio, err := reader.GetFile("Audio/sound.mp3")
+	if err != nil || audio.Method != zip.Store {
+		t.Errorf("Expected audio to be stored without compression, got %+v (%v)", audio, err)
+	}
+	if len(reader.ListFiles()) != 5 {
+		t.Errorf("Expected 5 files in optimized package, got %v", reader.ListFiles())
+	}
+}
+
+func TestOptimizeNonCanonicalFolders(t *testing.T) {
+	const contentXML = `<?xml version="1.0" encoding="utf-8"?>
+<package id="opt" name="Optimize" version="5">
+	<round name="Round 1">
+		<theme name="Theme 1">
+			<question price="100">
+				<params>
+					<param name="question" type="content">
+						<item type="image" isRef="True">pic.png</item>
+						<item type="audio" isRef="True">clip.mp3</item>
+						<item type="video" isRef="True">clip.mp4</item>
+					</param>
+				</params>
+				<right><answer>Answer</answer></right>
+			</question>
+		</theme>
+	</round>
+</package>`
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.siq")
+	clip := bytes.Repeat([]byte("clip"), 100)
+	siqtest.WriteArchive(t, input, map[string][]byte{
+		"content.xml":    []byte(contentXML),
+		"images/pic.png": encodeTestPNG(t, 10, 10, 255),
+		"Audio/clip.mp3": clip,
+		"Video/clip.mp4": clip,
+	})
+	output := filepath.Join(dir, "out.siq")
+
+	report, err := Optimize(input, output, DefaultOptions())
+	if err != nil {
+		t.Fatal("Failed to optimize package:", err)
+	}
+
+	actions := make(map[string]FileReport)
+	for _, file := range report.Files {
+		actions[file.Name] = file
+	}
+	if file := actions["Images/pic.png"]; file.Action == ActionUnreferenced || file.NewName != "Images/pic.png" {
+		t.Errorf("Expected image in a lower-case folder to be kept, got %+v", file)
+	}
+	// Identical files in different media folders are not duplicates of each other
+	if file := actions["Video/clip.mp4"]; file.Action != ActionKept {
+		t.Errorf("Expected video to be kept, got %+v", file)
+	}
+
+	reader, err := siq.NewSIQReader(output)
+	if err != nil {
+		t.Fatal("Failed to open optimized package:", err)
+	}
+	defer reader.Close()
+	for _, name := range []string{"Images/pic.png", "Audio/clip.mp3", "Video/clip.mp4"} {
+		if _, err := reader.GetFile(name); err != nil {
+			t.Errorf("Expected %s in optimized package: %v", name, err)
+		}
+	}
+}
+
+func TestOptimizeInPlace(t *testing.T) {
+	dir := t.TempDir()
+	input := createTestPackage(t, dir)
+
+	// A failed write keeps the input and leaves no output behind
+	options := DefaultOptions()
+	options.Level = 10
+	if _, err := Optimize(input, filepath.Join(dir, "out.siq"), options); err == nil {
+		t.Error("Expected error for an invalid compression level")
+	}
+	if _, err := Optimize(input, input, options); err == nil {
+		t.Error("Expected error for an invalid compression level")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected only the input to remain, got %d files", len(entries))
+	}
+
+	info, err := os.Stat(input)
+	if err != nil {
+		t.Fatal("Failed to stat input:", err)
+	}
+	report, err := Optimize(input, input, DefaultOptions())
+	if err != nil {
+		t.Fatal("Failed to optimize package in place:", err)
+	}
+	if report.OldSize != info.Size() {
+		t.Errorf("Expected old size %d, got %d", info.Size(), report.OldSize)
+	}
+	reader, err := siq.NewSIQReader(input)
+	if err != nil {
+		t.Fatal("Failed to open optimized package:", err)
+	}
+	defer reader.Close()
+	if _, err := reader.GetFile("Images/unused.png"); err == nil {
+		t.Error("Expected unreferenced file to be removed in place")
+	}
+	if _, err := reader.GetFile("Audio/sound.mp3"); err != nil {
+		t.Errorf("Expected audio to be kept in place: %v", err)
+	}
+}
+
+func TestOptimizeImageKeepsSmallerOriginal(t *testing.T) {
+	data := encodeTestPNG(t, 20, 20, 255)
+	options := DefaultOptions()
+
+	if result, ok := optimizeImage(data, options); ok && len(result.data) >= len(data) {
+		t.Errorf("Expected re-encoded image to be smaller, got %d >= %d bytes", len(result.data), len(data))
+	}
+	if _, ok := optimizeImage([]byte("not an image"), options); ok {
+		t.Error("Expected invalid image to be kept")
+	}
+}
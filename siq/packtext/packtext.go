@@ -0,0 +1,193 @@
+// Package packtext serializes packages into a compact text form for
+// language model prompts. Each question takes a single line with its ID,
+// price, content and answers; media is shown by file name. Text that does
+// not fit a token budget is split into chunks that repeat the round and
+// theme headers, so every chunk can be understood on its own.
+package packtext
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// charsPerToken is the average number of characters of a token used to
+// estimate prompt sizes without a tokenizer
+const charsPerToken = 4
+
+// minQuestionTokens is the smallest budget left for a question line after
+// the headers, questions are truncated down to it
+const minQuestionTokens = 16
+
+// Options configure serialization
+type Options struct {
+	// MaxTokens is the estimated token budget of a chunk, 0 puts everything
+	// into a single chunk
+	MaxTokens int
+	// HideAnswers leaves out right and wrong answers
+	HideAnswers bool
+	// HidePrices leaves out question prices
+	HidePrices bool
+}
+
+// EstimateTokens returns a rough token count of text
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// Render serializes the questions of the package within scope into chunks
+// that fit the token budget. Questions longer than the budget are truncated.
+func Render(pkg *siq.Package, scope Scope, options Options) ([]string, error) {
+	header := PackageLine(pkg)
+	var chunks []string
+	var chunk strings.Builder
+	var roundLine, themeLine string
+	lastRound, lastTheme := -1, -1
+	chunkTokens := 0
+
+	startChunk := func() {
+		if chunk.Len() > 0 {
+			chunks = append(chunks, chunk.String())
+			chunk.Reset()
+		}
+		chunk.WriteString(header)
+		chunk.WriteString(roundLine)
+		chunk.WriteString(themeLine)
+		chunkTokens = EstimateTokens(chunk.String())
+	}
+
+	err := pkg.Walk(func(ref siq.QuestionRef, question *siq.Question) error {
+		if !scope.Contains(ref) {
+			return nil
+		}
+
+		// Headers of a new round or theme are added to the current chunk
+		var headers string
+		if ref.RoundIndex != lastRound {
+			roundLine = fmt.Sprintf("Round %d: %s\n", ref.RoundIndex+1, ref.RoundName)
+			headers += roundLine
+			lastRound, lastTheme = ref.RoundIndex, -1
+		}
+		if ref.ThemeIndex != lastTheme {
+			themeLine = fmt.Sprintf("Theme %d.%d: %s\n", ref.RoundIndex+1, ref.ThemeIndex+1, ref.ThemeName)
+			headers += themeLine
+			lastTheme = ref.ThemeIndex
+		}
+
+		line := QuestionLine(ref, question, options) + "\n"
+		if chunk.Len() == 0 {
+			startChunk()
+			headers = ""
+		}
+		if options.MaxTokens <= 0 {
+			chunk.WriteString(headers + line)
+			return nil
+		}
+
+		if chunkTokens+EstimateTokens(headers+line) > options.MaxTokens && chunkTokens > EstimateTokens(header+roundLine+themeLine) {
+			startChunk()
+			headers = ""
+		}
+		available := options.MaxTokens - chunkTokens - EstimateTokens(headers)
+		if available < minQuestionTokens {
+			return fmt.Errorf("token budget %d is too small for the headers", options.MaxTokens)
+		}
+		line = truncate(line, available)
+		chunk.WriteString(headers + line)
+		chunkTokens += EstimateTokens(headers + line)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if chunk.Len() == 0 {
+		return nil, fmt.Errorf("scope %s matches no questions", scope)
+	}
+	return append(chunks, chunk.String()), nil
+}
+
+// PackageLine returns the header line of a package with its name and language
+func PackageLine(pkg *siq.Package) string {
+	line := "Package: " + pkg.Name
+	if pkg.Language != "" {
+		line += " (language: " + pkg.Language + ")"
+	}
+	return line + "\n"
+}
+
+// QuestionLine renders a question as a single line, for example
+// "[r1/t2/q3] 300: Who wrote it? [image: book.png] | answer: Pushkin | wrong: Gogol".
+// The token budget of options is not applied.
+func QuestionLine(ref siq.QuestionRef, question *siq.Question, options Options) string {
+	first := fmt.Sprintf("[%s] %s: %s", ref.ID(), ref.Price, ItemsText(question.GetQuestionContent()))
+	if options.HidePrices {
+		first = fmt.Sprintf("[%s] %s", ref.ID(), ItemsText(question.GetQuestionContent()))
+	}
+	parts := []string{first}
+	if question.Type != "" && question.Type != siq.QuestionTypeSimple {
+		parts = append(parts, "type: "+question.Type)
+	}
+	if theme := question.Theme(); theme != "" {
+		parts = append(parts, "announced theme: "+theme)
+	}
+	if options := question.AnswerOptions(); len(options) > 0 {
+		texts := make([]string, len(options))
+		for i, option := range options {
+			texts[i] = option.Label + ") " + ItemsText(option.Items)
+		}
+		parts = append(parts, "options: "+strings.Join(texts, "; "))
+	}
+	if !options.HideAnswers {
+		if len(question.Right) > 0 {
+			parts = append(parts, "answer: "+strings.Join(question.Right, "; "))
+		}
+		if len(question.Wrong) > 0 {
+			parts = append(parts, "wrong: "+strings.Join(question.Wrong, "; "))
+		}
+		if answer := question.AnswerContent(); len(answer) > 0 {
+			parts = append(parts, "shown answer: "+ItemsText(answer))
+		}
+	}
+	if question.Info != nil && len(question.Info.Comments) > 0 {
+		parts = append(parts, "comment: "+strings.Join(question.Info.Comments, " "))
+	}
+	return flatten(strings.Join(parts, " | "))
+}
+
+// ItemsText renders content items on a single line. Text items are shown as
+// is, media as "[type: file name]" and markers are left out.
+func ItemsText(items []siq.ContentItem) string {
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		switch item.GetType() {
+		case siq.ContentTypeText:
+			parts = append(parts, item.Value)
+		case siq.ContentTypeMarker:
+		default:
+			value := item.Value
+			if item.IsRef {
+				value = path.Base(siq.DecodeFileName(value))
+			}
+			parts = append(parts, fmt.Sprintf("[%s: %s]", item.GetType(), value))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// flatten joins the lines of text with spaces
+func flatten(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// truncate shortens a line ending with a newline to at most tokens
+// estimated tokens, marking the cut with an ellipsis
+func truncate(line string, tokens int) string {
+	if EstimateTokens(line) <= tokens {
+		return line
+	}
+	runes := []rune(strings.TrimSuffix(line, "\n"))
+	return string(runes[:tokens*charsPerToken-2]) + "…\n"
+}
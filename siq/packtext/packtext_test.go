@@ -0,0 +1,153 @@
+package packtext
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// textQuestion creates a simple question with text content
+func textQuestion(price int, text string, right ...string) siq.Question {
+	return siq.Question{
+		Type:      siq.QuestionTypeSimple,
+		BasePrice: price,
+		Params: []siq.Param{{
+			Name:  siq.ParamNameQuestion,
+			Type:  siq.ParamTypeContent,
+			Items: []siq.ContentItem{{Type: siq.ContentTypeText, Value: text}},
+		}},
+		Right: right,
+	}
+}
+
+func testPackage() *siq.Package {
+	media := textQuestion(200, "Whose portrait?", "Pushkin")
+	media.Params[0].Items = append(media.Params[0].Items, siq.ContentItem{Type: siq.ContentTypeImage, Value: "Alexander%20Pushkin.jpg", IsRef: true})
+	media.Wrong = []string{"Gogol"}
+
+	return &siq.Package{
+		Name:     "Pack",
+		Language: "en",
+		Rounds: []siq.Round{
+			{Name: "Round 1", Themes: []siq.Theme{
+				{Name: "Planets", Questions: []siq.Question{
+					textQuestion(100, "Largest\nplanet", "Jupiter"),
+					textQuestion(200, "Red planet", "Mars"),
+				}},
+				{Name: "Poets", Questions: []siq.Question{textQuestion(100, "Wrote Onegin", "Pushkin"), media}},
+			}},
+			{Name: "Final", Themes: []siq.Theme{{Name: "Rivers", Questions: []siq.Question{textQuestion(0, "Longest river", "Nile")}}}},
+		},
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	tests := map[string]Scope{
+		"":         {},
+		"all":      {},
+		"r2":       {Round: 2},
+		"r1/t2":    {Round: 1, Theme: 2},
+		"R1/T2/Q3": {Round: 1, Theme: 2, Question: 3},
+	}
+	for text, expected := range tests {
+		scope, err := ParseScope(text)
+		if err != nil || scope != expected {
+			t.Errorf("Expected %q to parse as %+v, got %+v (%v)", text, expected, scope, err)
+		}
+	}
+	for _, text := range []string{"t1", "r0", "r1/q2", "r1/t1/q1/x", "round1"} {
+		if _, err := ParseScope(text); err == nil {
+			t.Errorf("Expected error for scope %q", text)
+		}
+	}
+	if text := (Scope{Round: 1, Theme: 2}).String(); text != "r1/t2" {
+		t.Errorf("Expected r1/t2, got %s", text)
+	}
+}
+
+func TestRender(t *testing.T) {
+	chunks, err := Render(testPackage(), Scope{}, Options{})
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	if len(chunks) != 1 {
+		t.Fatalf("Expected a single chunk without a budget, got %d", len(chunks))
+	}
+
+	expected := `Package: Pack (language: en)
+Round 1: Round 1
+Theme 1.1: Planets
+[r1/t1/q1] 100: Largest planet | answer: Jupiter
+[r1/t1/q2] 200: Red planet | answer: Mars
+Theme 1.2: Poets
+[r1/t2/q1] 100: Wrote Onegin | answer: Pushkin
+[r1/t2/q2] 200: Whose portrait? [image: Alexander Pushkin.jpg] | answer: Pushkin | wrong: Gogol
+Round 2: Final
+Theme 2.1: Rivers
+[r2/t1/q1] 0: Longest river | answer: Nile
+`
+	if chunks[0] != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, chunks[0])
+	}
+
+	chunks, err = Render(testPackage(), Scope{Round: 1, Theme: 2, Question: 2}, Options{HideAnswers: true})
+	if err != nil {
+		t.Fatalf("Failed to render question: %v", err)
+	}
+	if !strings.HasSuffix(chunks[0], "Theme 1.2: Poets\n[r1/t2/q2] 200: Whose portrait? [image: Alexander Pushkin.jpg]\n") {
+		t.Errorf("Expected a single question without answers, got:\n%s", chunks[0])
+	}
+
+	chunks, err = Render(testPackage(), Scope{Round: 1, Theme: 2, Question: 2}, Options{HidePrices: true})
+	if err != nil {
+		t.Fatalf("Failed to render question: %v", err)
+	}
+	if !strings.HasSuffix(chunks[0], "[r1/t2/q2] Whose portrait? [image: Alexander Pushkin.jpg] | answer: Pushkin | wrong: Gogol\n") {
+		t.Errorf("Expected a single question without price, got:\n%s", chunks[0])
+	}
+
+	if _, err := Render(testPackage(), Scope{Round: 3}, Options{}); err == nil {
+		t.Errorf("Expected error for a scope matching no questions")
+	}
+}
+
+func TestRenderChunks(t *testing.T) {
+	pkg := testPackage()
+	chunks, err := Render(pkg, Scope{}, Options{MaxTokens: 40})
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	if len(chunks) < 3 {
+		t.Fatalf("Expected several chunks for a small budget, got %d", len(chunks))
+	}
+
+	questions := 0
+	for _, chunk := range chunks {
+		if tokens := EstimateTokens(chunk); tokens > 40 {
+			t.Errorf("Expected chunk within budget, got %d tokens:\n%s", tokens, chunk)
+		}
+		lines := strings.Split(strings.TrimSuffix(chunk, "\n"), "\n")
+		if lines[0] != "Package: Pack (language: en)" || !strings.HasPrefix(lines[1], "Round ") || !strings.HasPrefix(lines[2], "Theme ") {
+			t.Errorf("Expected chunk to start with package, round and theme headers, got:\n%s", chunk)
+		}
+		questions += strings.Count(chunk, "\n[r")
+	}
+	if questions != pkg.GetQuestionCount() {
+		t.Errorf("Expected every question once across chunks, got %d", questions)
+	}
+
+	long := textQuestion(100, strings.Repeat("very long question ", 50), "Answer")
+	pkg.Rounds[0].Themes[0].Questions[0] = long
+	chunks, err = Render(pkg, Scope{Round: 1, Theme: 1, Question: 1}, Options{MaxTokens: 40})
+	if err != nil {
+		t.Fatalf("Failed to render long question: %v", err)
+	}
+	if len(chunks) != 1 || EstimateTokens(chunks[0]) > 40 || !strings.HasSuffix(chunks[0], "…\n") {
+		t.Errorf("Expected long question to be truncated into one chunk, got %q", chunks)
+	}
+
+	if _, err := Render(pkg, Scope{}, Options{MaxTokens: 10}); err == nil {
+		t.Errorf("Expected error for a budget smaller than the headers")
+	}
+}
@@ -0,0 +1,65 @@
+package packtext
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// Scope selects the part of a package to serialize. Indexes are one-based
+// as in question IDs, zero selects everything at that level.
+type Scope struct {
+	Round    int
+	Theme    int
+	Question int
+}
+
+// ParseScope parses a scope in question ID form: "" or "all" for the whole
+// package, "r1" for a round, "r1/t2" for a theme and "r1/t2/q3" for a question
+func ParseScope(text string) (Scope, error) {
+	var scope Scope
+	text = strings.TrimSpace(text)
+	if text == "" || text == "all" {
+		return scope, nil
+	}
+
+	parts := strings.Split(text, "/")
+	if len(parts) > 3 {
+		return scope, fmt.Errorf("invalid scope %q", text)
+	}
+	targets := []*int{&scope.Round, &scope.Theme, &scope.Question}
+	for i, part := range parts {
+		prefix := "rtq"[i : i+1]
+		number, ok := strings.CutPrefix(strings.ToLower(part), prefix)
+		value, err := strconv.Atoi(number)
+		if !ok || err != nil || value < 1 {
+			return scope, fmt.Errorf("invalid scope %q: expected %s<number> at %q", text, prefix, part)
+		}
+		*targets[i] = value
+	}
+	return scope, nil
+}
+
+// Contains reports whether the question at ref is within the scope
+func (s Scope) Contains(ref siq.QuestionRef) bool {
+	return (s.Round == 0 || s.Round == ref.RoundIndex+1) &&
+		(s.Theme == 0 || s.Theme == ref.ThemeIndex+1) &&
+		(s.Question == 0 || s.Question == ref.QuestionIndex+1)
+}
+
+// String returns the scope in the form accepted by ParseScope
+func (s Scope) String() string {
+	if s.Round == 0 {
+		return "all"
+	}
+	text := fmt.Sprintf("r%d", s.Round)
+	if s.Theme > 0 {
+		text += fmt.Sprintf("/t%d", s.Theme)
+	}
+	if s.Question > 0 {
+		text += fmt.Sprintf("/q%d", s.Question)
+	}
+	return text
+}
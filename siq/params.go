@@ -0,0 +1,282 @@
+package siq
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NumberSet represents a range of numbers defined by minimum, maximum and step
+type NumberSet struct {
+	Minimum int `xml:"minimum,attr"`
+	Maximum int `xml:"maximum,attr"`
+	Step    int `xml:"step,attr"`
+}
+
+// Contains reports whether the value belongs to the number set
+func (n NumberSet) Contains(value int) bool {
+	if value < n.Minimum || value > n.Maximum {
+		return false
+	}
+	if n.Step <= 0 {
+		return true
+	}
+	return (value-n.Minimum)%n.Step == 0
+}
+
+// QuestionPrice represents a question price which is either a fixed value
+// or a number set the player selects from (secret questions)
+type QuestionPrice struct {
+	Value int
+	Set   *NumberSet
+}
+
+// IsSet reports whether the price is selected from a number set
+func (p QuestionPrice) IsSet() bool {
+	return p.Set != nil
+}
+
+// String returns a human-readable representation of the price
+func (p QuestionPrice) String() string {
+	if p.Set == nil {
+		return strconv.Itoa(p.Value)
+	}
+	if p.Set.Step > 0 && p.Set.Minimum != p.Set.Maximum {
+		return fmt.Sprintf("%d-%d (step %d)", p.Set.Minimum, p.Set.Maximum, p.Set.Step)
+	}
+	return fmt.Sprintf("%d-%d", p.Set.Minimum, p.Set.Maximum)
+}
+
+// GetType returns the parameter type, defaulting to simple when it is not set
+func (p *Param) GetType() string {
+	if p.Type == "" {
+		return ParamTypeSimple
+	}
+	return p.Type
+}
+
+// GetNumberSet returns the number set of a numberSet parameter
+func (p *Param) GetNumberSet() *NumberSet {
+	if p.NumberSet != nil {
+		return p.NumberSet
+	}
+	if p.Minimum != 0 || p.Maximum != 0 || p.Step != 0 {
+		return &NumberSet{Minimum: p.Minimum, Maximum: p.Maximum, Step: p.Step}
+	}
+	return nil
+}
+
+// Param returns the parameter at the given path or nil if it does not exist.
+// Path segments are separated by "/" and address nested group parameters,
+// for example "answerOptions/B". A segment that does not match any parameter
+// name but is a number selects the nested parameter by its zero-based index.
+func (q *Question) Param(path string) *Param {
+	params := q.Params
+	var found *Param
+	for _, segment := range strings.Split(path, "/") {
+		found = findParam(params, segment)
+		if found == nil {
+			return nil
+		}
+		params = found.Params
+	}
+	return found
+}
+
+// findParam finds a parameter by name or by zero-based index
+func findParam(params []Param, segment string) *Param {
+	for i := range params {
+		if params[i].Name == segment {
+			return &params[i]
+		}
+	}
+	if index, err := strconv.Atoi(segment); err == nil && index >= 0 && index < len(params) {
+		return &params[index]
+	}
+	return nil
+}
+
+// Theme returns the theme announced for a secret question
+func (q *Question) Theme() string {
+	return q.GetParamValue(ParamNameTheme)
+}
+
+// SelectionMode returns who can receive a secret question
+func (q *Question) SelectionMode() string {
+	return q.GetParamValue(ParamNameSelectionMode)
+}
+
+// Price returns the effective question price. The price parameter of
+// secret questions overrides the price attribute of the question.
+func (q *Question) Price() QuestionPrice {
+	param := q.Param(ParamNamePrice)
+	if param != nil {
+		if set := param.GetNumberSet(); set != nil {
+			if set.Minimum == set.Maximum {
+				return QuestionPrice{Value: set.Minimum}
+			}
+			return QuestionPrice{Set: set}
+		}
+		if value, err := strconv.Atoi(strings.TrimSpace(param.Value)); err == nil {
+			return QuestionPrice{Value: value}
+		}
+	}
+	return QuestionPrice{Value: q.BasePrice}
+}
+
+// AnswerContent returns the content items displayed as the answer
+func (q *Question) AnswerContent() []ContentItem {
+	return q.GetParamItems(ParamNameAnswer)
+}
+
+// AnswerOption represents one of the choices of a select-type question
+type AnswerOption struct {
+	Label string
+	Items []ContentItem
+}
+
+// AnswerType returns how players answer the question, defaulting to text
+func (q *Question) AnswerType() string {
+	if answerType := q.GetParamValue(ParamNameAnswerType); answerType != "" {
+		return answerType
+	}
+	return AnswerTypeText
+}
+
+// AnswerOptions returns the choices of a select-type question in their original order.
+// Options without a name are labeled with letters by their position.
+func (q *Question) AnswerOptions() []AnswerOption {
+	group := q.Param(ParamNameAnswerOptions)
+	if group == nil {
+		return nil
+	}
+
+	options := make([]AnswerOption, 0, len(group.Params))
+	for i, param := range group.Params {
+		label := param.Name
+		if label == "" {
+			label = OptionLabel(i)
+		}
+		options = append(options, AnswerOption{Label: label, Items: param.Items})
+	}
+	return options
+}
+
+// IsRightOption reports whether the option label is listed among the right answers
+func (q *Question) IsRightOption(label string) bool {
+	for _, answer := range q.Right {
+		if strings.EqualFold(strings.TrimSpace(answer), label) {
+			return true
+		}
+	}
+	return false
+}
+
+// OptionLabel returns the letter label for a zero-based option index (A, B, ..., Z, AA, ...)
+func OptionLabel(index int) string {
+	label := ""
+	for index >= 0 {
+		label = string(rune('A'+index%26)) + label
+		index = index/26 - 1
+	}
+	return label
+}
+
+// QuestionParams represents decoded well-known parameters of a question
+type QuestionParams struct {
+	Question      []ContentItem
+	Answer        []ContentItem
+	Theme         string
+	SelectionMode string
+	Price         QuestionPrice
+	AnswerType    string
+	AnswerOptions []AnswerOption
+}
+
+// paramRule describes a well-known parameter expected by a question type
+type paramRule struct {
+	name      string
+	paramType string
+}
+
+// requiredParams lists parameters required by well-known question types
+// in addition to the question content
+var requiredParams = map[string][]paramRule{
+	QuestionTypeSecret: {
+		{ParamNameTheme, ParamTypeSimple},
+		{ParamNamePrice, ParamTypeNumberSet},
+		{ParamNameSelectionMode, ParamTypeSimple},
+	},
+	QuestionTypeSecretPublicPrice: {
+		{ParamNameTheme, ParamTypeSimple},
+		{ParamNamePrice, ParamTypeNumberSet},
+		{ParamNameSelectionMode, ParamTypeSimple},
+	},
+	QuestionTypeSecretNoQuestion: {
+		{ParamNameTheme, ParamTypeSimple},
+		{ParamNamePrice, ParamTypeNumberSet},
+		{ParamNameSelectionMode, ParamTypeSimple},
+	},
+}
+
+// DecodeParams decodes the well-known parameters of the question and
+// validates that the parameters required by its type are present
+func (q *Question) DecodeParams() (*QuestionParams, error) {
+	var errs []error
+
+	rules := requiredParams[q.Type]
+	if q.Type != QuestionTypeSecretNoQuestion {
+		rules = append([]paramRule{{ParamNameQuestion, ParamTypeContent}}, rules...)
+	}
+	for _, rule := range rules {
+		param := q.Param(rule.name)
+		if param == nil {
+			errs = append(errs, fmt.Errorf("missing required parameter %q", rule.name))
+			continue
+		}
+		if param.GetType() != rule.paramType {
+			errs = append(errs, fmt.Errorf("parameter %q has type %q, expected %q", rule.name, param.GetType(), rule.paramType))
+		}
+	}
+
+	if mode := q.SelectionMode(); mode != "" && mode != SelectionModeExceptCurrent && mode != SelectionModeAny {
+		errs = append(errs, fmt.Errorf("unknown selection mode %q", mode))
+	}
+
+	if q.AnswerType() == AnswerTypeSelect {
+		options := q.Param(ParamNameAnswerOptions)
+		switch {
+		case options == nil:
+			errs = append(errs, fmt.Errorf("missing required parameter %q", ParamNameAnswerOptions))
+		case options.GetType() != ParamTypeGroup:
+			errs = append(errs, fmt.Errorf("parameter %q has type %q, expected %q", ParamNameAnswerOptions, options.GetType(), ParamTypeGroup))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid %s question: %w", q.Type, err)
+	}
+
+	return &QuestionParams{
+		Question:      q.GetQuestionContent(),
+		Answer:        q.AnswerContent(),
+		Theme:         q.Theme(),
+		SelectionMode: q.SelectionMode(),
+		Price:         q.Price(),
+		AnswerType:    q.AnswerType(),
+		AnswerOptions: q.AnswerOptions(),
+	}, nil
+}
+
+// Validate checks the parameters of every question in the package
+func (p *Package) Validate() error {
+	var errs []error
+	p.Walk(func(ref QuestionRef, question *Question) error {
+		if _, err := question.DecodeParams(); err != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): %w", ref.ID(), ref, err))
+		}
+		return nil
+	})
+	return errors.Join(errs...)
+}
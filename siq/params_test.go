@@ -0,0 +1,246 @@
+package siq
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+const secretQuestionXML = `<question type="secret" price="100">
+	<params>
+		<param name="question" type="content">
+			<item type="text">Secret question</item>
+		</param>
+		<param name="theme">Cats</param>
+		<param name="selectionMode">exceptCurrent</param>
+		<param name="price" type="numberSet">
+			<numberSet minimum="100" maximum="500" step="100" />
+		</param>
+		<param name="answer" type="content">
+			<item type="image" isRef="True">cat.png</item>
+		</param>
+		<param name="extra" type="group">
+			<param name="first">one</param>
+			<param name="second">two</param>
+		</param>
+	</params>
+	<right>
+		<answer>Cat</answer>
+	</right>
+</question>`
+
+func decodeTestQuestion(t *testing.T, data string) *Question {
+	var question Question
+	if err := xml.Unmarshal([]byte(data), &question); err != nil {
+		t.Fatal("Failed to decode question:", err)
+	}
+	return &question
+}
+
+func TestQuestionTypedAccessors(t *testing.T) {
+	question := decodeTestQuestion(t, secretQuestionXML)
+
+	if question.Theme() != "Cats" {
+		t.Errorf("Expected theme 'Cats', got '%s'", question.Theme())
+	}
+	if question.SelectionMode() != SelectionModeExceptCurrent {
+		t.Errorf("Expected selection mode '%s', got '%s'", SelectionModeExceptCurrent, question.SelectionMode())
+	}
+
+	price := question.Price()
+	if !price.IsSet() {
+		t.Fatal("Expected price to be a number set")
+	}
+	if price.Set.Minimum != 100 || price.Set.Maximum != 500 || price.Set.Step != 100 {
+		t.Errorf("Unexpected number set %+v", *price.Set)
+	}
+	if price.String() != "100-500 (step 100)" {
+		t.Errorf("Unexpected price string '%s'", price.String())
+	}
+	if !price.Set.Contains(300) || price.Set.Contains(250) {
+		t.Error("Unexpected number set membership")
+	}
+
+	answer := question.AnswerContent()
+	if len(answer) != 1 || answer[0].Value != "cat.png" {
+		t.Errorf("Expected answer content 'cat.png', got %+v", answer)
+	}
+}
+
+func TestQuestionPriceFallback(t *testing.T) {
+	question := &Question{Type: QuestionTypeSimple, BasePrice: 200}
+	price := question.Price()
+	if price.IsSet() || price.Value != 200 {
+		t.Errorf("Expected fixed price 200, got %+v", price)
+	}
+}
+
+func TestQuestionParamPath(t *testing.T) {
+	question := decodeTestQuestion(t, secretQuestionXML)
+
+	if param := question.Param("extra/second"); param == nil || param.Value != "two" {
+		t.Errorf("Expected 'two' at 'extra/second', got %+v", param)
+	}
+	if param := question.Param("extra/0"); param == nil || param.Value != "one" {
+		t.Errorf("Expected 'one' at 'extra/0', got %+v", param)
+	}
+	if param := question.Param("extra/missing"); param != nil {
+		t.Errorf("Expected nil for missing param, got %+v", param)
+	}
+	if param := question.Param("theme/nested"); param != nil {
+		t.Errorf("Expected nil below a simple param, got %+v", param)
+	}
+}
+
+func TestDecodeParams(t *testing.T) {
+	question := decodeTestQuestion(t, secretQuestionXML)
+
+	params, err := question.DecodeParams()
+	if err != nil {
+		t.Fatal("Failed to decode params:", err)
+	}
+	if params.Theme != "Cats" || len(params.Question) != 1 || len(params.Answer) != 1 {
+		t.Errorf("Unexpected decoded params %+v", params)
+	}
+
+	// A secret question without its required params is invalid
+	invalid := &Question{
+		Type: QuestionTypeSecret,
+		Params: []Param{
+			{Name: ParamNameQuestion, Type: ParamTypeContent},
+			{Name: ParamNamePrice, Value: "100"},
+		},
+	}
+	_, err = invalid.DecodeParams()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, expected := range []string{`"theme"`, `"selectionMode"`, `expected "numberSet"`} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error to mention %s, got: %v", expected, err)
+		}
+	}
+
+	// Simple questions only require the question content
+	if _, err := (&Question{Type: QuestionTypeSimple}).DecodeParams(); err == nil {
+		t.Error("Expected error for missing question content")
+	}
+}
+
+func TestPackageValidate(t *testing.T) {
+	pkg := &Package{
+		Rounds: []Round{
+			{
+				Themes: []Theme{
+					{
+						Questions: []Question{
+							*decodeTestQuestion(t, secretQuestionXML),
+							{Type: QuestionTypeSecret},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := pkg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	if !strings.Contains(err.Error(), "r1/t1/q2") {
+		t.Errorf("Expected error location, got: %v", err)
+	}
+}
+
+const selectQuestionXML = `<question price="200">
+	<params>
+		<param name="question" type="content">
+			<item type="text">Pick the largest planet</item>
+		</param>
+		<param name="answerType">select</param>
+		<param name="answerOptions" type="group">
+			<param name="A" type="content">
+				<item type="text">Mars</item>
+			</param>
+			<param name="B" type="content">
+				<item type="text">Jupiter</item>
+			</param>
+			<param name="C" type="content">
+				<item type="image" isRef="True">venus.png</item>
+			</param>
+		</param>
+	</params>
+	<right>
+		<answer>B</answer>
+	</right>
+</question>`
+
+func TestAnswerOptions(t *testing.T) {
+	question := decodeTestQuestion(t, selectQuestionXML)
+
+	if question.AnswerType() != AnswerTypeSelect {
+		t.Errorf("Expected answer type '%s', got '%s'", AnswerTypeSelect, question.AnswerType())
+	}
+
+	options := question.AnswerOptions()
+	if len(options) != 3 {
+		t.Fatalf("Expected 3 options, got %d", len(options))
+	}
+	if options[1].Label != "B" || options[1].Items[0].Value != "Jupiter" {
+		t.Errorf("Unexpected option %+v", options[1])
+	}
+	if !question.IsRightOption("B") || question.IsRightOption("A") {
+		t.Error("Expected only option B to be right")
+	}
+
+	params, err := question.DecodeParams()
+	if err != nil {
+		t.Fatal("Failed to decode params:", err)
+	}
+	if len(params.AnswerOptions) != 3 {
+		t.Errorf("Expected 3 decoded options, got %d", len(params.AnswerOptions))
+	}
+
+	// Select questions without options are invalid
+	invalid := &Question{
+		Params: []Param{
+			{Name: ParamNameQuestion, Type: ParamTypeContent},
+			{Name: ParamNameAnswerType, Value: AnswerTypeSelect},
+		},
+	}
+	if _, err := invalid.DecodeParams(); err == nil || !strings.Contains(err.Error(), ParamNameAnswerOptions) {
+		t.Errorf("Expected missing answerOptions error, got: %v", err)
+	}
+}
+
+func TestParamItems(t *testing.T) {
+	question := decodeTestQuestion(t, selectQuestionXML)
+
+	var names []string
+	for name, item := range question.ParamItems() {
+		names = append(names, name+"="+item.Value)
+	}
+	expected := "question=Pick the largest planet,answerOptions/A=Mars,answerOptions/B=Jupiter,answerOptions/C=venus.png"
+	if strings.Join(names, ",") != expected {
+		t.Errorf("Expected items %s, got %s", expected, strings.Join(names, ","))
+	}
+
+	for item := range question.ContentItems() {
+		if item.IsRef {
+			item.Value = "mars.png"
+			break
+		}
+	}
+	if param := question.Param("answerOptions/C"); param.Items[0].Value != "mars.png" {
+		t.Errorf("Expected item to be modified in place, got %+v", param.Items)
+	}
+}
+
+func TestOptionLabel(t *testing.T) {
+	expected := map[int]string{0: "A", 1: "B", 25: "Z", 26: "AA", 27: "AB"}
+	for index, label := range expected {
+		if OptionLabel(index) != label {
+			t.Errorf("Expected label '%s' for %d, got '%s'", label, index, OptionLabel(index))
+		}
+	}
+}
@@ -2,10 +2,12 @@ package siq
 
 import (
 	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/xml"
 	"fmt"
 	"io"
-	"net/url"
+	"iter"
 	"os"
 	"path/filepath"
 	"strings"
@@ -28,11 +30,14 @@ type Package struct {
 	Rounds      []Round `xml:"round"`
 	// Version 4 compatibility
 	RoundsV4 []RoundV4 `xml:"rounds>round,omitempty"`
+	// FormatVersion is the detected format version of the source file (4 or 5)
+	FormatVersion int `xml:"-"`
 }
 
 // Round represents a round in the SIG pack (v5 format)
 type Round struct {
 	Name   string  `xml:"name,attr"`
+	Type   string  `xml:"type,attr,omitempty"`
 	Info   *Info   `xml:"info,omitempty"`
 	Themes []Theme `xml:"theme"`
 }
@@ -40,6 +45,7 @@ type Round struct {
 // RoundV4 represents a round in the SIG pack (v4 format)
 type RoundV4 struct {
 	Name   string    `xml:"name,attr"`
+	Type   string    `xml:"type,attr,omitempty"`
 	Info   *Info     `xml:"info,omitempty"`
 	Themes []ThemeV4 `xml:"themes>theme"`
 }
@@ -60,21 +66,36 @@ type ThemeV4 struct {
 
 // Question represents a question in a theme (v5 format)
 type Question struct {
-	Type   string   `xml:"type,attr"`
-	Params []Param  `xml:"params>param"`
-	Right  []string `xml:"right>answer"`
-	Wrong  []string `xml:"wrong>answer"`
-	Script *Script  `xml:"script,omitempty"`
-	Info   *Info    `xml:"info,omitempty"`
+	Type string `xml:"type,attr"`
+	// BasePrice is the nominal price from the price attribute, see Price for the effective price
+	BasePrice int      `xml:"price,attr,omitempty"`
+	Params    []Param  `xml:"params>param"`
+	Right     []string `xml:"right>answer"`
+	Wrong     []string `xml:"wrong>answer"`
+	Script    *Script  `xml:"script,omitempty"`
+	Info      *Info    `xml:"info,omitempty"`
 }
 
 // QuestionV4 represents a question in a theme (v4 format)
 type QuestionV4 struct {
-	Price    int       `xml:"price,attr"`
-	Scenario *Scenario `xml:"scenario,omitempty"`
-	Right    []string  `xml:"right>answer"`
-	Wrong    []string  `xml:"wrong>answer"`
-	Info     *Info     `xml:"info,omitempty"`
+	Price    int             `xml:"price,attr"`
+	Type     *QuestionTypeV4 `xml:"type,omitempty"`
+	Scenario *Scenario       `xml:"scenario,omitempty"`
+	Right    []string        `xml:"right>answer"`
+	Wrong    []string        `xml:"wrong>answer"`
+	Info     *Info           `xml:"info,omitempty"`
+}
+
+// QuestionTypeV4 represents a question type with its parameters in v4 format
+type QuestionTypeV4 struct {
+	Name   string    `xml:"name,attr"`
+	Params []ParamV4 `xml:"param"`
+}
+
+// ParamV4 represents a question type parameter in v4 format
+type ParamV4 struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
 }
 
 // Scenario represents a scenario in v4 format
@@ -99,6 +120,8 @@ type Param struct {
 	// For group type parameters
 	Params []Param `xml:"param,omitempty"`
 	// For numberSet type parameters
+	NumberSet *NumberSet `xml:"numberSet,omitempty"`
+	// Legacy numberSet representation with child elements
 	Minimum int `xml:"minimum,omitempty"`
 	Maximum int `xml:"maximum,omitempty"`
 	Step    int `xml:"step,omitempty"`
@@ -111,7 +134,39 @@ type ContentItem struct {
 	Duration      int    `xml:"duration,attr,omitempty"`
 	IsRef         bool   `xml:"isRef,attr,omitempty"`
 	Placement     string `xml:"placement,attr,omitempty"`
-	WaitForFinish bool   `xml:"waitForFinish,attr,omitempty"`
+	WaitForFinish *bool  `xml:"waitForFinish,attr,omitempty"`
+}
+
+// GetType returns the item type, defaulting to text when it is not set
+func (c *ContentItem) GetType() string {
+	if c.Type == "" {
+		return ContentTypeText
+	}
+	return c.Type
+}
+
+// GetPlacement returns the item placement, defaulting to screen when it is not set
+func (c *ContentItem) GetPlacement() string {
+	if c.Placement == "" {
+		return PlacementScreen
+	}
+	return c.Placement
+}
+
+// GetWaitForFinish reports whether playback waits for the item to finish (true when not set)
+func (c *ContentItem) GetWaitForFinish() bool {
+	if c.WaitForFinish == nil {
+		return true
+	}
+	return *c.WaitForFinish
+}
+
+// normalize fills attributes missing in the XML with their spec defaults
+func (c *ContentItem) normalize() {
+	c.Type = c.GetType()
+	c.Placement = c.GetPlacement()
+	waitForFinish := c.GetWaitForFinish()
+	c.WaitForFinish = &waitForFinish
 }
 
 // Script represents a script for complex scenarios
@@ -183,6 +238,13 @@ func (r *SIQReader) Read() (*Package, error) {
 		return nil, err
 	}
 
+	// Convert v4 rounds so consumers always work with the v5 structure
+	r.pkg.FormatVersion = r.version
+	r.pkg.ConvertV4()
+
+	// Apply spec defaults so consumers never see missing attributes
+	r.pkg.Normalize()
+
 	return r.pkg, nil
 }
 
@@ -204,29 +266,16 @@ func (r *SIQReader) parseContent(file *zip.File) error {
 	}
 	defer rc.Close()
 
-	// First, try to detect the version by reading the XML declaration
-	content, err := io.ReadAll(rc)
+	// Detect the version from the root element so the content is decoded in a single pass
+	decoder := xml.NewDecoder(rc)
+	root, err := rootElement(decoder)
 	if err != nil {
-		return fmt.Errorf("failed to read content.xml: %w", err)
-	}
-
-	// Check if it's version 4 (has xmlns="http://vladimirkhil.com/ygpackage3.0.xsd")
-	if strings.Contains(string(content), `xmlns="http://vladimirkhil.com/ygpackage3.0.xsd"`) {
-		r.version = 4
-	} else {
-		r.version = 5
+		return err
 	}
+	r.version = detectVersion(root)
 
-	// Parse based on version
-	decoder := xml.NewDecoder(strings.NewReader(string(content)))
-	if r.version == 4 {
-		if err := decoder.Decode(&r.pkg); err != nil {
-			return fmt.Errorf("failed to decode v4 XML: %w", err)
-		}
-	} else {
-		if err := decoder.Decode(&r.pkg); err != nil {
-			return fmt.Errorf("failed to decode v5 XML: %w", err)
-		}
+	if err := decoder.DecodeElement(&r.pkg, &root); err != nil {
+		return fmt.Errorf("failed to decode v%d XML: %w", r.version, err)
 	}
 
 	return nil
@@ -242,14 +291,15 @@ func (r *SIQReader) Close() error {
 
 // GetFile retrieves a file from the SIQ archive
 func (r *SIQReader) GetFile(filePath string) (*zip.File, error) {
-	// Handle URI-encoded file names
-	decodedPath, err := url.QueryUnescape(filePath)
-	if err != nil {
-		decodedPath = filePath
+	for _, file := range r.zipReader.File {
+		if file.Name == filePath {
+			return file, nil
+		}
 	}
-
+	// Either the archive entry or the requested path may be URI-encoded
+	decodedPath := DecodeFileName(filePath)
 	for _, file := range r.zipReader.File {
-		if file.Name == decodedPath || file.Name == filePath {
+		if DecodeFileName(file.Name) == decodedPath {
 			return file, nil
 		}
 	}
@@ -298,11 +348,63 @@ func (r *SIQReader) ExtractFile(filePath, destPath string) error {
 	return nil
 }
 
+// HashFile returns the hex-encoded SHA-256 and the uncompressed size of a file in the SIQ archive
+func (r *SIQReader) HashFile(filePath string) (string, int64, error) {
+	file, err := r.GetFile(filePath)
+	if err != nil {
+		return "", 0, err
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open file %s: %w", filePath, err)
+	}
+	defer rc.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, rc)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to hash file %s: %w", filePath, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
 // GetVersion returns the detected SIQ format version
 func (r *SIQReader) GetVersion() int {
 	return r.version
 }
 
+// Normalize fills attributes that were omitted in the XML with the defaults
+// defined by the v5 spec (question type "simple", content item type "text",
+// placement "screen" and waitForFinish true)
+func (p *Package) Normalize() {
+	for i := range p.Rounds {
+		for j := range p.Rounds[i].Themes {
+			for k := range p.Rounds[i].Themes[j].Questions {
+				p.Rounds[i].Themes[j].Questions[k].normalize()
+			}
+		}
+	}
+}
+
+// normalize fills the question type and content item attributes with their spec defaults
+func (q *Question) normalize() {
+	if q.Type == "" {
+		q.Type = QuestionTypeSimple
+	}
+	normalizeParams(q.Params)
+}
+
+// normalizeParams normalizes content items of params and nested group params
+func normalizeParams(params []Param) {
+	for i := range params {
+		for j := range params[i].Items {
+			params[i].Items[j].normalize()
+		}
+		normalizeParams(params[i].Params)
+	}
+}
+
 // GetQuestionCount returns the total number of questions in the package
 func (p *Package) GetQuestionCount() int {
 	count := 0
@@ -346,82 +448,56 @@ func (p *Package) GetThemeCount() int {
 	return count
 }
 
-// GetAllQuestions returns all questions from the package (converted to v5 format)
-func (p *Package) GetAllQuestions() []Question {
-	var questions []Question
+// Questions returns an iterator over all questions of the package (v4 questions converted to v5 format)
+func (p *Package) Questions() iter.Seq2[int, Question] {
+	return func(yield func(int, Question) bool) {
+		index := 0
 
-	// Add v5 questions
-	for _, round := range p.Rounds {
-		for _, theme := range round.Themes {
-			questions = append(questions, theme.Questions...)
+		for _, round := range p.Rounds {
+			for _, theme := range round.Themes {
+				for _, question := range theme.Questions {
+					if !yield(index, question) {
+						return
+					}
+					index++
+				}
+			}
 		}
-	}
 
-	// Convert v4 questions to v5 format
-	for _, round := range p.RoundsV4 {
-		for _, theme := range round.Themes {
-			for _, qv4 := range theme.Questions {
-				question := convertV4ToV5Question(qv4)
-				questions = append(questions, question)
+		for _, round := range p.RoundsV4 {
+			for _, theme := range round.Themes {
+				for _, qv4 := range theme.Questions {
+					if !yield(index, convertV4ToV5Question(qv4)) {
+						return
+					}
+					index++
+				}
 			}
 		}
 	}
+}
 
+// GetAllQuestions returns all questions from the package (converted to v5 format)
+func (p *Package) GetAllQuestions() []Question {
+	var questions []Question
+	for _, question := range p.Questions() {
+		questions = append(questions, question)
+	}
 	return questions
 }
 
 // GetQuestionsByType returns all questions of a specific type
 func (p *Package) GetQuestionsByType(questionType string) []Question {
 	var questions []Question
-	allQuestions := p.GetAllQuestions()
-
-	for _, question := range allQuestions {
+	p.Walk(func(ref QuestionRef, question *Question) error {
 		if question.Type == questionType {
-			questions = append(questions, question)
+			questions = append(questions, *question)
 		}
-	}
-
+		return nil
+	})
 	return questions
 }
 
-// ConvertV4ToV5Question converts a v4 question to v5 format (exported version)
-func ConvertV4ToV5Question(qv4 QuestionV4) Question {
-	return convertV4ToV5Question(qv4)
-}
-
-// convertV4ToV5Question converts a v4 question to v5 format
-func convertV4ToV5Question(qv4 QuestionV4) Question {
-	question := Question{
-		Type:  "simple", // Default type for v4 questions
-		Right: qv4.Right,
-		Wrong: qv4.Wrong,
-		Info:  qv4.Info,
-	}
-
-	// Convert scenario atoms to content items
-	if qv4.Scenario != nil {
-		var items []ContentItem
-		for _, atom := range qv4.Scenario.Atoms {
-			item := ContentItem{
-				Type:     atom.Type,
-				Value:    atom.Content,
-				Duration: atom.Duration,
-			}
-			items = append(items, item)
-		}
-
-		// Create a question parameter with the content items
-		param := Param{
-			Name:  "question",
-			Type:  "content",
-			Items: items,
-		}
-		question.Params = append(question.Params, param)
-	}
-
-	return question
-}
-
 // GetQuestionContent returns the content items for a question
 func (q *Question) GetQuestionContent() []ContentItem {
 	for _, param := range q.Params {
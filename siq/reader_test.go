@@ -6,8 +6,44 @@ import (
 	"testing"
 )
 
+// testContentXML is a sample v5 content.xml
+const testContentXML = `<?xml version="1.0" encoding="UTF-8"?>
+<package id="test-package" name="Test Package" version="5.0" difficulty="5" language="en">
+<info>
+	<authors>
+		<author>Test Author</author>
+	</authors>
+	<sources>
+		<source>Test Source</source>
+	</sources>
+</info>
+<round name="Round 1">
+	<theme name="Test Theme">
+		<question type="simple">
+			<params>
+				<param name="question" type="content">
+					<item type="text">What is 2+2?</item>
+				</param>
+			</params>
+			<right>
+				<answer>4</answer>
+			</right>
+			<wrong>
+				<answer>3</answer>
+				<answer>5</answer>
+			</wrong>
+		</question>
+	</theme>
+</round>
+</package>`
+
 // createTestSIQFile creates a test SIQ file for testing
 func createTestSIQFile(t *testing.T) string {
+	return createTestSIQFileWithContent(t, testContentXML)
+}
+
+// createTestSIQFileWithContent creates a test SIQ file with the given content.xml
+func createTestSIQFileWithContent(t *testing.T, contentXML string) string {
 	// Create a temporary file
 	tmpFile, err := os.CreateTemp("", "test-*.siq")
 	if err != nil {
@@ -19,37 +55,6 @@ func createTestSIQFile(t *testing.T) string {
 	zipWriter := zip.NewWriter(tmpFile)
 	defer zipWriter.Close()
 
-	// Create sample content.xml
-	contentXML := `<?xml version="1.0" encoding="UTF-8"?>
-<package id="test-package" name="Test Package" version="5.0" difficulty="5" language="en">
-	<info>
-		<authors>
-			<author>Test Author</author>
-		</authors>
-		<sources>
-			<source>Test Source</source>
-		</sources>
-	</info>
-	<round name="Round 1">
-		<theme name="Test Theme">
-			<question type="simple">
-				<params>
-					<param name="question" type="content">
-						<item type="text">What is 2+2?</item>
-					</param>
-				</params>
-				<right>
-					<answer>4</answer>
-				</right>
-				<wrong>
-					<answer>3</answer>
-					<answer>5</answer>
-				</wrong>
-			</question>
-		</theme>
-	</round>
-</package>`
-
 	// Add content.xml to the zip
 	contentFile, err := zipWriter.Create("content.xml")
 	if err != nil {
@@ -255,3 +260,157 @@ func TestResolveReference(t *testing.T) {
 		t.Errorf("Expected 'Direct text', got '%s'", resolved)
 	}
 }
+
+func TestReadNormalizesContentItems(t *testing.T) {
+	contentXML := `<?xml version="1.0" encoding="UTF-8"?>
+<package id="defaults" name="Defaults" version="5.0">
+	<round name="Round 1">
+		<theme name="Theme">
+			<question price="100">
+				<params>
+					<param name="question" type="content">
+						<item type="audio" isRef="True" placement="background" waitForFinish="false">song.mp3</item>
+						<item type="text">Name the song</item>
+					</param>
+				</params>
+				<right>
+					<answer>Song</answer>
+				</right>
+			</question>
+		</theme>
+	</round>
+</package>`
+
+	testFile := createTestSIQFileWithContent(t, contentXML)
+	defer os.Remove(testFile)
+
+	reader, err := NewSIQReader(testFile)
+	if err != nil {
+		t.Fatal("Failed to create SIQ reader:", err)
+	}
+	defer reader.Close()
+
+	pkg, err := reader.Read()
+	if err != nil {
+		t.Fatal("Failed to read SIQ file:", err)
+	}
+
+	content := pkg.Rounds[0].Themes[0].Questions[0].GetQuestionContent()
+	if len(content) != 2 {
+		t.Fatalf("Expected 2 content items, got %d", len(content))
+	}
+
+	// Explicit attributes are kept
+	if content[0].Placement != PlacementBackground {
+		t.Errorf("Expected placement '%s', got '%s'", PlacementBackground, content[0].Placement)
+	}
+	if content[0].WaitForFinish == nil || *content[0].WaitForFinish {
+		t.Error("Expected waitForFinish to be false")
+	}
+
+	// Missing attributes get spec defaults
+	if content[1].Type != ContentTypeText {
+		t.Errorf("Expected type '%s', got '%s'", ContentTypeText, content[1].Type)
+	}
+	if content[1].Placement != PlacementScreen {
+		t.Errorf("Expected placement '%s', got '%s'", PlacementScreen, content[1].Placement)
+	}
+	if content[1].WaitForFinish == nil || !*content[1].WaitForFinish {
+		t.Error("Expected waitForFinish to default to true")
+	}
+}
+
+func TestReadV4NormalizesContentItems(t *testing.T) {
+	contentXML := `<?xml version="1.0" encoding="utf-8"?>
+<package name="V4 Package" version="4" id="v4-package" xmlns="http://vladimirkhil.com/ygpackage3.0.xsd">
+	<rounds>
+		<round name="Round 1">
+			<themes>
+				<theme name="Theme">
+					<questions>
+						<question price="100">
+							<scenario>
+								<atom>Question text</atom>
+							</scenario>
+							<right>
+								<answer>Answer</answer>
+							</right>
+						</question>
+					</questions>
+				</theme>
+			</themes>
+		</round>
+	</rounds>
+</package>`
+
+	testFile := createTestSIQFileWithContent(t, contentXML)
+	defer os.Remove(testFile)
+
+	reader, err := NewSIQReader(testFile)
+	if err != nil {
+		t.Fatal("Failed to create SIQ reader:", err)
+	}
+	defer reader.Close()
+
+	pkg, err := reader.Read()
+	if err != nil {
+		t.Fatal("Failed to read SIQ file:", err)
+	}
+	if reader.GetVersion() != 4 {
+		t.Errorf("Expected version 4, got %d", reader.GetVersion())
+	}
+
+	questions := pkg.GetAllQuestions()
+	if len(questions) != 1 {
+		t.Fatalf("Expected 1 question, got %d", len(questions))
+	}
+	content := questions[0].GetQuestionContent()
+	if len(content) != 1 {
+		t.Fatalf("Expected 1 content item, got %d", len(content))
+	}
+	if content[0].Placement != PlacementScreen {
+		t.Errorf("Expected placement '%s', got '%s'", PlacementScreen, content[0].Placement)
+	}
+	if !content[0].GetWaitForFinish() {
+		t.Error("Expected waitForFinish to default to true")
+	}
+}
+
+func TestContentItemAccessors(t *testing.T) {
+	item := ContentItem{Type: ContentTypeText, Value: "text"}
+	if item.GetPlacement() != PlacementScreen {
+		t.Errorf("Expected placement '%s', got '%s'", PlacementScreen, item.GetPlacement())
+	}
+	if !item.GetWaitForFinish() {
+		t.Error("Expected waitForFinish to default to true")
+	}
+
+	noWait := false
+	item = ContentItem{Placement: PlacementReplic, WaitForFinish: &noWait}
+	if item.GetPlacement() != PlacementReplic {
+		t.Errorf("Expected placement '%s', got '%s'", PlacementReplic, item.GetPlacement())
+	}
+	if item.GetWaitForFinish() {
+		t.Error("Expected waitForFinish to be false")
+	}
+}
+
+func TestMediaFolders(t *testing.T) {
+	if MediaFolder(ContentTypeVoice) != FolderAudio || MediaFolder(ContentTypeText) != "" {
+		t.Error("Unexpected media folder")
+	}
+
+	tests := map[string]string{
+		"Images/pic.png":    ContentTypeImage,
+		"audio/song.mp3":    ContentTypeAudio,
+		"Video/clip.mp4":    ContentTypeVideo,
+		"Html/page.html":    ContentTypeHtml,
+		"content.xml":       "",
+		"Texts/authors.xml": "",
+	}
+	for path, expected := range tests {
+		if result := MediaType(path); result != expected {
+			t.Errorf("MediaType(%q) = %q, expected %q", path, result, expected)
+		}
+	}
+}
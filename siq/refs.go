@@ -0,0 +1,136 @@
+package siq
+
+import (
+	"iter"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ContentItems returns an iterator over all content items of the question
+// params, including nested group params such as answer options. Items are
+// yielded by pointer so they can be modified in place.
+func (q *Question) ContentItems() iter.Seq[*ContentItem] {
+	return func(yield func(*ContentItem) bool) {
+		for _, item := range q.ParamItems() {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// ParamItems is like ContentItems but also yields the path of the param
+// holding each item as accepted by Param, for example "answerOptions/A"
+func (q *Question) ParamItems() iter.Seq2[string, *ContentItem] {
+	return func(yield func(string, *ContentItem) bool) {
+		paramItems(q.Params, "", yield)
+	}
+}
+
+// paramItems yields the content items of params recursively and reports whether to continue
+func paramItems(params []Param, prefix string, yield func(string, *ContentItem) bool) bool {
+	for i := range params {
+		name := prefix + params[i].Name
+		for j := range params[i].Items {
+			if !yield(name, &params[i].Items[j]) {
+				return false
+			}
+		}
+		if !paramItems(params[i].Params, name+"/", yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// ItemPath returns the archive path of the file referenced by an isRef
+// content item, for example "Images/cat.png". URI-encoded names are decoded.
+// It returns an empty string for items that do not reference package files.
+func ItemPath(item ContentItem) string {
+	folder := MediaFolder(item.GetType())
+	if !item.IsRef || folder == "" {
+		return ""
+	}
+	return folder + "/" + DecodeFileName(item.Value)
+}
+
+// LogoPath returns the archive path of the package logo, or an empty string
+// when the logo is not a reference to a package file
+func (p *Package) LogoPath() string {
+	if !strings.HasPrefix(p.Logo, "@") {
+		return ""
+	}
+	return FolderImages + "/" + DecodeFileName(p.Logo[1:])
+}
+
+// DecodeFileName decodes a URI-encoded file name, names that are not
+// valid URI encodings are returned unchanged
+func DecodeFileName(name string) string {
+	decoded, err := url.PathUnescape(name)
+	if err != nil {
+		return name
+	}
+	return decoded
+}
+
+// EntryPath returns the canonical archive path of a media file stored in the
+// archive under name, so that it can be compared with ItemPath and LogoPath
+// results: the folder gets its canonical spelling and the file name is
+// decoded, for example "images/cat%20x.png" becomes "Images/cat x.png".
+// It returns an empty string for entries outside of the media folders.
+func EntryPath(name string) string {
+	mediaType := MediaType(name)
+	if mediaType == "" || strings.HasSuffix(name, "/") {
+		return ""
+	}
+	_, fileName, _ := strings.Cut(name, "/")
+	return MediaFolder(mediaType) + "/" + DecodeFileName(fileName)
+}
+
+// MediaPaths returns the archive paths of all files referenced by the
+// package logo and its isRef content items, in order of first use
+func (p *Package) MediaPaths() []string {
+	var paths []string
+	seen := make(map[string]bool)
+	add := func(filePath string) {
+		if filePath != "" && !seen[filePath] {
+			seen[filePath] = true
+			paths = append(paths, filePath)
+		}
+	}
+
+	add(p.LogoPath())
+	p.Walk(func(_ QuestionRef, question *Question) error {
+		for item := range question.ContentItems() {
+			add(ItemPath(*item))
+		}
+		return nil
+	})
+	return paths
+}
+
+// RenameFile returns a variant of an archive file name with a numeric
+// suffix before the extension, for example "Images/cat_2.png"
+func RenameFile(filePath string, n int) string {
+	ext := path.Ext(filePath)
+	return strings.TrimSuffix(filePath, ext) + "_" + strconv.Itoa(n) + ext
+}
+
+// RenameFiles rewrites the logo and the isRef content items that reference
+// renamed archive files. renames maps old archive paths to new paths in the
+// same media folder.
+func (p *Package) RenameFiles(renames map[string]string) {
+	if newPath, ok := renames[p.LogoPath()]; ok {
+		p.Logo = "@" + path.Base(newPath)
+	}
+	p.Walk(func(_ QuestionRef, question *Question) error {
+		for item := range question.ContentItems() {
+			if newPath, ok := renames[ItemPath(*item)]; ok {
+				item.Value = path.Base(newPath)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,103 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/minmaxmean/sigma/textnorm"
+)
+
+// Matching modes
+const (
+	ModeSubstring = "substring"
+	ModeRegex     = "regex"
+	ModeFuzzy     = "fuzzy"
+)
+
+// Matcher checks whether a text matches a query.
+// All matchers are case- and diacritic-insensitive.
+type Matcher interface {
+	Match(text string) bool
+}
+
+// NewMatcher creates a matcher for the query in the given mode
+func NewMatcher(query, mode string) (Matcher, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("empty search query")
+	}
+
+	switch mode {
+	case ModeSubstring, "":
+		return &substringMatcher{query: textnorm.Fold(query)}, nil
+	case ModeRegex:
+		// Diacritics are stripped from the pattern but its case is kept
+		// because escapes such as \W are case-sensitive
+		re, err := regexp.Compile("(?i)" + textnorm.StripDiacritics(query))
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return &regexMatcher{re: re}, nil
+	case ModeFuzzy:
+		folded := []rune(textnorm.Fold(query))
+		return &fuzzyMatcher{query: folded, maxErrors: len(folded) / 4}, nil
+	default:
+		return nil, fmt.Errorf("unknown search mode %q", mode)
+	}
+}
+
+// substringMatcher matches texts containing the query
+type substringMatcher struct {
+	query string
+}
+
+func (m *substringMatcher) Match(text string) bool {
+	return strings.Contains(textnorm.Fold(text), m.query)
+}
+
+// regexMatcher matches texts against a regular expression
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m *regexMatcher) Match(text string) bool {
+	return m.re.MatchString(textnorm.StripDiacritics(text))
+}
+
+// fuzzyMatcher matches texts containing a substring within maxErrors
+// edits (insertions, deletions, substitutions) of the query
+type fuzzyMatcher struct {
+	query     []rune
+	maxErrors int
+}
+
+func (m *fuzzyMatcher) Match(text string) bool {
+	return substringDistance(m.query, []rune(textnorm.Fold(text))) <= m.maxErrors
+}
+
+// substringDistance returns the smallest edit distance between the pattern
+// and any substring of the text (Sellers algorithm)
+func substringDistance(pattern, text []rune) int {
+	// column[i] is the distance between pattern[:i] and the best substring ending at the current position
+	column := make([]int, len(pattern)+1)
+	for i := range column {
+		column[i] = i
+	}
+	best := column[len(pattern)]
+
+	for _, r := range text {
+		diagonal := column[0] // a match may start at any position
+		for i := 1; i <= len(pattern); i++ {
+			cost := 1
+			if pattern[i-1] == r {
+				cost = 0
+			}
+			current := min(column[i]+1, column[i-1]+1, diagonal+cost)
+			diagonal = column[i]
+			column[i] = current
+		}
+		best = min(best, column[len(pattern)])
+	}
+
+	return best
+}
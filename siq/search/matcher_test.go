@@ -0,0 +1,74 @@
+package search
+
+import (
+	"testing"
+)
+
+func TestSubstringMatcher(t *testing.T) {
+	matcher, err := NewMatcher("ЁЛКА", ModeSubstring)
+	if err != nil {
+		t.Fatal("Failed to create matcher:", err)
+	}
+	if !matcher.Match("Зеленая елка в лесу") {
+		t.Error("Expected case- and diacritic-insensitive match")
+	}
+	if matcher.Match("Зеленая ель") {
+		t.Error("Unexpected match")
+	}
+}
+
+func TestRegexMatcher(t *testing.T) {
+	matcher, err := NewMatcher(`^caf[eé]\s+\w+$`, ModeRegex)
+	if err != nil {
+		t.Fatal("Failed to create matcher:", err)
+	}
+	if !matcher.Match("Café Noir") {
+		t.Error("Expected regex match")
+	}
+	if matcher.Match("The Café") {
+		t.Error("Unexpected regex match")
+	}
+
+	if _, err := NewMatcher("(", ModeRegex); err == nil {
+		t.Error("Expected invalid regex error")
+	}
+}
+
+func TestFuzzyMatcher(t *testing.T) {
+	matcher, err := NewMatcher("Достоевский", ModeFuzzy)
+	if err != nil {
+		t.Fatal("Failed to create matcher:", err)
+	}
+	if !matcher.Match("Роман Достоевскаго «Идиот»") {
+		t.Error("Expected fuzzy match with one typo")
+	}
+	if matcher.Match("Роман Толстого") {
+		t.Error("Unexpected fuzzy match")
+	}
+}
+
+func TestNewMatcherErrors(t *testing.T) {
+	if _, err := NewMatcher("  ", ModeSubstring); err == nil {
+		t.Error("Expected error for empty query")
+	}
+	if _, err := NewMatcher("query", "unknown"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+}
+
+func TestSubstringDistance(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		expected      int
+	}{
+		{"abc", "xxabcxx", 0},
+		{"abc", "xxabxx", 1},
+		{"abc", "", 3},
+		{"", "text", 0},
+	}
+	for _, test := range tests {
+		if result := substringDistance([]rune(test.pattern), []rune(test.text)); result != test.expected {
+			t.Errorf("substringDistance(%q, %q) = %d, expected %d", test.pattern, test.text, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+// Package search finds questions, answers, themes and rounds matching a query
+// across one or many SIQ packages
+package search
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/minmaxmean/sigma/siq"
+)
+
+// Fields a hit can be found in
+const (
+	FieldRound    = "round"
+	FieldTheme    = "theme"
+	FieldQuestion = "question"
+	FieldAnswer   = "answer"
+	FieldRight    = "right"
+	FieldWrong    = "wrong"
+)
+
+// Hit represents a matched text in a package
+type Hit struct {
+	Path  string
+	Field string
+	Text  string
+	// Ref locates the hit. Round hits only fill the round fields and theme
+	// hits only fill the round and theme fields.
+	Ref siq.QuestionRef
+}
+
+// Location returns a human-readable location of the hit inside the package
+func (h Hit) Location() string {
+	switch h.Field {
+	case FieldRound:
+		return h.Ref.RoundName
+	case FieldTheme:
+		return fmt.Sprintf("%s / %s", h.Ref.RoundName, h.Ref.ThemeName)
+	default:
+		return h.Ref.String()
+	}
+}
+
+// Result holds the hits found in one package
+type Result struct {
+	Path string
+	Hits []Hit
+	Err  error
+}
+
+// SearchFile searches a single package. The package is decoded in streaming
+// mode so memory use does not depend on the package size.
+func SearchFile(path string, matcher Matcher) ([]Hit, error) {
+	reader, err := siq.NewSIQReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var hits []Hit
+	ref := siq.QuestionRef{RoundIndex: -1}
+
+	add := func(field, text string, ref siq.QuestionRef) {
+		if text != "" && matcher.Match(text) {
+			hits = append(hits, Hit{Path: path, Field: field, Text: text, Ref: ref})
+		}
+	}
+
+	err = reader.Stream(siq.StreamHandler{
+		Round: func(round *siq.Round) error {
+			ref = siq.QuestionRef{RoundIndex: ref.RoundIndex + 1, RoundName: round.Name, ThemeIndex: -1}
+			add(FieldRound, round.Name, ref)
+			return nil
+		},
+		Theme: func(round *siq.Round, theme *siq.Theme) error {
+			ref.ThemeIndex++
+			ref.ThemeName = theme.Name
+			ref.QuestionIndex = -1
+			add(FieldTheme, theme.Name, ref)
+			return nil
+		},
+		Question: func(round *siq.Round, theme *siq.Theme, question *siq.Question) error {
+			ref.QuestionIndex++
+			questionRef := ref
+			questionRef.Price = question.Price()
+
+			for _, item := range question.GetQuestionContent() {
+				if item.GetType() == siq.ContentTypeText {
+					add(FieldQuestion, item.Value, questionRef)
+				}
+			}
+			for _, item := range question.AnswerContent() {
+				if item.GetType() == siq.ContentTypeText {
+					add(FieldAnswer, item.Value, questionRef)
+				}
+			}
+			for _, answer := range question.Right {
+				add(FieldRight, answer, questionRef)
+			}
+			for _, answer := range question.Wrong {
+				add(FieldWrong, answer, questionRef)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return hits, fmt.Errorf("failed to search %s: %w", path, err)
+	}
+
+	return hits, nil
+}
+
+// SearchFiles searches packages concurrently with at most workers packages
+// processed at the same time. Results are returned in the order of paths.
+func SearchFiles(paths []string, matcher Matcher, workers int) []Result {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]Result, len(paths))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(workers, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				hits, err := SearchFile(paths[index], matcher)
+				results[index] = Result{Path: paths[index], Hits: hits, Err: err}
+			}
+		}()
+	}
+
+	for index := range paths {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// CollectFiles expands directories into the .siq files they contain.
+// Files given explicitly are kept regardless of their extension.
+func CollectFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !entry.IsDir() && strings.EqualFold(filepath.Ext(filePath), ".siq") {
+				files = append(files, filePath)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %w", path, err)
+		}
+	}
+	return files, nil
+}
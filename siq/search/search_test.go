@@ -0,0 +1,125 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minmaxmean/sigma/internal/siqtest"
+)
+
+const testContentXML = `<?xml version="1.0" encoding="utf-8"?>
+<package id="search" name="Search Package" version="5">
+	<round name="Литература">
+		<theme name="Русские писатели">
+			<question price="100">
+				<params>
+					<param name="question" type="content">
+						<item>Автор романа «Идиот»</item>
+					</param>
+				</params>
+				<right><answer>Фёдор Достоевский</answer></right>
+				<wrong><answer>Лев Толстой</answer></wrong>
+			</question>
+			<question price="200">
+				<params>
+					<param name="question" type="content">
+						<item>Автор «Войны и мира»</item>
+					</param>
+				</params>
+				<right><answer>Толстой</answer></right>
+			</question>
+		</theme>
+	</round>
+</package>`
+
+func TestSearchFile(t *testing.T) {
+	path := siqtest.CreateFile(t, t.TempDir(), "test.siq", testContentXML, nil)
+
+	matcher, err := NewMatcher("толстой", ModeSubstring)
+	if err != nil {
+		t.Fatal("Failed to create matcher:", err)
+	}
+
+	hits, err := SearchFile(path, matcher)
+	if err != nil {
+		t.Fatal("Failed to search file:", err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("Expected 2 hits, got %d: %+v", len(hits), hits)
+	}
+
+	if hits[0].Field != FieldWrong || hits[0].Ref.ID() != "r1/t1/q1" {
+		t.Errorf("Unexpected first hit %+v", hits[0])
+	}
+	if hits[1].Field != FieldRight || hits[1].Ref.ID() != "r1/t1/q2" || hits[1].Ref.Price.Value != 200 {
+		t.Errorf("Unexpected second hit %+v", hits[1])
+	}
+	if hits[1].Location() != "Литература / Русские писатели / 200" {
+		t.Errorf("Unexpected location '%s'", hits[1].Location())
+	}
+}
+
+func TestSearchFileNames(t *testing.T) {
+	path := siqtest.CreateFile(t, t.TempDir(), "test.siq", testContentXML, nil)
+
+	matcher, err := NewMatcher("писатели", ModeSubstring)
+	if err != nil {
+		t.Fatal("Failed to create matcher:", err)
+	}
+
+	hits, err := SearchFile(path, matcher)
+	if err != nil {
+		t.Fatal("Failed to search file:", err)
+	}
+	if len(hits) != 1 || hits[0].Field != FieldTheme || hits[0].Location() != "Литература / Русские писатели" {
+		t.Errorf("Unexpected theme hits %+v", hits)
+	}
+}
+
+func TestSearchFilesInDirectory(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "nested")
+	if err := os.Mkdir(nested, 0755); err != nil {
+		t.Fatal("Failed to create directory:", err)
+	}
+	siqtest.CreateFile(t, dir, "a.siq", testContentXML, nil)
+	siqtest.CreateFile(t, nested, "b.SIQ", testContentXML, nil)
+	siqtest.CreateFile(t, dir, "broken.siq", "<package><round>", nil)
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("Достоевский"), 0644); err != nil {
+		t.Fatal("Failed to write file:", err)
+	}
+
+	files, err := CollectFiles([]string{dir})
+	if err != nil {
+		t.Fatal("Failed to collect files:", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("Expected 3 packages, got %v", files)
+	}
+
+	matcher, err := NewMatcher("Достоевскии", ModeFuzzy)
+	if err != nil {
+		t.Fatal("Failed to create matcher:", err)
+	}
+
+	results := SearchFiles(files, matcher, 2)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	for i, result := range results {
+		if result.Path != files[i] {
+			t.Errorf("Expected results in input order, got %s at %d", result.Path, i)
+		}
+		if strings.HasSuffix(result.Path, "broken.siq") {
+			if result.Err == nil {
+				t.Error("Expected error for broken package")
+			}
+			continue
+		}
+		if result.Err != nil || len(result.Hits) != 1 {
+			t.Errorf("Unexpected result for %s: %+v", result.Path, result)
+		}
+	}
+}
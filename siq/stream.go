@@ -0,0 +1,322 @@
+package siq
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+)
+
+// namespaceV4 is the XML namespace of version 4 packages
+const namespaceV4 = "http://vladimirkhil.com/ygpackage3.0.xsd"
+
+// errStopStream is returned by stream callbacks to stop decoding early
+var errStopStream = errors.New("stream stopped")
+
+// StreamHandler receives package elements while content.xml is decoded.
+// Rounds and themes are passed without their children, questions are passed
+// one by one and are not retained, so memory use does not grow with the
+// package size. Any callback may be nil. Returning an error stops decoding.
+type StreamHandler struct {
+	// Package is called with the package attributes, info, tags and global definitions
+	Package func(pkg *Package) error
+	// Round is called when a round starts, after its info is decoded
+	Round func(round *Round) error
+	// Theme is called when a theme starts, after its info is decoded
+	Theme func(round *Round, theme *Theme) error
+	// Question is called for every question, v4 questions are converted to v5 format
+	Question func(round *Round, theme *Theme, question *Question) error
+}
+
+// Stream decodes content.xml token by token and passes its elements to the handler
+func (r *SIQReader) Stream(handler StreamHandler) error {
+	contentFile, err := r.findContentFile()
+	if err != nil {
+		return err
+	}
+
+	rc, err := contentFile.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open content.xml: %w", err)
+	}
+	defer rc.Close()
+
+	version, err := StreamContent(rc, handler)
+	if version != 0 {
+		r.version = version
+	}
+	return err
+}
+
+// Questions returns an iterator over the package questions decoded in streaming mode.
+// A decoding error is yielded as the last element of the sequence.
+func (r *SIQReader) Questions() iter.Seq2[*Question, error] {
+	return func(yield func(*Question, error) bool) {
+		err := r.Stream(StreamHandler{
+			Question: func(_ *Round, _ *Theme, question *Question) error {
+				if !yield(question, nil) {
+					return errStopStream
+				}
+				return nil
+			},
+		})
+		if err != nil && !errors.Is(err, errStopStream) {
+			yield(nil, err)
+		}
+	}
+}
+
+// StreamContent decodes content.xml from the reader token by token and passes
+// its elements to the handler. It returns the detected format version.
+func StreamContent(r io.Reader, handler StreamHandler) (int, error) {
+	decoder := xml.NewDecoder(r)
+	root, err := rootElement(decoder)
+	if err != nil {
+		return 0, err
+	}
+
+	s := &streamDecoder{
+		decoder: decoder,
+		handler: handler,
+		version: detectVersion(root),
+		pkg:     &Package{},
+	}
+	if err := s.decodePackage(root); err != nil {
+		if errors.Is(err, errStopStream) {
+			return s.version, err
+		}
+		return s.version, fmt.Errorf("failed to decode v%d XML: %w", s.version, err)
+	}
+
+	return s.version, nil
+}
+
+// rootElement reads tokens until the root element of the document
+func rootElement(decoder *xml.Decoder) (xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, fmt.Errorf("failed to read content.xml: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
+}
+
+// detectVersion detects the format version by the namespace of the root element
+func detectVersion(root xml.StartElement) int {
+	if root.Name.Space == namespaceV4 {
+		return 4
+	}
+	return 5
+}
+
+// streamDecoder holds the state of a streaming decode
+type streamDecoder struct {
+	decoder     *xml.Decoder
+	handler     StreamHandler
+	version     int
+	pkg         *Package
+	pkgReported bool
+}
+
+// children calls fn for every child element of the current element until its end.
+// fn must consume the whole child element.
+func (s *streamDecoder) children(fn func(start xml.StartElement) error) error {
+	for {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if err := fn(t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// reportPackage passes the package header to the handler once
+func (s *streamDecoder) reportPackage() error {
+	if s.pkgReported {
+		return nil
+	}
+	s.pkgReported = true
+	if s.handler.Package != nil {
+		return s.handler.Package(s.pkg)
+	}
+	return nil
+}
+
+func (s *streamDecoder) decodePackage(root xml.StartElement) error {
+	s.pkg.FormatVersion = s.version
+	for _, attr := range root.Attr {
+		switch attr.Name.Local {
+		case "id":
+			s.pkg.ID = attr.Value
+		case "name":
+			s.pkg.Name = attr.Value
+		case "version":
+			s.pkg.Version = attr.Value
+		case "restriction":
+			s.pkg.Restriction = attr.Value
+		case "date":
+			s.pkg.Date = attr.Value
+		case "publisher":
+			s.pkg.Publisher = attr.Value
+		case "difficulty":
+			difficulty, err := strconv.Atoi(attr.Value)
+			if err != nil {
+				return fmt.Errorf("invalid difficulty %q: %w", attr.Value, err)
+			}
+			s.pkg.Difficulty = difficulty
+		case "logo":
+			s.pkg.Logo = attr.Value
+		case "language":
+			s.pkg.Language = attr.Value
+		}
+	}
+
+	var handle func(start xml.StartElement) error
+	handle = func(start xml.StartElement) error {
+		switch start.Name.Local {
+		case "info":
+			return s.decoder.DecodeElement(&s.pkg.Info, &start)
+		case "tags":
+			return s.decoder.DecodeElement(&s.pkg.Tags, &start)
+		case "global":
+			return s.decoder.DecodeElement(&s.pkg.Global, &start)
+		case "rounds":
+			return s.children(handle)
+		case "round":
+			if err := s.reportPackage(); err != nil {
+				return err
+			}
+			return s.decodeRound(start)
+		default:
+			return s.decoder.Skip()
+		}
+	}
+	if err := s.children(handle); err != nil {
+		return err
+	}
+
+	// Packages without rounds are reported at the end
+	return s.reportPackage()
+}
+
+func (s *streamDecoder) decodeRound(start xml.StartElement) error {
+	round := &Round{Name: attrValue(start, "name"), Type: attrValue(start, "type")}
+	reported := false
+	report := func() error {
+		if reported {
+			return nil
+		}
+		reported = true
+		if s.handler.Round != nil {
+			return s.handler.Round(round)
+		}
+		return nil
+	}
+
+	var handle func(start xml.StartElement) error
+	handle = func(start xml.StartElement) error {
+		switch start.Name.Local {
+		case "info":
+			return s.decoder.DecodeElement(&round.Info, &start)
+		case "themes":
+			return s.children(handle)
+		case "theme":
+			if err := report(); err != nil {
+				return err
+			}
+			return s.decodeTheme(round, start)
+		default:
+			return s.decoder.Skip()
+		}
+	}
+	if err := s.children(handle); err != nil {
+		return err
+	}
+
+	return report()
+}
+
+func (s *streamDecoder) decodeTheme(round *Round, start xml.StartElement) error {
+	theme := &Theme{Name: attrValue(start, "name")}
+	reported := false
+	report := func() error {
+		if reported {
+			return nil
+		}
+		reported = true
+		if s.handler.Theme != nil {
+			return s.handler.Theme(round, theme)
+		}
+		return nil
+	}
+
+	var handle func(start xml.StartElement) error
+	handle = func(start xml.StartElement) error {
+		switch start.Name.Local {
+		case "info":
+			return s.decoder.DecodeElement(&theme.Info, &start)
+		case "questions":
+			return s.children(handle)
+		case "question":
+			if err := report(); err != nil {
+				return err
+			}
+			question, err := s.decodeQuestion(start)
+			if err != nil {
+				return err
+			}
+			if s.handler.Question != nil {
+				return s.handler.Question(round, theme, question)
+			}
+			return nil
+		default:
+			return s.decoder.Skip()
+		}
+	}
+	if err := s.children(handle); err != nil {
+		return err
+	}
+
+	return report()
+}
+
+// decodeQuestion decodes a question element and converts it to normalized v5 format
+func (s *streamDecoder) decodeQuestion(start xml.StartElement) (*Question, error) {
+	if s.version == 4 {
+		var qv4 QuestionV4
+		if err := s.decoder.DecodeElement(&qv4, &start); err != nil {
+			return nil, err
+		}
+		question := convertV4ToV5Question(qv4)
+		return &question, nil
+	}
+
+	var question Question
+	if err := s.decoder.DecodeElement(&question, &start); err != nil {
+		return nil, err
+	}
+	question.normalize()
+	return &question, nil
+}
+
+// attrValue returns the value of an attribute of the element
+func attrValue(start xml.StartElement, name string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,228 @@
+package siq
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const streamV5ContentXML = `<?xml version="1.0" encoding="utf-8"?>
+<package id="stream" name="Stream Package" version="5" difficulty="3">
+	<tags>
+		<tag>Music</tag>
+	</tags>
+	<round name="Round 1">
+		<info>
+			<comments>
+				<comment>First round</comment>
+			</comments>
+		</info>
+		<theme name="Theme 1">
+			<question price="100">
+				<params>
+					<param name="question" type="content">
+						<item>Question 1</item>
+					</param>
+				</params>
+				<right><answer>Answer 1</answer></right>
+			</question>
+			<question price="200">
+				<params>
+					<param name="question" type="content">
+						<item>Question 2</item>
+					</param>
+				</params>
+				<right><answer>Answer 2</answer></right>
+			</question>
+		</theme>
+		<theme name="Empty theme" />
+	</round>
+	<round name="Round 2">
+		<theme name="Theme 2">
+			<question price="300" type="stake">
+				<params>
+					<param name="question" type="content">
+						<item>Question 3</item>
+					</param>
+				</params>
+				<right><answer>Answer 3</answer></right>
+			</question>
+		</theme>
+	</round>
+</package>`
+
+const streamV4ContentXML = `<?xml version="1.0" encoding="utf-8"?>
+<package name="V4 Stream" version="4" id="v4-stream" xmlns="http://vladimirkhil.com/ygpackage3.0.xsd">
+	<rounds>
+		<round name="Round 1">
+			<themes>
+				<theme name="Theme 1">
+					<questions>
+						<question price="100">
+							<scenario>
+								<atom>Question 1</atom>
+							</scenario>
+							<right><answer>Answer 1</answer></right>
+						</question>
+						<question price="200">
+							<scenario>
+								<atom>Question 2</atom>
+							</scenario>
+							<right><answer>Answer 2</answer></right>
+						</question>
+					</questions>
+				</theme>
+			</themes>
+		</round>
+	</rounds>
+</package>`
+
+func TestStreamContentV5(t *testing.T) {
+	var pkg *Package
+	var rounds, themes []string
+	var questions []string
+
+	version, err := StreamContent(strings.NewReader(streamV5ContentXML), StreamHandler{
+		Package: func(p *Package) error {
+			pkg = p
+			return nil
+		},
+		Round: func(round *Round) error {
+			rounds = append(rounds, round.Name)
+			if round.Name == "Round 1" && (round.Info == nil || len(round.Info.Comments) != 1) {
+				t.Errorf("Expected round info to be decoded before the round is reported")
+			}
+			return nil
+		},
+		Theme: func(round *Round, theme *Theme) error {
+			themes = append(themes, round.Name+"/"+theme.Name)
+			return nil
+		},
+		Question: func(round *Round, theme *Theme, question *Question) error {
+			questions = append(questions, theme.Name+":"+question.GetQuestionContent()[0].Value)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal("Failed to stream content:", err)
+	}
+
+	if version != 5 {
+		t.Errorf("Expected version 5, got %d", version)
+	}
+	if pkg == nil || pkg.Name != "Stream Package" || pkg.Difficulty != 3 {
+		t.Fatalf("Unexpected package header %+v", pkg)
+	}
+	if pkg.Tags == nil || len(pkg.Tags.Tags) != 1 {
+		t.Errorf("Expected package tags to be decoded")
+	}
+	if strings.Join(rounds, ",") != "Round 1,Round 2" {
+		t.Errorf("Unexpected rounds %v", rounds)
+	}
+	if strings.Join(themes, ",") != "Round 1/Theme 1,Round 1/Empty theme,Round 2/Theme 2" {
+		t.Errorf("Unexpected themes %v", themes)
+	}
+	if strings.Join(questions, ",") != "Theme 1:Question 1,Theme 1:Question 2,Theme 2:Question 3" {
+		t.Errorf("Unexpected questions %v", questions)
+	}
+}
+
+func TestStreamContentV4(t *testing.T) {
+	var questions []Question
+
+	version, err := StreamContent(strings.NewReader(streamV4ContentXML), StreamHandler{
+		Question: func(round *Round, theme *Theme, question *Question) error {
+			questions = append(questions, *question)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal("Failed to stream content:", err)
+	}
+
+	if version != 4 {
+		t.Errorf("Expected version 4, got %d", version)
+	}
+	if len(questions) != 2 {
+		t.Fatalf("Expected 2 questions, got %d", len(questions))
+	}
+	if questions[1].BasePrice != 200 {
+		t.Errorf("Expected price 200, got %d", questions[1].BasePrice)
+	}
+	if questions[1].GetQuestionContent()[0].Value != "Question 2" {
+		t.Errorf("Unexpected question content %+v", questions[1].GetQuestionContent())
+	}
+}
+
+func TestReaderQuestionsIterator(t *testing.T) {
+	testFile := createTestSIQFileWithContent(t, streamV5ContentXML)
+	defer os.Remove(testFile)
+
+	reader, err := NewSIQReader(testFile)
+	if err != nil {
+		t.Fatal("Failed to create SIQ reader:", err)
+	}
+	defer reader.Close()
+
+	// Stop after the second question
+	count := 0
+	for question, err := range reader.Questions() {
+		if err != nil {
+			t.Fatal("Failed to stream questions:", err)
+		}
+		count++
+		if question.Type != QuestionTypeSimple {
+			t.Errorf("Expected normalized type '%s', got '%s'", QuestionTypeSimple, question.Type)
+		}
+		if count == 2 {
+			break
+		}
+	}
+	if count != 2 {
+		t.Errorf("Expected to stop after 2 questions, got %d", count)
+	}
+	if reader.GetVersion() != 5 {
+		t.Errorf("Expected version 5, got %d", reader.GetVersion())
+	}
+}
+
+func TestReaderQuestionsIteratorError(t *testing.T) {
+	testFile := createTestSIQFileWithContent(t, `<package><round name="broken"><theme>`)
+	defer os.Remove(testFile)
+
+	reader, err := NewSIQReader(testFile)
+	if err != nil {
+		t.Fatal("Failed to create SIQ reader:", err)
+	}
+	defer reader.Close()
+
+	var lastErr error
+	for _, err := range reader.Questions() {
+		lastErr = err
+	}
+	if lastErr == nil {
+		t.Error("Expected decoding error for truncated XML")
+	}
+}
+
+func TestPackageQuestionsIterator(t *testing.T) {
+	pkg := &Package{
+		Rounds: []Round{
+			{Themes: []Theme{{Questions: []Question{{Type: QuestionTypeSimple}, {Type: QuestionTypeStake}}}}},
+		},
+		RoundsV4: []RoundV4{
+			{Themes: []ThemeV4{{Questions: []QuestionV4{{Price: 100}}}}},
+		},
+	}
+
+	var types []string
+	for index, question := range pkg.Questions() {
+		if index != len(types) {
+			t.Errorf("Expected index %d, got %d", len(types), index)
+		}
+		types = append(types, question.Type)
+	}
+	if strings.Join(types, ",") != "simple,stake,simple" {
+		t.Errorf("Unexpected question types %v", types)
+	}
+}
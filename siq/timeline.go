@@ -0,0 +1,108 @@
+package siq
+
+// TimelineStep represents a set of content items that start playing at the same time
+type TimelineStep struct {
+	Items []ContentItem
+	// Duration is the longest declared duration of the items the step waits for (0 if unknown)
+	Duration int
+}
+
+// Screen returns the step items displayed on the game screen
+func (s TimelineStep) Screen() []ContentItem {
+	return s.itemsWithPlacement(PlacementScreen)
+}
+
+// Replic returns the step items provided as showman replics
+func (s TimelineStep) Replic() []ContentItem {
+	return s.itemsWithPlacement(PlacementReplic)
+}
+
+// Background returns the step items played in the background
+func (s TimelineStep) Background() []ContentItem {
+	return s.itemsWithPlacement(PlacementBackground)
+}
+
+func (s TimelineStep) itemsWithPlacement(placement string) []ContentItem {
+	var items []ContentItem
+	for _, item := range s.Items {
+		if item.Placement == placement {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Timeline represents the playback schedule of a question.
+// Steps are played sequentially, items inside a step are played concurrently.
+type Timeline struct {
+	Steps []TimelineStep
+	// AnswerStart is the index of the first step that belongs to the answer.
+	// It equals len(Steps) when the question has no answer content.
+	AnswerStart int
+}
+
+// QuestionSteps returns the steps played while the question is asked
+func (t *Timeline) QuestionSteps() []TimelineStep {
+	return t.Steps[:t.AnswerStart]
+}
+
+// AnswerSteps returns the steps played when the answer is revealed
+func (t *Timeline) AnswerSteps() []TimelineStep {
+	return t.Steps[t.AnswerStart:]
+}
+
+// NewTimeline builds the playback schedule of a question.
+//
+// Content items are played in order. An item that does not wait for finish
+// is played together with the items following it, so consecutive items are
+// merged into one step until an item that waits for finish closes it.
+// Items placed after a marker (v4 scenarios) and the items of the "answer"
+// parameter belong to the answer part of the timeline.
+func NewTimeline(q *Question) *Timeline {
+	var questionItems, answerItems []ContentItem
+	inAnswer := false
+	for _, item := range q.GetQuestionContent() {
+		if item.Type == ContentTypeMarker {
+			inAnswer = true
+			continue
+		}
+		if inAnswer {
+			answerItems = append(answerItems, item)
+		} else {
+			questionItems = append(questionItems, item)
+		}
+	}
+	answerItems = append(answerItems, q.GetParamItems("answer")...)
+
+	timeline := &Timeline{}
+	timeline.Steps = appendSteps(timeline.Steps, questionItems)
+	timeline.AnswerStart = len(timeline.Steps)
+	timeline.Steps = appendSteps(timeline.Steps, answerItems)
+
+	return timeline
+}
+
+// appendSteps groups content items into concurrent steps and appends them to steps
+func appendSteps(steps []TimelineStep, items []ContentItem) []TimelineStep {
+	var current TimelineStep
+	for _, item := range items {
+		item.Placement = item.GetPlacement()
+		current.Items = append(current.Items, item)
+
+		if !item.GetWaitForFinish() {
+			continue
+		}
+		if item.Duration > current.Duration {
+			current.Duration = item.Duration
+		}
+		steps = append(steps, current)
+		current = TimelineStep{}
+	}
+
+	// Trailing items that do not wait still have to be shown
+	if len(current.Items) > 0 {
+		steps = append(steps, current)
+	}
+
+	return steps
+}
@@ -0,0 +1,142 @@
+package siq
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestNewTimelineSequential(t *testing.T) {
+	question := &Question{
+		Type: QuestionTypeSimple,
+		Params: []Param{
+			{
+				Name: "question",
+				Type: ParamTypeContent,
+				Items: []ContentItem{
+					{Type: ContentTypeText, Value: "First"},
+					{Type: ContentTypeImage, Value: "pic.png", IsRef: true, Duration: 5},
+				},
+			},
+		},
+	}
+
+	timeline := NewTimeline(question)
+	if len(timeline.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %d", len(timeline.Steps))
+	}
+	if timeline.AnswerStart != 2 {
+		t.Errorf("Expected answer start 2, got %d", timeline.AnswerStart)
+	}
+	if timeline.Steps[1].Duration != 5 {
+		t.Errorf("Expected step duration 5, got %d", timeline.Steps[1].Duration)
+	}
+	if timeline.Steps[0].Items[0].Placement != PlacementScreen {
+		t.Errorf("Expected default placement '%s', got '%s'", PlacementScreen, timeline.Steps[0].Items[0].Placement)
+	}
+	if len(timeline.AnswerSteps()) != 0 {
+		t.Errorf("Expected no answer steps, got %d", len(timeline.AnswerSteps()))
+	}
+}
+
+func TestNewTimelineConcurrent(t *testing.T) {
+	noWait := false
+	question := &Question{
+		Params: []Param{
+			{
+				Name: "question",
+				Type: ParamTypeContent,
+				Items: []ContentItem{
+					{Type: ContentTypeAudio, Value: "song.mp3", IsRef: true, Placement: PlacementBackground, WaitForFinish: &noWait},
+					{Type: ContentTypeText, Value: "Name the song"},
+					{Type: ContentTypeText, Value: "Hint", Placement: PlacementReplic, WaitForFinish: &noWait},
+				},
+			},
+			{
+				Name: "answer",
+				Type: ParamTypeContent,
+				Items: []ContentItem{
+					{Type: ContentTypeImage, Value: "cover.png", IsRef: true},
+				},
+			},
+		},
+	}
+
+	timeline := NewTimeline(question)
+	if len(timeline.Steps) != 3 {
+		t.Fatalf("Expected 3 steps, got %d", len(timeline.Steps))
+	}
+
+	first := timeline.Steps[0]
+	if len(first.Items) != 2 {
+		t.Errorf("Expected 2 concurrent items, got %d", len(first.Items))
+	}
+	if len(first.Background()) != 1 || len(first.Screen()) != 1 {
+		t.Errorf("Expected 1 background and 1 screen item, got %d and %d", len(first.Background()), len(first.Screen()))
+	}
+
+	// The trailing item that does not wait still forms its own step
+	if len(timeline.Steps[1].Replic()) != 1 {
+		t.Errorf("Expected 1 replic item in second step, got %d", len(timeline.Steps[1].Replic()))
+	}
+
+	if timeline.AnswerStart != 2 {
+		t.Errorf("Expected answer start 2, got %d", timeline.AnswerStart)
+	}
+	answer := timeline.AnswerSteps()
+	if len(answer) != 1 || answer[0].Items[0].Value != "cover.png" {
+		t.Errorf("Expected answer step with 'cover.png', got %+v", answer)
+	}
+}
+
+func TestNewTimelineMarker(t *testing.T) {
+	question := ConvertV4ToV5Question(QuestionV4{
+		Price: 100,
+		Scenario: &Scenario{
+			Atoms: []Atom{
+				{Type: ContentTypeText, Content: "Question text"},
+				{Type: ContentTypeMarker},
+				{Type: ContentTypeText, Content: "Answer text"},
+			},
+		},
+	})
+	timeline := NewTimeline(&question)
+	if len(timeline.QuestionSteps()) != 1 {
+		t.Errorf("Expected 1 question step, got %d", len(timeline.QuestionSteps()))
+	}
+	answer := timeline.AnswerSteps()
+	if len(answer) != 1 || answer[0].Items[0].Value != "Answer text" {
+		t.Errorf("Expected answer step with 'Answer text', got %+v", answer)
+	}
+}
+
+func TestNewTimelineWaitForFinishDefault(t *testing.T) {
+	data := `<question price="100">
+		<params>
+			<param name="question" type="content">
+				<item type="audio" isRef="True" placement="background" waitForFinish="False">song.mp3</item>
+				<item type="text">Listen</item>
+				<item type="image" isRef="True">cover.png</item>
+			</param>
+		</params>
+	</question>`
+
+	var question Question
+	if err := xml.Unmarshal([]byte(data), &question); err != nil {
+		t.Fatalf("Failed to unmarshal question: %v", err)
+	}
+	if question.Params[0].Items[1].WaitForFinish != nil {
+		t.Fatalf("Expected omitted waitForFinish to stay unset")
+	}
+
+	// Items without the attribute block the timeline, so each closes its own step
+	timeline := NewTimeline(&question)
+	if len(timeline.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %d", len(timeline.Steps))
+	}
+	if len(timeline.Steps[0].Items) != 2 {
+		t.Errorf("Expected 2 items in first step, got %d", len(timeline.Steps[0].Items))
+	}
+	if len(timeline.Steps[1].Items) != 1 || timeline.Steps[1].Items[0].Value != "cover.png" {
+		t.Errorf("Expected second step with 'cover.png', got %+v", timeline.Steps[1].Items)
+	}
+}
@@ -0,0 +1,125 @@
+package translate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Cache stores translations by a hash of the source text and everything
+// that affects its translation, so unchanged strings are not sent to the
+// model again, see cacheKey
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]string
+}
+
+// NewCache creates an empty cache
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]string)}
+}
+
+// LoadCache reads a cache saved with Save. A missing file gives an empty cache.
+func LoadCache(path string) (*Cache, error) {
+	cache := NewCache()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &cache.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse cache %s: %w", path, err)
+	}
+	if cache.entries == nil {
+		cache.entries = make(map[string]string)
+	}
+	return cache, nil
+}
+
+// Save writes the cache as a JSON object
+func (c *Cache) Save(path string) error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return nil
+}
+
+// Len returns the number of cached translations
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *Cache) get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	translation, ok := c.entries[key]
+	return translation, ok
+}
+
+func (c *Cache) put(key, translation string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = translation
+}
+
+// cacheKey returns the hex SHA-256 of the text with the target and source
+// languages, the model and the glossary lines of the terms in the text.
+// Changing any of them, or a glossary term the text uses, translates the
+// text again.
+func cacheKey(options Options, text string) string {
+	parts := []string{options.Language, options.SourceLanguage, options.Model}
+	parts = append(parts, options.Glossary.relevant([]string{text})...)
+	parts = append(parts, text)
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Glossary maps source terms, usually proper nouns, to their required translations
+type Glossary map[string]string
+
+// LoadGlossary reads a glossary from a YAML file mapping source terms to
+// translations, for example "Пушкин: Pushkin"
+func LoadGlossary(path string) (Glossary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read glossary: %w", err)
+	}
+	var glossary Glossary
+	if err := yaml.Unmarshal(data, &glossary); err != nil {
+		return nil, fmt.Errorf("failed to parse glossary %s: %w", path, err)
+	}
+	return glossary, nil
+}
+
+// relevant returns the glossary lines of terms found in any of the texts,
+// sorted by term
+func (g Glossary) relevant(texts []string) []string {
+	joined := strings.ToLower(strings.Join(texts, "\n"))
+	var lines []string
+	for term, translation := range g {
+		if term != "" && strings.Contains(joined, strings.ToLower(term)) {
+			lines = append(lines, fmt.Sprintf("- %s: %s", term, translation))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
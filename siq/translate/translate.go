@@ -0,0 +1,358 @@
+// Package translate translates the text of SIQ packages with a language
+// model. Question and answer texts, round and theme names and comments are
+// translated while media references and the package structure stay intact.
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/minmaxmean/sigma/llm"
+	"github.com/minmaxmean/sigma/siq"
+	"github.com/minmaxmean/sigma/siq/packtext"
+)
+
+// systemPrompt instructs the model how to translate package strings
+const systemPrompt = `You translate quiz game packages. Translate every string of the input array and return them in the same order.
+Keep the meaning of questions and answers so that the answers still fit the questions. Keep numbers, dates, punctuation and line breaks.
+Do not explain, add or merge strings.`
+
+// Options configures translation
+type Options struct {
+	Model string
+	// Language is the target language, a code such as "en" or a name. It is
+	// set as the package language.
+	Language string
+	// SourceLanguage is the language of the package, by default its language attribute
+	SourceLanguage string
+	Glossary       Glossary
+	// Cache holds translations from earlier runs, new translations are added to it
+	Cache *Cache
+	// BatchSize is the maximal number of strings per request
+	BatchSize int
+	// BatchTokens is the estimated token budget of the strings of a request
+	BatchTokens int
+	// MaxRetries is the number of unusable responses tolerated per package.
+	// The batch of an unusable response is retried in two halves.
+	MaxRetries int
+	// Progress is called after every request with the number of translated
+	// and total unique strings
+	Progress func(done, total int)
+}
+
+// DefaultOptions returns the default translation options
+func DefaultOptions() Options {
+	return Options{
+		BatchSize:   20,
+		BatchTokens: 1000,
+		MaxRetries:  5,
+	}
+}
+
+// Result summarizes a translation
+type Result struct {
+	// Strings is the number of translated fields of the package
+	Strings int
+	// Unique is the number of distinct texts among them
+	Unique int
+	// Cached is the number of distinct texts found in the cache
+	Cached   int
+	Requests int
+	// Retries is the number of unusable responses whose batch was retried
+	Retries int
+	// PromptTokens and CompletionTokens are the totals reported by the provider
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// ResponseError is returned for a model response that cannot be used: one
+// that is not valid JSON or has a wrong number of translations
+type ResponseError struct {
+	// Expected is the number of requested translations
+	Expected int
+	// Got is the number of returned translations, -1 when the response was not parsed
+	Got int
+	// Response is the text returned by the model
+	Response string
+	Err      error
+}
+
+func (e *ResponseError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("invalid response %q: %v", e.Response, e.Err)
+	}
+	return fmt.Sprintf("expected %d translations, got %d", e.Expected, e.Got)
+}
+
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
+// TranslateFile translates the package at input and writes it together with
+// its media to output. Output may be the input file, it is replaced only
+// once the translated package is complete.
+func TranslateFile(ctx context.Context, provider llm.Provider, input, output string, options Options) (*Result, error) {
+	reader, err := siq.NewSIQReader(input)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	pkg, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := Translate(ctx, provider, pkg, options)
+	if err != nil {
+		return nil, err
+	}
+
+	// The output may be the input, it is replaced once the package is complete
+	err = siq.ReplaceFile(output, func(writer *siq.SIQWriter) error {
+		if err := writer.WritePackage(pkg); err != nil {
+			return err
+		}
+		return writer.CopyFiles(reader)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Translate translates the package in place and sets its language
+func Translate(ctx context.Context, provider llm.Provider, pkg *siq.Package, options Options) (*Result, error) {
+	if options.Language == "" {
+		return nil, fmt.Errorf("target language is not set")
+	}
+	if options.Cache == nil {
+		options.Cache = NewCache()
+	}
+	if options.SourceLanguage == "" {
+		options.SourceLanguage = pkg.Language
+	}
+
+	fields := collectFields(pkg)
+	result := &Result{Strings: len(fields)}
+
+	// Identical texts are translated once
+	targets := make(map[string][]*string)
+	var pending []string
+	for _, field := range fields {
+		text := *field
+		if _, seen := targets[text]; !seen {
+			if _, ok := options.Cache.get(cacheKey(options, text)); ok {
+				result.Cached++
+			} else {
+				pending = append(pending, text)
+			}
+		}
+		targets[text] = append(targets[text], field)
+	}
+	result.Unique = len(targets)
+
+	t := &translator{provider: provider, options: options, result: result}
+	done := 0
+	for _, batch := range batches(pending, options.BatchSize, options.BatchTokens) {
+		if err := t.translate(ctx, batch); err != nil {
+			return nil, err
+		}
+		done += len(batch)
+		if options.Progress != nil {
+			options.Progress(done, len(pending))
+		}
+	}
+
+	for text, fields := range targets {
+		translation, _ := options.Cache.get(cacheKey(options, text))
+		for _, field := range fields {
+			*field = translation
+		}
+	}
+	pkg.Language = options.Language
+	return result, nil
+}
+
+// collectFields returns pointers to the translatable strings of the package
+// in package order: names, comments, text content items and answers. Right
+// and wrong answers of select questions are option labels and are kept.
+func collectFields(pkg *siq.Package) []*string {
+	var fields []*string
+	add := func(value *string) {
+		if translatable(*value) {
+			fields = append(fields, value)
+		}
+	}
+	addInfo := func(info *siq.Info) {
+		if info == nil {
+			return
+		}
+		for i := range info.Comments {
+			add(&info.Comments[i])
+		}
+		for i := range info.ShowmanComments {
+			add(&info.ShowmanComments[i])
+		}
+	}
+
+	add(&pkg.Name)
+	addInfo(pkg.Info)
+	for i := range pkg.Rounds {
+		round := &pkg.Rounds[i]
+		add(&round.Name)
+		addInfo(round.Info)
+		for j := range round.Themes {
+			theme := &round.Themes[j]
+			add(&theme.Name)
+			addInfo(theme.Info)
+			for k := range theme.Questions {
+				question := &theme.Questions[k]
+				if param := question.Param(siq.ParamNameTheme); param != nil && param.GetType() == siq.ParamTypeSimple {
+					add(&param.Value)
+				}
+				for item := range question.ContentItems() {
+					if item.GetType() == siq.ContentTypeText && !item.IsRef {
+						add(&item.Value)
+					}
+				}
+				if question.AnswerType() != siq.AnswerTypeSelect {
+					for a := range question.Right {
+						add(&question.Right[a])
+					}
+					for a := range question.Wrong {
+						add(&question.Wrong[a])
+					}
+				}
+				addInfo(question.Info)
+			}
+		}
+	}
+	return fields
+}
+
+// translatable reports whether a text contains letters, numbers and
+// punctuation are kept as they are
+func translatable(text string) bool {
+	return strings.IndexFunc(text, unicode.IsLetter) >= 0
+}
+
+// batches splits texts into groups of at most size texts and about tokens
+// estimated tokens, a longer text gets a group of its own
+func batches(texts []string, size, tokens int) [][]string {
+	var result [][]string
+	var batch []string
+	batchTokens := 0
+	for _, text := range texts {
+		textTokens := packtext.EstimateTokens(text)
+		if len(batch) > 0 && ((size > 0 && len(batch) >= size) || (tokens > 0 && batchTokens+textTokens > tokens)) {
+			result = append(result, batch)
+			batch, batchTokens = nil, 0
+		}
+		batch = append(batch, text)
+		batchTokens += textTokens
+	}
+	if len(batch) > 0 {
+		result = append(result, batch)
+	}
+	return result
+}
+
+// translator sends batches of texts to the model and fills the cache
+type translator struct {
+	provider llm.Provider
+	options  Options
+	result   *Result
+}
+
+// translate translates a batch of texts. Unusable responses are retried
+// with both halves of the batch until Options.MaxRetries is exceeded.
+func (t *translator) translate(ctx context.Context, texts []string) error {
+	translations, err := t.request(ctx, texts)
+	var responseErr *ResponseError
+	if errors.As(err, &responseErr) {
+		if len(texts) == 1 {
+			return fmt.Errorf("failed to translate %q: %w", texts[0], err)
+		}
+		if t.result.Retries >= t.options.MaxRetries {
+			return fmt.Errorf("failed to translate after %d retries: %w", t.result.Retries, err)
+		}
+		t.result.Retries++
+		half := len(texts) / 2
+		if err := t.translate(ctx, texts[:half]); err != nil {
+			return err
+		}
+		return t.translate(ctx, texts[half:])
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, text := range texts {
+		t.options.Cache.put(cacheKey(t.options, text), translations[i])
+	}
+	return nil
+}
+
+// request asks the model for translations of texts
+func (t *translator) request(ctx context.Context, texts []string) ([]string, error) {
+	input, err := json.Marshal(texts)
+	if err != nil {
+		return nil, err
+	}
+
+	source := t.options.SourceLanguage
+	if source == "" {
+		source = "the source language"
+	}
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Translate these %d strings from %s to %s.\n", len(texts), source, t.options.Language)
+	if lines := t.options.Glossary.relevant(texts); len(lines) > 0 {
+		fmt.Fprintf(&prompt, "Always translate these terms as given:\n%s\n", strings.Join(lines, "\n"))
+	}
+	fmt.Fprintf(&prompt, "Strings:\n%s\n", input)
+
+	schema, err := json.Marshal(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"translations": map[string]any{
+				"type":     "array",
+				"items":    map[string]string{"type": "string"},
+				"minItems": len(texts),
+				"maxItems": len(texts),
+			},
+		},
+		"required": []string{"translations"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.provider.Generate(ctx, llm.GenerateRequest{
+		Model:  t.options.Model,
+		System: systemPrompt,
+		Prompt: prompt.String(),
+		Format: schema,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to translate: %w", err)
+	}
+	t.result.Requests++
+	t.result.PromptTokens += resp.PromptTokens
+	t.result.CompletionTokens += resp.CompletionTokens
+
+	var payload struct {
+		Translations []string `json:"translations"`
+	}
+	if err := json.Unmarshal([]byte(resp.Text), &payload); err != nil {
+		return nil, &ResponseError{Expected: len(texts), Got: -1, Response: resp.Text, Err: err}
+	}
+	if len(payload.Translations) != len(texts) {
+		return nil, &ResponseError{Expected: len(texts), Got: len(payload.Translations), Response: resp.Text}
+	}
+	return payload.Translations, nil
+}
@@ -0,0 +1,262 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minmaxmean/sigma/internal/siqtest"
+	"github.com/minmaxmean/sigma/llm"
+	"github.com/minmaxmean/sigma/siq"
+)
+
+const russianPackageXML = `<?xml version="1.0" encoding="utf-8"?>
+<package id="ru" name="Пакет" version="5" language="ru">
+	<info><comments><comment>Для друзей</comment></comments></info>
+	<round name="Первый раунд">
+		<theme name="Поэты">
+			<question price="100">
+				<params>
+					<param name="question" type="content">
+						<item>Кто написал «Онегина»?</item>
+						<item type="image" isRef="True">pushkin.jpg</item>
+					</param>
+				</params>
+				<right><answer>Пушкин</answer></right>
+				<wrong><answer>Гоголь</answer></wrong>
+			</question>
+			<question price="200" type="secret">
+				<params>
+					<param name="theme">Поэты</param>
+					<param name="question" type="content"><item>Год рождения Пушкина</item></param>
+				</params>
+				<right><answer>1799</answer></right>
+			</question>
+			<question price="300">
+				<params>
+					<param name="question" type="content"><item>Столица России?</item></param>
+					<param name="answerType">select</param>
+					<param name="answerOptions" type="group">
+						<param name="A" type="content"><item>Москва</item></param>
+						<param name="B" type="content"><item>Казань</item></param>
+					</param>
+				</params>
+				<right><answer>A</answer></right>
+			</question>
+		</theme>
+	</round>
+</package>`
+
+// prefixProvider translates every string by prefixing it with "en:"
+type prefixProvider struct {
+	*llm.Scripted
+	prompts []string
+}
+
+func (p *prefixProvider) Generate(ctx context.Context, req llm.GenerateRequest) (*llm.Response, error) {
+	p.prompts = append(p.prompts, req.Prompt)
+	_, input, _ := strings.Cut(req.Prompt, "Strings:\n")
+	var texts []string
+	if err := json.Unmarshal([]byte(input), &texts); err != nil {
+		return nil, err
+	}
+	for i, text := range texts {
+		texts[i] = "en:" + text
+	}
+	data, _ := json.Marshal(map[string][]string{"translations": texts})
+	return &llm.Response{Text: string(data), PromptTokens: 10, CompletionTokens: 5}, nil
+}
+
+func TestTranslateFile(t *testing.T) {
+	dir := t.TempDir()
+	input := siqtest.CreateFile(t, dir, "ru.siq", russianPackageXML, map[string]string{"Images/pushkin.jpg": "jpeg"})
+	output := filepath.Join(dir, "en.siq")
+
+	provider := &prefixProvider{Scripted: llm.NewScripted()}
+	options := DefaultOptions()
+	options.Language = "en"
+	options.Glossary = Glossary{"Пушкин": "Pushkin", "Толстой": "Tolstoy"}
+	options.Cache = NewCache()
+	options.BatchSize = 4
+
+	result, err := TranslateFile(context.Background(), provider, input, output, options)
+	if err != nil {
+		t.Fatalf("Failed to translate: %v", err)
+	}
+	// Name, comment, round, theme and the same announced theme, question texts, answers and options
+	if result.Strings != 12 || result.Unique != 11 || result.Cached != 0 {
+		t.Errorf("Expected 12 strings with 11 unique, got %+v", result)
+	}
+	if result.Requests != 3 || result.PromptTokens != 30 {
+		t.Errorf("Expected 3 requests of 4 strings, got %+v", result)
+	}
+	if !strings.Contains(provider.prompts[0], "from ru to en") {
+		t.Errorf("Expected source and target languages in prompt, got %q", provider.prompts[0])
+	}
+	glossaryPrompts := 0
+	for _, prompt := range provider.prompts {
+		if strings.Contains(prompt, "- Пушкин: Pushkin") {
+			glossaryPrompts++
+		}
+		if strings.Contains(prompt, "Tolstoy") {
+			t.Errorf("Expected unused glossary terms to be left out, got %q", prompt)
+		}
+	}
+	if glossaryPrompts == 0 {
+		t.Errorf("Expected glossary terms in prompts mentioning them")
+	}
+
+	reader, err := siq.NewSIQReader(output)
+	if err != nil {
+		t.Fatalf("Failed to open output: %v", err)
+	}
+	defer reader.Close()
+	pkg, err := reader.Read()
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if _, err := reader.GetFile("Images/pushkin.jpg"); err != nil {
+		t.Errorf("Expected media to be copied: %v", err)
+	}
+
+	if pkg.Language != "en" || pkg.Name != "en:Пакет" || pkg.Info.Comments[0] != "en:Для друзей" {
+		t.Errorf("Expected translated package attributes, got %q %q %v", pkg.Language, pkg.Name, pkg.Info.Comments)
+	}
+	theme := pkg.Rounds[0].Themes[0]
+	if pkg.Rounds[0].Name != "en:Первый раунд" || theme.Name != "en:Поэты" {
+		t.Errorf("Expected translated round and theme names, got %q, %q", pkg.Rounds[0].Name, theme.Name)
+	}
+	first := theme.Questions[0]
+	content := first.GetQuestionContent()
+	if content[0].Value != "en:Кто написал «Онегина»?" || content[1].Value != "pushkin.jpg" || !content[1].IsRef {
+		t.Errorf("Expected text translated and media reference kept, got %+v", content)
+	}
+	if first.Right[0] != "en:Пушкин" || first.Wrong[0] != "en:Гоголь" {
+		t.Errorf("Expected translated answers, got %v %v", first.Right, first.Wrong)
+	}
+	secret := theme.Questions[1]
+	if secret.Theme() != "en:Поэты" || secret.Right[0] != "1799" {
+		t.Errorf("Expected translated announced theme and kept number, got %q %v", secret.Theme(), secret.Right)
+	}
+	selectQuestion := theme.Questions[2]
+	answerOptions := selectQuestion.AnswerOptions()
+	if selectQuestion.Right[0] != "A" || answerOptions[0].Items[0].Value != "en:Москва" {
+		t.Errorf("Expected option label kept and option text translated, got %v %+v", selectQuestion.Right, answerOptions)
+	}
+
+	// A second run is served from the cache
+	cachePath := filepath.Join(dir, "cache.json")
+	if err := options.Cache.Save(cachePath); err != nil {
+		t.Fatalf("Failed to save cache: %v", err)
+	}
+	options.Cache, err = LoadCache(cachePath)
+	if err != nil {
+		t.Fatalf("Failed to load cache: %v", err)
+	}
+	provider.prompts = nil
+	result, err = TranslateFile(context.Background(), provider, input, output, options)
+	if err != nil {
+		t.Fatalf("Failed to translate again: %v", err)
+	}
+	if result.Requests != 0 || result.Cached != 11 || len(provider.prompts) != 0 {
+		t.Errorf("Expected all translations from the cache, got %+v", result)
+	}
+
+	// Texts using a changed glossary term are translated again
+	options.Glossary = Glossary{"Пушкин": "Poushkine", "Толстой": "Tolstoy"}
+	result, err = TranslateFile(context.Background(), provider, input, output, options)
+	if err != nil {
+		t.Fatalf("Failed to translate with a new glossary: %v", err)
+	}
+	if result.Cached != 9 || result.Requests != 1 {
+		t.Errorf("Expected the 2 texts with the changed term to miss the cache, got %+v", result)
+	}
+
+	// Another model does not reuse the cache, the output replaces the input
+	options.Model = "other"
+	result, err = TranslateFile(context.Background(), provider, output, output, options)
+	if err != nil {
+		t.Fatalf("Failed to translate in place: %v", err)
+	}
+	if result.Cached != 0 {
+		t.Errorf("Expected no cached translations for another model, got %+v", result)
+	}
+	reader, err = siq.NewSIQReader(output)
+	if err != nil {
+		t.Fatalf("Failed to open output translated in place: %v", err)
+	}
+	defer reader.Close()
+	if pkg, err = reader.Read(); err != nil || pkg.Name != "en:en:Пакет" {
+		t.Errorf("Expected the package translated in place, got %v", err)
+	}
+	if _, err := reader.GetFile("Images/pushkin.jpg"); err != nil {
+		t.Errorf("Expected media to be kept in place: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 3 {
+		t.Errorf("Expected no temporary files left, got %d entries", len(entries))
+	}
+}
+
+func TestTranslateRetriesWrongCount(t *testing.T) {
+	pkg := &siq.Package{Name: "Один", Rounds: []siq.Round{{Name: "Два", Themes: []siq.Theme{{Name: "Три"}}}}}
+	provider := llm.NewScripted(
+		`not json`,
+		`{"translations":["One"]}`,
+		`{"translations":["Two three"]}`,
+		`{"translations":["Two"]}`,
+		`{"translations":["Three"]}`,
+	)
+
+	options := DefaultOptions()
+	options.Language = "English"
+	result, err := Translate(context.Background(), provider, pkg, options)
+	if err != nil {
+		t.Fatalf("Failed to translate: %v", err)
+	}
+	if pkg.Name != "One" || pkg.Rounds[0].Name != "Two" || pkg.Rounds[0].Themes[0].Name != "Three" || result.Requests != 5 {
+		t.Errorf("Expected halves to be retried, got %q %q after %d requests", pkg.Name, pkg.Rounds[0].Name, result.Requests)
+	}
+	if result.Retries != 2 {
+		t.Errorf("Expected 2 retries, got %d", result.Retries)
+	}
+
+	var responseErr *ResponseError
+	provider.Responses = []string{`{"translations":[]}`}
+	_, err = Translate(context.Background(), provider, &siq.Package{Name: "Четыре"}, options)
+	if !errors.As(err, &responseErr) || responseErr.Expected != 1 || responseErr.Got != 0 {
+		t.Errorf("Expected a response error when a single string cannot be translated, got %v", err)
+	}
+
+	pkg = &siq.Package{Name: "Пять", Rounds: []siq.Round{{Name: "Шесть"}}}
+	provider.Responses = []string{`not json`}
+	options.MaxRetries = 0
+	_, err = Translate(context.Background(), provider, pkg, options)
+	if !errors.As(err, &responseErr) || responseErr.Got != -1 || responseErr.Response != "not json" {
+		t.Errorf("Expected a response error for an unparsable response without retries, got %v", err)
+	}
+	if _, err := Translate(context.Background(), provider, pkg, Options{}); err == nil {
+		t.Errorf("Expected error without a target language")
+	}
+}
+
+func TestLoadGlossaryAndCache(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "glossary.yaml")
+	os.WriteFile(path, []byte("Пушкин: Pushkin\n\"Санкт-Петербург\": Saint Petersburg\n"), 0644)
+	glossary, err := LoadGlossary(path)
+	if err != nil {
+		t.Fatalf("Failed to load glossary: %v", err)
+	}
+	if glossary["Санкт-Петербург"] != "Saint Petersburg" {
+		t.Errorf("Unexpected glossary %v", glossary)
+	}
+
+	cache, err := LoadCache(filepath.Join(dir, "missing.json"))
+	if err != nil || cache.Len() != 0 {
+		t.Errorf("Expected empty cache for a missing file, got %v", err)
+	}
+}
@@ -1,5 +1,9 @@
 package siq
 
+import (
+	"strings"
+)
+
 // QuestionType represents well-known question types
 const (
 	QuestionTypeSimple  = "simple"
@@ -14,6 +18,33 @@ const (
 	QuestionTypeMedia   = "media"
 	QuestionTypeStake   = "stake"
 	QuestionTypeFinal   = "final"
+	// Version 5 secret question variants
+	QuestionTypeSecretPublicPrice = "secretPublicPrice"
+	QuestionTypeSecretNoQuestion  = "secretNoQuestion"
+	QuestionTypeForAll            = "forAll"
+)
+
+// Well-known question parameter names
+const (
+	ParamNameQuestion      = "question"
+	ParamNameAnswer        = "answer"
+	ParamNameTheme         = "theme"
+	ParamNamePrice         = "price"
+	ParamNameSelectionMode = "selectionMode"
+	ParamNameAnswerType    = "answerType"
+	ParamNameAnswerOptions = "answerOptions"
+)
+
+// AnswerType represents how players give their answers
+const (
+	AnswerTypeText   = "text"
+	AnswerTypeSelect = "select"
+)
+
+// SelectionMode represents who can receive a secret question
+const (
+	SelectionModeExceptCurrent = "exceptCurrent"
+	SelectionModeAny           = "any"
 )
 
 // ContentType represents content item types
@@ -27,6 +58,55 @@ const (
 	ContentTypeHtml   = "html"
 )
 
+// Version 4 atom types that have no content type with the same name
+const (
+	AtomTypeSay = "say"
+)
+
+// Media folders inside a SIQ archive
+const (
+	FolderImages = "Images"
+	FolderAudio  = "Audio"
+	FolderVideo  = "Video"
+	FolderHtml   = "Html"
+)
+
+// MediaFolder returns the archive folder storing files of a content type,
+// or an empty string for types that are not stored as files
+func MediaFolder(contentType string) string {
+	switch contentType {
+	case ContentTypeImage:
+		return FolderImages
+	case ContentTypeAudio, ContentTypeVoice:
+		return FolderAudio
+	case ContentTypeVideo:
+		return FolderVideo
+	case ContentTypeHtml:
+		return FolderHtml
+	}
+	return ""
+}
+
+// MediaType returns the content type of an archive file by its folder,
+// or an empty string for files outside of the media folders
+func MediaType(filePath string) string {
+	folder, _, found := strings.Cut(filePath, "/")
+	if !found {
+		return ""
+	}
+	switch {
+	case strings.EqualFold(folder, FolderImages):
+		return ContentTypeImage
+	case strings.EqualFold(folder, FolderAudio):
+		return ContentTypeAudio
+	case strings.EqualFold(folder, FolderVideo):
+		return ContentTypeVideo
+	case strings.EqualFold(folder, FolderHtml):
+		return ContentTypeHtml
+	}
+	return ""
+}
+
 // PlacementType represents content placement types
 const (
 	PlacementScreen     = "screen"
@@ -57,6 +137,9 @@ func IsWellKnownType(questionType string) bool {
 		QuestionTypeMedia,
 		QuestionTypeStake,
 		QuestionTypeFinal,
+		QuestionTypeSecretPublicPrice,
+		QuestionTypeSecretNoQuestion,
+		QuestionTypeForAll,
 	}
 
 	for _, t := range wellKnownTypes {
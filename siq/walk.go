@@ -0,0 +1,74 @@
+package siq
+
+import (
+	"fmt"
+)
+
+// QuestionRef describes where a question is located in a package
+type QuestionRef struct {
+	RoundIndex    int // zero-based
+	RoundName     string
+	ThemeIndex    int // zero-based, within the round
+	ThemeName     string
+	QuestionIndex int // zero-based, within the theme
+	Price         QuestionPrice
+}
+
+// ID returns a stable path identifier of the question, for example "r1/t2/q3"
+func (r QuestionRef) ID() string {
+	return fmt.Sprintf("r%d/t%d/q%d", r.RoundIndex+1, r.ThemeIndex+1, r.QuestionIndex+1)
+}
+
+// String returns a human-readable location of the question
+func (r QuestionRef) String() string {
+	return fmt.Sprintf("%s / %s / %s", r.RoundName, r.ThemeName, r.Price)
+}
+
+// Walk calls fn for every question of the package in order, together with
+// its location. Version 4 rounds are walked after version 5 rounds and their
+// questions are passed converted to v5 format, so changes made through the
+// pointer only persist for version 5 questions. Walking stops at the first
+// error returned by fn and that error is returned.
+func (p *Package) Walk(fn func(ref QuestionRef, question *Question) error) error {
+	for roundIndex := range p.Rounds {
+		round := &p.Rounds[roundIndex]
+		for themeIndex := range round.Themes {
+			theme := &round.Themes[themeIndex]
+			for questionIndex := range theme.Questions {
+				question := &theme.Questions[questionIndex]
+				ref := QuestionRef{
+					RoundIndex:    roundIndex,
+					RoundName:     round.Name,
+					ThemeIndex:    themeIndex,
+					ThemeName:     theme.Name,
+					QuestionIndex: questionIndex,
+					Price:         question.Price(),
+				}
+				if err := fn(ref, question); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for roundIndex, round := range p.RoundsV4 {
+		for themeIndex, theme := range round.Themes {
+			for questionIndex, qv4 := range theme.Questions {
+				question := convertV4ToV5Question(qv4)
+				ref := QuestionRef{
+					RoundIndex:    len(p.Rounds) + roundIndex,
+					RoundName:     round.Name,
+					ThemeIndex:    themeIndex,
+					ThemeName:     theme.Name,
+					QuestionIndex: questionIndex,
+					Price:         question.Price(),
+				}
+				if err := fn(ref, &question); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package siq
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	pkg := &Package{
+		Rounds: []Round{
+			{
+				Name: "Round 1",
+				Themes: []Theme{
+					{Name: "Theme A", Questions: []Question{{BasePrice: 100}, {BasePrice: 200}}},
+					{Name: "Theme B", Questions: []Question{{BasePrice: 300}}},
+				},
+			},
+		},
+		RoundsV4: []RoundV4{
+			{
+				Name:   "Old Round",
+				Themes: []ThemeV4{{Name: "Old Theme", Questions: []QuestionV4{{Price: 400}}}},
+			},
+		},
+	}
+
+	var ids, locations []string
+	err := pkg.Walk(func(ref QuestionRef, question *Question) error {
+		ids = append(ids, ref.ID())
+		locations = append(locations, ref.String())
+		return nil
+	})
+	if err != nil {
+		t.Fatal("Unexpected walk error:", err)
+	}
+
+	if strings.Join(ids, ",") != "r1/t1/q1,r1/t1/q2,r1/t2/q1,r2/t1/q1" {
+		t.Errorf("Unexpected question IDs %v", ids)
+	}
+	if locations[1] != "Round 1 / Theme A / 200" {
+		t.Errorf("Unexpected location '%s'", locations[1])
+	}
+	if locations[3] != "Old Round / Old Theme / 400" {
+		t.Errorf("Unexpected v4 location '%s'", locations[3])
+	}
+}
+
+func TestWalkModifiesQuestions(t *testing.T) {
+	pkg := &Package{
+		Rounds: []Round{{Themes: []Theme{{Questions: []Question{{Type: QuestionTypeSimple}}}}}},
+	}
+
+	pkg.Walk(func(ref QuestionRef, question *Question) error {
+		question.Type = QuestionTypeStake
+		return nil
+	})
+	if pkg.Rounds[0].Themes[0].Questions[0].Type != QuestionTypeStake {
+		t.Error("Expected walk to modify the question in place")
+	}
+}
+
+func TestWalkStopsOnError(t *testing.T) {
+	pkg := &Package{
+		Rounds: []Round{{Themes: []Theme{{Questions: []Question{{}, {}, {}}}}}},
+	}
+
+	stop := errors.New("stop")
+	visited := 0
+	err := pkg.Walk(func(ref QuestionRef, question *Question) error {
+		visited++
+		if ref.QuestionIndex == 1 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected stop error, got %v", err)
+	}
+	if visited != 2 {
+		t.Errorf("Expected 2 visited questions, got %d", visited)
+	}
+}
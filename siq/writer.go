@@ -0,0 +1,387 @@
+package siq
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// namespaceV5 is the XML namespace of version 5 packages
+const namespaceV5 = "https://github.com/VladimirKhil/SI/blob/master/assets/siq_5.xsd"
+
+// MarshalContent encodes the package as a version 5 content.xml document.
+// Version 4 rounds are converted and spec defaults are omitted from the output.
+func MarshalContent(pkg *Package) ([]byte, error) {
+	out := *pkg
+	out.Version = "5"
+	if len(out.RoundsV4) > 0 {
+		out.Rounds = append([]Round(nil), out.Rounds...)
+		out.ConvertV4()
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	start := xml.StartElement{
+		Name: xml.Name{Local: "package"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: namespaceV5}},
+	}
+	if err := encoder.EncodeElement(out, start); err != nil {
+		return nil, fmt.Errorf("failed to encode content.xml: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode content.xml: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalXML encodes the package in version 5 layout
+func (p Package) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type packageV5 struct {
+		ID          string  `xml:"id,attr"`
+		Name        string  `xml:"name,attr"`
+		Version     string  `xml:"version,attr"`
+		Restriction string  `xml:"restriction,attr,omitempty"`
+		Date        string  `xml:"date,attr,omitempty"`
+		Publisher   string  `xml:"publisher,attr,omitempty"`
+		Difficulty  int     `xml:"difficulty,attr,omitempty"`
+		Logo        string  `xml:"logo,attr,omitempty"`
+		Language    string  `xml:"language,attr,omitempty"`
+		Info        *Info   `xml:"info,omitempty"`
+		Tags        *Tags   `xml:"tags,omitempty"`
+		Global      *Global `xml:"global,omitempty"`
+		Rounds      []Round `xml:"round"`
+	}
+	return e.EncodeElement(packageV5{
+		ID:          p.ID,
+		Name:        p.Name,
+		Version:     p.Version,
+		Restriction: p.Restriction,
+		Date:        p.Date,
+		Publisher:   p.Publisher,
+		Difficulty:  p.Difficulty,
+		Logo:        p.Logo,
+		Language:    p.Language,
+		Info:        p.Info,
+		Tags:        p.Tags,
+		Global:      p.Global,
+		Rounds:      p.Rounds,
+	}, start)
+}
+
+// MarshalXML encodes the question, omitting empty answer lists
+func (q Question) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = []xml.Attr{{Name: xml.Name{Local: "type"}, Value: q.Type}}
+	if q.BasePrice != 0 {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "price"}, Value: strconv.Itoa(q.BasePrice)})
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeList(e, "params", "param", q.Params); err != nil {
+		return err
+	}
+	if err := encodeList(e, "right", "answer", q.Right); err != nil {
+		return err
+	}
+	if err := encodeList(e, "wrong", "answer", q.Wrong); err != nil {
+		return err
+	}
+	if q.Script != nil {
+		if err := e.EncodeElement(q.Script, xml.StartElement{Name: xml.Name{Local: "script"}}); err != nil {
+			return err
+		}
+	}
+	if q.Info != nil {
+		if err := e.EncodeElement(q.Info, xml.StartElement{Name: xml.Name{Local: "info"}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// MarshalXML encodes the info, omitting empty lists
+func (i Info) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeList(e, "authors", "author", i.Authors); err != nil {
+		return err
+	}
+	if err := encodeList(e, "sources", "source", i.Sources); err != nil {
+		return err
+	}
+	if err := encodeList(e, "comments", "comment", i.Comments); err != nil {
+		return err
+	}
+	if err := encodeList(e, "showmanComments", "comment", i.ShowmanComments); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// MarshalXML encodes the global definitions, omitting empty lists
+func (g Global) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeList(e, "authors", "author", g.Authors); err != nil {
+		return err
+	}
+	if err := encodeList(e, "sources", "source", g.Sources); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// encodeList writes values as child elements of a wrapper element.
+// Nothing is written for an empty list.
+func encodeList[T any](e *xml.Encoder, wrapper, child string, values []T) error {
+	if len(values) == 0 {
+		return nil
+	}
+	start := xml.StartElement{Name: xml.Name{Local: wrapper}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, value := range values {
+		if err := e.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: child}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// MarshalXML encodes the content item, omitting attributes equal to their spec defaults
+func (c ContentItem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = nil
+	if c.GetType() != ContentTypeText {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "type"}, Value: c.Type})
+	}
+	if c.Duration != 0 {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "duration"}, Value: strconv.Itoa(c.Duration)})
+	}
+	if c.IsRef {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "isRef"}, Value: "True"})
+	}
+	if c.GetPlacement() != PlacementScreen {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "placement"}, Value: c.Placement})
+	}
+	if !c.GetWaitForFinish() {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "waitForFinish"}, Value: "False"})
+	}
+	return e.EncodeElement(c.Value, start)
+}
+
+// MarshalXML encodes the parameter. The type is omitted for simple params and
+// the text value is only written for simple params, so whitespace between
+// child elements of decoded params is not repeated.
+func (p Param) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = []xml.Attr{{Name: xml.Name{Local: "name"}, Value: p.Name}}
+	if p.GetType() != ParamTypeSimple {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "type"}, Value: p.Type})
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch p.GetType() {
+	case ParamTypeSimple:
+		if err := e.EncodeToken(xml.CharData(p.Value)); err != nil {
+			return err
+		}
+	case ParamTypeNumberSet:
+		if set := p.GetNumberSet(); set != nil {
+			if err := e.EncodeElement(set, xml.StartElement{Name: xml.Name{Local: "numberSet"}}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, item := range p.Items {
+		if err := e.EncodeElement(item, xml.StartElement{Name: xml.Name{Local: "item"}}); err != nil {
+			return err
+		}
+	}
+	for _, child := range p.Params {
+		if err := e.EncodeElement(child, xml.StartElement{Name: xml.Name{Local: "param"}}); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// NewID returns a random UUID used as a package id
+func NewID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// SIQWriter represents a writer for SIQ files
+type SIQWriter struct {
+	file      *os.File
+	zipWriter *zip.Writer
+	names     map[string]bool
+}
+
+// NewSIQWriter creates a SIQ file at the given path
+func NewSIQWriter(filePath string) (*SIQWriter, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SIQ file: %w", err)
+	}
+	return &SIQWriter{file: file, zipWriter: zip.NewWriter(file), names: make(map[string]bool)}, nil
+}
+
+// WritePackage writes the package as content.xml
+func (w *SIQWriter) WritePackage(pkg *Package) error {
+	data, err := MarshalContent(pkg)
+	if err != nil {
+		return err
+	}
+	return w.WriteFile("content.xml", data)
+}
+
+// WriteFile adds a file with the given content to the archive
+func (w *SIQWriter) WriteFile(name string, data []byte) error {
+	writer, err := w.CreateFile(name)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// CreateFile adds a compressed file to the archive and returns a writer for
+// its content. The writer is valid until the next call to the SIQWriter.
+func (w *SIQWriter) CreateFile(name string) (io.Writer, error) {
+	return w.createFile(name, zip.Deflate)
+}
+
+// CreateStoredFile adds a file stored without compression, which suits
+// already compressed media such as JPEG images, MP3 audio and video
+func (w *SIQWriter) CreateStoredFile(name string) (io.Writer, error) {
+	return w.createFile(name, zip.Store)
+}
+
+// SetCompressionLevel sets the deflate level of compressed files added
+// afterwards, from flate.BestSpeed to flate.BestCompression
+func (w *SIQWriter) SetCompressionLevel(level int) error {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return fmt.Errorf("invalid compression level %d", level)
+	}
+	w.zipWriter.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, level)
+	})
+	return nil
+}
+
+func (w *SIQWriter) createFile(name string, method uint16) (io.Writer, error) {
+	if err := w.claim(name); err != nil {
+		return nil, err
+	}
+	writer, err := w.zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	return writer, nil
+}
+
+// CopyFile copies a file from another archive under a new name without recompressing it
+func (w *SIQWriter) CopyFile(file *zip.File, name string) error {
+	if err := w.claim(name); err != nil {
+		return err
+	}
+
+	header := file.FileHeader
+	header.Name = name
+	writer, err := w.zipWriter.CreateRaw(&header)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+	reader, err := file.OpenRaw()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file.Name, err)
+	}
+	if _, err := io.Copy(writer, reader); err != nil {
+		return fmt.Errorf("failed to copy %s: %w", file.Name, err)
+	}
+	return nil
+}
+
+// CopyFiles copies every file of another archive except content.xml, so a
+// modified package can be written together with the original media
+func (w *SIQWriter) CopyFiles(reader *SIQReader) error {
+	for _, file := range reader.zipReader.File {
+		if file.Name == "content.xml" || strings.HasSuffix(file.Name, "/") {
+			continue
+		}
+		if err := w.CopyFile(file, file.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasFile reports whether a file with the given name was added to the archive
+func (w *SIQWriter) HasFile(name string) bool {
+	return w.names[name]
+}
+
+// claim registers a file name, archives cannot contain duplicate names
+func (w *SIQWriter) claim(name string) error {
+	if w.names[name] {
+		return fmt.Errorf("file %s already exists in SIQ archive", name)
+	}
+	w.names[name] = true
+	return nil
+}
+
+// Close finishes the archive and closes the file
+func (w *SIQWriter) Close() error {
+	if err := w.zipWriter.Close(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("failed to finish SIQ archive: %w", err)
+	}
+	return w.file.Close()
+}
+
+// ReplaceFile creates the SIQ file at path with write. The archive is
+// written next to path and replaces it once complete, so path may be the
+// package being read. Nothing is left behind when writing fails.
+func ReplaceFile(path string, write func(w *SIQWriter) error) error {
+	temp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	writer, err := NewSIQWriter(temp)
+	if err != nil {
+		return err
+	}
+	if err := write(writer); err != nil {
+		writer.Close()
+		os.Remove(temp)
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package siq
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	sourceFile := createTestSIQFileWithContent(t, secretPackageXML)
+	defer os.Remove(sourceFile)
+
+	reader, err := NewSIQReader(sourceFile)
+	if err != nil {
+		t.Fatal("Failed to create SIQ reader:", err)
+	}
+	defer reader.Close()
+	pkg, err := reader.Read()
+	if err != nil {
+		t.Fatal("Failed to read package:", err)
+	}
+
+	data, err := MarshalContent(pkg)
+	if err != nil {
+		t.Fatal("Failed to marshal content:", err)
+	}
+	content := string(data)
+	for _, expected := range []string{`xmlns="` + namespaceV5 + `"`, `version="5"`, `isRef="True"`, `<numberSet minimum="100" maximum="500" step="100">`, `waitForFinish="False"`} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected content to contain %s, got:\n%s", expected, content)
+		}
+	}
+	for _, unexpected := range []string{`placement="screen"`, `type="text"`, `waitForFinish="true"`, `publisher=""`} {
+		if strings.Contains(content, unexpected) {
+			t.Errorf("Expected defaults to be omitted, found %s in:\n%s", unexpected, content)
+		}
+	}
+
+	// Write the package with a media file and read it back
+	outputFile := filepath.Join(t.TempDir(), "out.siq")
+	writer, err := NewSIQWriter(outputFile)
+	if err != nil {
+		t.Fatal("Failed to create SIQ writer:", err)
+	}
+	if err := writer.WritePackage(pkg); err != nil {
+		t.Fatal("Failed to write package:", err)
+	}
+	if err := writer.WriteFile("Images/cat.png", []byte("cat")); err != nil {
+		t.Fatal("Failed to write file:", err)
+	}
+	if err := writer.WriteFile("Images/cat.png", []byte("cat")); err == nil {
+		t.Error("Expected error for duplicate file name")
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal("Failed to close writer:", err)
+	}
+
+	written, err := NewSIQReader(outputFile)
+	if err != nil {
+		t.Fatal("Failed to open written package:", err)
+	}
+	defer written.Close()
+	roundTrip, err := written.Read()
+	if err != nil {
+		t.Fatal("Failed to read written package:", err)
+	}
+
+	if roundTrip.Name != pkg.Name || roundTrip.GetQuestionCount() != pkg.GetQuestionCount() {
+		t.Errorf("Unexpected package after round trip %+v", roundTrip)
+	}
+	question := roundTrip.Rounds[0].Themes[0].Questions[0]
+	if question.Theme() != "Cats" || question.Price().String() != "100-500 (step 100)" {
+		t.Errorf("Expected params to survive the round trip, got %+v", question.Params)
+	}
+	answer := question.AnswerContent()
+	if len(answer) != 1 || !answer[0].IsRef || answer[0].GetWaitForFinish() {
+		t.Errorf("Expected answer item attributes to survive the round trip, got %+v", answer)
+	}
+	if question.GetQuestionContent()[0].Value != "Secret question" {
+		t.Errorf("Unexpected question content %+v", question.GetQuestionContent())
+	}
+	if _, err := written.GetFile("Images/cat.png"); err != nil {
+		t.Error("Expected media file in written package:", err)
+	}
+
+	// Copy the media into another archive with a modified package
+	copyFile := filepath.Join(t.TempDir(), "copy.siq")
+	copyWriter, err := NewSIQWriter(copyFile)
+	if err != nil {
+		t.Fatal("Failed to create SIQ writer:", err)
+	}
+	roundTrip.Name = "Copy"
+	if err := copyWriter.WritePackage(roundTrip); err != nil {
+		t.Fatal("Failed to write package:", err)
+	}
+	if err := copyWriter.CopyFiles(written); err != nil {
+		t.Fatal("Failed to copy files:", err)
+	}
+	if err := copyWriter.Close(); err != nil {
+		t.Fatal("Failed to close writer:", err)
+	}
+	copied, err := NewSIQReader(copyFile)
+	if err != nil {
+		t.Fatal("Failed to open copied package:", err)
+	}
+	defer copied.Close()
+	if files := copied.ListFiles(); len(files) != 2 {
+		t.Errorf("Expected content.xml and the media file, got %v", files)
+	}
+	if copiedPkg, err := copied.Read(); err != nil || copiedPkg.Name != "Copy" {
+		t.Errorf("Expected modified package in the copy, got %v", err)
+	}
+}
+
+func TestReplaceFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pack.siq")
+	if err := ReplaceFile(path, func(w *SIQWriter) error {
+		if err := w.WritePackage(&Package{Name: "First"}); err != nil {
+			return err
+		}
+		return w.WriteFile("Images/cat.png", []byte("cat"))
+	}); err != nil {
+		t.Fatal("Failed to write package:", err)
+	}
+
+	// The package being read is replaced together with its media
+	reader, err := NewSIQReader(path)
+	if err != nil {
+		t.Fatal("Failed to open package:", err)
+	}
+	defer reader.Close()
+	if err := ReplaceFile(path, func(w *SIQWriter) error {
+		if err := w.WritePackage(&Package{Name: "Second"}); err != nil {
+			return err
+		}
+		return w.CopyFiles(reader)
+	}); err != nil {
+		t.Fatal("Failed to replace package:", err)
+	}
+	replaced, err := NewSIQReader(path)
+	if err != nil {
+		t.Fatal("Failed to open replaced package:", err)
+	}
+	defer replaced.Close()
+	if pkg, err := replaced.Read(); err != nil || pkg.Name != "Second" {
+		t.Errorf("Expected replaced package, got %v", err)
+	}
+	if _, err := replaced.GetFile("Images/cat.png"); err != nil {
+		t.Errorf("Expected media to be kept: %v", err)
+	}
+
+	// A failed write keeps the original and leaves no temporary file
+	failure := errors.New("failure")
+	if err := ReplaceFile(path, func(w *SIQWriter) error {
+		w.WriteFile("Images/dog.png", []byte("dog"))
+		return failure
+	}); !errors.Is(err, failure) {
+		t.Errorf("Expected the write error, got %v", err)
+	}
+	if err := ReplaceFile(filepath.Join(dir, "new.siq"), func(w *SIQWriter) error { return failure }); !errors.Is(err, failure) {
+		t.Errorf("Expected the write error, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected only the original package to remain, got %d files", len(entries))
+	}
+}
+
+func TestMediaPaths(t *testing.T) {
+	pkg := &Package{
+		Logo: "@logo%20file.png",
+		Rounds: []Round{{Themes: []Theme{{Questions: []Question{
+			*decodeTestQuestion(t, secretQuestionXML),
+			*decodeTestQuestion(t, selectQuestionXML),
+			{Params: []Param{{Name: ParamNameQuestion, Type: ParamTypeContent, Items: []ContentItem{
+				{Type: ContentTypeVoice, IsRef: true, Value: "%D0%B0.mp3"},
+				{Type: ContentTypeImage, Value: "https://example.com/cat.png"},
+				{Type: ContentTypeImage, IsRef: true, Value: "cat.png"},
+			}}}},
+		}}}}},
+	}
+
+	expected := "Images/logo file.png,Images/cat.png,Images/venus.png,Audio/а.mp3"
+	if paths := strings.Join(pkg.MediaPaths(), ","); paths != expected {
+		t.Errorf("Expected media paths %s, got %s", expected, paths)
+	}
+
+	pkg.RenameFiles(map[string]string{"Images/cat.png": "Images/cat_2.png", "Images/logo file.png": "Images/logo.jpg"})
+	if pkg.Logo != "@logo.jpg" || pkg.Rounds[0].Themes[0].Questions[2].GetQuestionContent()[2].Value != "cat_2.png" {
+		t.Errorf("Expected references to be renamed, got logo %s and items %+v", pkg.Logo, pkg.Rounds[0].Themes[0].Questions[2].GetQuestionContent())
+	}
+
+	if RenameFile("Images/cat.png", 2) != "Images/cat_2.png" {
+		t.Errorf("Unexpected renamed file %s", RenameFile("Images/cat.png", 2))
+	}
+}
+
+const secretPackageXML = `<?xml version="1.0" encoding="utf-8"?>
+<package id="secret" name="Secret Package" version="5">
+	<round name="Round 1">
+		<theme name="Animals">
+			<question type="secret" price="100">
+				<params>
+					<param name="question" type="content">
+						<item type="text">Secret question</item>
+					</param>
+					<param name="theme">Cats</param>
+					<param name="selectionMode">exceptCurrent</param>
+					<param name="price" type="numberSet">
+						<numberSet minimum="100" maximum="500" step="100" />
+					</param>
+					<param name="answer" type="content">
+						<item type="image" isRef="True" waitForFinish="False">cat.png</item>
+					</param>
+				</params>
+				<right>
+					<answer>Cat</answer>
+				</right>
+			</question>
+		</theme>
+	</round>
+</package>`
@@ -0,0 +1,28 @@
+// Package textnorm normalizes text for case- and diacritic-insensitive comparison
+package textnorm
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// StripDiacritics removes combining marks from the text, for example
+// "café" becomes "cafe" and "ёжик" becomes "ежик"
+func StripDiacritics(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return result
+}
+
+// Fold returns the text in lower case without diacritics and with
+// runs of whitespace collapsed to a single space
+func Fold(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(StripDiacritics(s))), " ")
+}
@@ -0,0 +1,34 @@
+package textnorm
+
+import (
+	"testing"
+)
+
+func TestStripDiacritics(t *testing.T) {
+	tests := map[string]string{
+		"café":         "cafe",
+		"Ёжик":         "Ежик",
+		"Йогурт":       "Иогурт",
+		"naïve":        "naive",
+		"plain":        "plain",
+		"Crème Brûlée": "Creme Brulee",
+	}
+	for input, expected := range tests {
+		if result := StripDiacritics(input); result != expected {
+			t.Errorf("StripDiacritics(%q) = %q, expected %q", input, result, expected)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := map[string]string{
+		"  Ёлка\tЗЕЛЁНАЯ ": "елка зеленая",
+		"Hello,   World":   "hello, world",
+		"":                 "",
+	}
+	for input, expected := range tests {
+		if result := Fold(input); result != expected {
+			t.Errorf("Fold(%q) = %q, expected %q", input, result, expected)
+		}
+	}
+}